@@ -0,0 +1,29 @@
+package api
+
+import (
+	"context"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Batch queues write commands and sends them pipelined on one connection,
+// each with its own clrID, instead of waiting for every round-trip.
+// Commands are validated when queued; an invalid one is reported in its
+// BatchResult and never sent.
+type Batch interface {
+	SetProp(p types.SetPropPayload)
+	SetRoomPkg(p types.SetRoomPkgPayload)
+	SetRoomAvl(p types.UpdRoomAvlPayload)
+	IncRoomAvl(p types.UpdRoomAvlPayload)
+	DecRoomAvl(p types.UpdRoomAvlPayload)
+	DelRoomDay(p types.DelRoomDayRequest)
+	Len() int
+
+	// Exec sends the queued commands and empties the batch. It returns one
+	// result per command in queue order, and the first failure, if any, as
+	// the error. The client Timeout covers the whole batch when ctx has no
+	// deadline, so give large batches an explicit one. A key set with
+	// WithIdempotencyKey on ctx is extended with each command's index, so
+	// resending the same batch with the same key applies nothing twice.
+	Exec(ctx context.Context) ([]types.BatchResult, error)
+}
@@ -1,26 +1,79 @@
 package api
 
-import "github.com/roomzin/roomzin-go/types"
+import (
+	"context"
+
+	"github.com/roomzin/roomzin-go/types"
+)
 
 type CacheClientAPI interface {
+	CacheClientAPIContext
+
 	GetCodecs() (*types.Codecs, error)
 	SetProp(p types.SetPropPayload) error
 	SearchProp(p types.SearchPropPayload) ([]string, error)
 	SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error)
 	SetRoomPkg(p types.SetRoomPkgPayload) error
+	SetRoomPkgRange(p types.SetRoomPkgRangePayload) ([]types.DateResult, error)
 	SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error)
+	SetRoomAvlIf(p types.SetRoomAvlIfPayload) (uint8, error)
 	IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error)
 	DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error)
 	PropExist(propertyID string) (bool, error)
 	PropRoomExist(p types.PropRoomExistPayload) (bool, error)
 	PropRoomList(propertyID string) ([]string, error)
-	PropRoomDateList(p types.PropRoomDateListPayload) ([]string, error)
+	PropRoomDateList(p types.PropRoomDateListPayload) ([]types.Date, error)
 	DelProp(propertyID string) error
 	DelSegment(segment string) error
 	DelPropDay(p types.DelPropDayRequest) error
 	DelPropRoom(p types.DelPropRoomPayload) error
 	DelRoomDay(p types.DelRoomDayRequest) error
+	DelRoomRange(p types.DelRoomRangePayload) ([]types.DateResult, error)
 	GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error)
 	GetSegments() ([]types.SegmentInfo, error)
+	NewBatch() Batch
+	// WatchCodecs calls fn whenever the client fetches codecs whose
+	// Version differs from the last ones, which happens after a
+	// reconnect and on GetCodecs. Calls come one at a time from an
+	// internal goroutine of the client, not from the caller of GetCodecs,
+	// and fn must not block: later notifications wait for it. fn may use
+	// the client. stop unregisters fn.
+	WatchCodecs(fn func(old, new *types.Codecs)) (stop func())
 	Close() error
 }
+
+// CacheClientAPIContext mirrors CacheClientAPI with a caller supplied context.
+// The context bounds the whole call: cancelling it drops the pending request
+// and returns an error matching ctx.Err() with errors.Is. When ctx carries no
+// deadline the client's configured Timeout is applied.
+type CacheClientAPIContext interface {
+	GetCodecsCtx(ctx context.Context) (*types.Codecs, error)
+	SetPropCtx(ctx context.Context, p types.SetPropPayload) error
+	SearchPropCtx(ctx context.Context, p types.SearchPropPayload) ([]string, error)
+	SearchAvailCtx(ctx context.Context, p types.SearchAvailPayload) ([]types.PropertyAvail, error)
+	SetRoomPkgCtx(ctx context.Context, p types.SetRoomPkgPayload) error
+	// SetRoomPkgRangeCtx expands p into one SetRoomPkg per selected day and
+	// sends them pipelined. It returns one DateResult per day and the first
+	// failure, if any, as the error.
+	SetRoomPkgRangeCtx(ctx context.Context, p types.SetRoomPkgRangePayload) ([]types.DateResult, error)
+	SetRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error)
+	// SetRoomAvlIfCtx writes p.Amount only if availability still equals
+	// p.Expected. Otherwise it fails with a KindConflict error and returns
+	// the current availability.
+	SetRoomAvlIfCtx(ctx context.Context, p types.SetRoomAvlIfPayload) (uint8, error)
+	IncRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error)
+	DecRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error)
+	PropExistCtx(ctx context.Context, propertyID string) (bool, error)
+	PropRoomExistCtx(ctx context.Context, p types.PropRoomExistPayload) (bool, error)
+	PropRoomListCtx(ctx context.Context, propertyID string) ([]string, error)
+	PropRoomDateListCtx(ctx context.Context, p types.PropRoomDateListPayload) ([]types.Date, error)
+	DelPropCtx(ctx context.Context, propertyID string) error
+	DelSegmentCtx(ctx context.Context, segment string) error
+	DelPropDayCtx(ctx context.Context, p types.DelPropDayRequest) error
+	DelPropRoomCtx(ctx context.Context, p types.DelPropRoomPayload) error
+	DelRoomDayCtx(ctx context.Context, p types.DelRoomDayRequest) error
+	// DelRoomRangeCtx is the DelRoomDay counterpart of SetRoomPkgRangeCtx.
+	DelRoomRangeCtx(ctx context.Context, p types.DelRoomRangePayload) ([]types.DateResult, error)
+	GetPropRoomDayCtx(ctx context.Context, p types.GetRoomDayRequest) (types.GetRoomDayResult, error)
+	GetSegmentsCtx(ctx context.Context) ([]types.SegmentInfo, error)
+}
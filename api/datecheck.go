@@ -0,0 +1,20 @@
+package api
+
+import "context"
+
+type anyDateCtx struct{}
+
+// WithAnyDate makes calls made with ctx accept dates outside the booking
+// window: before today or beyond the horizon. Tools that dump or prune
+// what is already stored, such as snapshot.Export and reconcile, use it
+// so one stale day does not fail their whole run. The server still has
+// the final say on each date.
+func WithAnyDate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, anyDateCtx{}, true)
+}
+
+// AnyDateFrom reports whether ctx was made by WithAnyDate.
+func AnyDateFrom(ctx context.Context) bool {
+	v, _ := ctx.Value(anyDateCtx{}).(bool)
+	return v
+}
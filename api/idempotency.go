@@ -0,0 +1,26 @@
+package api
+
+import "context"
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey returns a ctx whose mutating calls carry key. The key
+// travels in an extra payload field that the server uses to answer a
+// resent write without applying it twice, which is what makes resending
+// safe: the cluster client resends IncRoomAvl and DecRoomAvl after 503,
+// 429 or a dropped connection only when they carry a key. Without a key no
+// such field is sent, since servers that predate it may reject unknown
+// fields.
+//
+// Every call made with the returned ctx sends the same key, and the server
+// answers all but the first from its replay cache, so derive a fresh ctx
+// for each distinct write and reuse it only to retry that write.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// IdempotencyKeyFrom returns the key set by WithIdempotencyKey, if any.
+func IdempotencyKeyFrom(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key, ok && key != ""
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+type locationCtx struct{}
+
+// WithLocation makes calls made with ctx check dates against today in loc,
+// overriding the client's configured Location and PropertyLocation. Use it
+// for a one-off write to a property whose time zone the client does not
+// know.
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationCtx{}, loc)
+}
+
+// LocationFrom returns the location set by WithLocation, if any.
+func LocationFrom(ctx context.Context) (*time.Location, bool) {
+	loc, ok := ctx.Value(locationCtx{}).(*time.Location)
+	return loc, ok && loc != nil
+}
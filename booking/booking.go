@@ -0,0 +1,143 @@
+// Package booking builds multi-night stays on top of the per-day
+// availability commands, with all-or-nothing semantics.
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// StayError reports the night that stopped a Reserve or Release.
+// Nights applied before it have been undone unless RollbackErr is set,
+// in which case Unrestored lists the nights still carrying the change.
+// When Night failed without a server reply (a timeout or dropped
+// connection) it may have been applied; Unknown is then set and Night is
+// left as it is, for the caller to check, e.g. with GetPropRoomDay.
+type StayError struct {
+	Night       types.Date
+	Err         error // e.g. UNDERFLOW from DecRoomAvl
+	Unknown     bool  // whether Night itself was applied is not known
+	RollbackErr error
+	Unrestored  []types.Date
+}
+
+func (e *StayError) Error() string {
+	msg := fmt.Sprintf("night %s: %v", e.Night, e.Err)
+	if e.Unknown {
+		msg += "; night may have been applied"
+	}
+	if e.RollbackErr != nil {
+		nights := make([]string, len(e.Unrestored))
+		for i, n := range e.Unrestored {
+			nights[i] = n.String()
+		}
+		msg += fmt.Sprintf("; rollback failed for %s: %v", strings.Join(nights, ","), e.RollbackErr)
+	}
+	return msg
+}
+
+func (e *StayError) Unwrap() error { return e.Err }
+
+// Reserve takes qty rooms of roomType for every night in [checkIn, checkOut).
+// Nights are decremented in order; when one fails the nights already taken
+// are given back and a *StayError names the blocking night.
+//
+// A key set with api.WithIdempotencyKey names the stay: Reserve derives the
+// key of each night from it, so a Reserve whose outcome was lost, e.g. to
+// a crash, can be repeated with the same key without taking any night
+// twice. A Reserve that returned a StayError has been undone; retry it
+// with a new key, or none.
+func Reserve(ctx context.Context, c api.CacheClientAPIContext, propertyID, roomType string, checkIn, checkOut types.Date, qty uint8) error {
+	return apply(ctx, "reserve", propertyID, roomType, checkIn, checkOut, qty, c.DecRoomAvlCtx, c.IncRoomAvlCtx)
+}
+
+// Release gives back qty rooms for every night in [checkIn, checkOut),
+// undoing a Reserve. It is all-or-nothing in the same way.
+func Release(ctx context.Context, c api.CacheClientAPIContext, propertyID, roomType string, checkIn, checkOut types.Date, qty uint8) error {
+	return apply(ctx, "release", propertyID, roomType, checkIn, checkOut, qty, c.IncRoomAvlCtx, c.DecRoomAvlCtx)
+}
+
+type avlFunc func(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error)
+
+func apply(ctx context.Context, op, propertyID, roomType string, checkIn, checkOut types.Date, qty uint8, do, undo avlFunc) error {
+	nights, err := nights(checkIn, checkOut)
+	if err != nil {
+		return types.RzError("VALIDATION_ERROR: " + err.Error())
+	}
+	if qty == 0 {
+		return types.RzError("VALIDATION_ERROR: qty must be greater than 0")
+	}
+
+	// Each night gets its own key, so the client's automatic retries of a
+	// night are applied once.
+	stayKey, ok := api.IdempotencyKeyFrom(ctx)
+	if !ok {
+		stayKey = uuid.NewString()
+	}
+	stayKey += "/" + op
+
+	for i, night := range nights {
+		p := types.UpdRoomAvlPayload{PropertyID: propertyID, RoomType: roomType, Date: night, Amount: qty}
+		if _, err := do(api.WithIdempotencyKey(ctx, stayKey+"/"+night.String()), p); err != nil {
+			// A night that may have been applied is not resent: only a
+			// server that deduplicates by key would make that safe.
+			unrestored, rbErr := rollback(ctx, stayKey, p, nights[:i], undo)
+			return &StayError{Night: night, Err: err, Unknown: maybeApplied(err), RollbackErr: rbErr, Unrestored: unrestored}
+		}
+	}
+	return nil
+}
+
+// maybeApplied reports whether a write failing with err may still have
+// been applied: the server did not answer it, or answered with a retry
+// status that does not prove the write never ran.
+func maybeApplied(err error) bool {
+	var e *types.RoomzinError
+	if !errors.As(err, &e) {
+		return true
+	}
+	switch e.Kind {
+	case types.KindClient, types.KindRequest, types.KindConflict:
+		return false
+	}
+	return e.Code != "308" && e.Code != "405"
+}
+
+// rollback undoes the given nights even if ctx is already cancelled and
+// returns the nights it could not undo.
+func rollback(ctx context.Context, stayKey string, p types.UpdRoomAvlPayload, done []types.Date, undo avlFunc) ([]types.Date, error) {
+	ctx = context.WithoutCancel(ctx)
+	var unrestored []types.Date
+	var errs []error
+	for _, night := range done {
+		p.Date = night
+		if _, err := undo(api.WithIdempotencyKey(ctx, stayKey+"/"+night.String()+"/undo"), p); err != nil {
+			unrestored = append(unrestored, night)
+			errs = append(errs, fmt.Errorf("%s: %w", night, err))
+		}
+	}
+	return unrestored, errors.Join(errs...)
+}
+
+// nights lists every date in [checkIn, checkOut).
+func nights(checkIn, checkOut types.Date) ([]types.Date, error) {
+	switch {
+	case !checkIn.IsValid():
+		return nil, errors.New("a valid checkIn date is required")
+	case !checkOut.IsValid():
+		return nil, errors.New("a valid checkOut date is required")
+	case !checkOut.After(checkIn):
+		return nil, errors.New("checkOut must be after checkIn")
+	}
+	var out []types.Date
+	for d := range types.DateRange(checkIn, checkOut.AddDays(-1)) {
+		out = append(out, d)
+	}
+	return out, nil
+}
@@ -0,0 +1,197 @@
+package booking_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/booking"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/single"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+const (
+	propertyID = "hotel-1"
+	roomType   = "dbl"
+)
+
+func newClient(t *testing.T) api.CacheClientAPI {
+	t.Helper()
+	srv, err := roomzintest.NewServer(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	cfg := srv.SingleConfig()
+	c, err := single.New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	err = c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: propertyID, PropertyType: "hotel", Category: "c", Stars: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func setAvl(t *testing.T, c api.CacheClientAPI, d types.Date, avl uint8) {
+	t.Helper()
+	if err := c.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: propertyID, RoomType: roomType, Date: d, Availability: &avl}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func avl(t *testing.T, c api.CacheClientAPI, d types.Date) uint8 {
+	t.Helper()
+	day, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: propertyID, RoomType: roomType, Date: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return day.Availability
+}
+
+func TestReserveRetryAfterRollback(t *testing.T) {
+	c := newClient(t)
+	in := types.Today(nil, nil).AddDays(2)
+	out := in.AddDays(3)
+	for d := range types.DateRange(in, out.AddDays(-1)) {
+		setAvl(t, c, d, 1)
+	}
+	blocking := in.AddDays(2)
+	setAvl(t, c, blocking, 0)
+
+	ctx := api.WithIdempotencyKey(context.Background(), "stay-42")
+	err := booking.Reserve(ctx, c, propertyID, roomType, in, out, 1)
+	var se *booking.StayError
+	if !errors.As(err, &se) || se.Night != blocking || !types.IsRequest(err) {
+		t.Fatalf("Reserve = %v, want UNDERFLOW on %s", err, blocking)
+	}
+	for _, d := range []types.Date{in, in.AddDays(1)} {
+		if got := avl(t, c, d); got != 1 {
+			t.Fatalf("after rollback %s = %d, want 1", d, got)
+		}
+	}
+
+	if _, err := c.IncRoomAvl(types.UpdRoomAvlPayload{PropertyID: propertyID, RoomType: roomType, Date: blocking, Amount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	ctx = api.WithIdempotencyKey(context.Background(), "stay-43")
+	if err := booking.Reserve(ctx, c, propertyID, roomType, in, out, 1); err != nil {
+		t.Fatalf("retried Reserve: %v", err)
+	}
+	for d := range types.DateRange(in, out.AddDays(-1)) {
+		if got := avl(t, c, d); got != 0 {
+			t.Fatalf("after retry %s = %d, want 0", d, got)
+		}
+	}
+}
+
+// TestReserveRepeatedKey repeats a successful Reserve with its key, as a
+// caller that lost the first reply would, and checks no night is taken
+// twice.
+func TestReserveRepeatedKey(t *testing.T) {
+	c := newClient(t)
+	in := types.Today(nil, nil).AddDays(2)
+	out := in.AddDays(2)
+	for d := range types.DateRange(in, out.AddDays(-1)) {
+		setAvl(t, c, d, 2)
+	}
+
+	ctx := api.WithIdempotencyKey(context.Background(), "stay-42")
+	for range 2 {
+		if err := booking.Reserve(ctx, c, propertyID, roomType, in, out, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for d := range types.DateRange(in, out.AddDays(-1)) {
+		if got := avl(t, c, d); got != 1 {
+			t.Fatalf("%s = %d, want 1", d, got)
+		}
+	}
+}
+
+// flaky applies writes once per idempotency key, like a server, but can
+// skip chosen writes and drop their replies.
+type flaky struct {
+	api.CacheClientAPIContext
+	avl   map[types.Date]int
+	seen  map[string]uint8
+	drop  func(night types.Date, call int) (apply, reply bool)
+	calls map[types.Date]int
+}
+
+func newFlaky(nights ...types.Date) *flaky {
+	f := &flaky{avl: map[types.Date]int{}, seen: map[string]uint8{}, calls: map[types.Date]int{}}
+	for _, d := range nights {
+		f.avl[d] = 1
+	}
+	return f
+}
+
+func (f *flaky) DecRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	f.calls[p.Date]++
+	apply, reply := true, true
+	if f.drop != nil {
+		apply, reply = f.drop(p.Date, f.calls[p.Date])
+	}
+	key, _ := api.IdempotencyKeyFrom(ctx)
+	if _, ok := f.seen[key]; !ok && apply {
+		f.avl[p.Date] -= int(p.Amount)
+		f.seen[key] = uint8(f.avl[p.Date])
+	}
+	if !reply {
+		return 0, types.RzError("request timed out")
+	}
+	return f.seen[key], nil
+}
+
+func (f *flaky) IncRoomAvlCtx(_ context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	f.avl[p.Date] += int(p.Amount)
+	return uint8(f.avl[p.Date]), nil
+}
+
+func TestReserveAmbiguousNight(t *testing.T) {
+	in := types.Today(nil, nil).AddDays(2)
+	second := in.AddDays(1)
+
+	tests := []struct {
+		name    string
+		apply   bool // whether the second night's lost write landed
+		wantAvl int  // of the second night
+	}{
+		{name: "applied", apply: true, wantAvl: 0},
+		{name: "not applied", apply: false, wantAvl: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFlaky(in, second)
+			f.drop = func(night types.Date, _ int) (bool, bool) {
+				if night == second {
+					return tt.apply, false
+				}
+				return true, true
+			}
+			err := booking.Reserve(context.Background(), f, propertyID, roomType, in, in.AddDays(2), 1)
+			var se *booking.StayError
+			if !errors.As(err, &se) || se.Night != second {
+				t.Fatalf("Reserve = %v, want a StayError on %s", err, second)
+			}
+			if !se.Unknown {
+				t.Fatal("the unanswered night is not reported as unknown")
+			}
+			if f.calls[second] != 1 {
+				t.Fatalf("the unanswered night was sent %d times, want 1", f.calls[second])
+			}
+			if f.avl[in] != 1 {
+				t.Fatalf("first night = %d, want 1", f.avl[in])
+			}
+			if f.avl[second] != tt.wantAvl {
+				t.Fatalf("second night = %d, want %d", f.avl[second], tt.wantAvl)
+			}
+		})
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"time"
 
 	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/internal/batch"
 	"github.com/roomzin/roomzin-go/internal/cluster"
+	"github.com/roomzin/roomzin-go/internal/codecs"
 	"github.com/roomzin/roomzin-go/internal/command"
+	"github.com/roomzin/roomzin-go/internal/protocol"
 	"github.com/roomzin/roomzin-go/types"
 )
 
@@ -16,7 +19,7 @@ type client struct {
 	cfg     *ClusterConfig
 	ctx     context.Context
 	cancel  context.CancelFunc
-	codecs  *types.Codecs
+	codecs  *codecs.Cache
 }
 
 func New(cfg *ClusterConfig) (api.CacheClientAPI, error) {
@@ -36,6 +39,8 @@ func New(cfg *ClusterConfig) (api.CacheClientAPI, error) {
 		KeepAlive:         cfg.KeepAlive,
 		MaxActiveConns:    cfg.MaxActiveConns,
 		NodeProbeInterval: 2 * time.Second,
+		TLSConfig:         cfg.TLSConfig,
+		DisableWriteRetry: cfg.DisableWriteRetry,
 	}
 
 	clusterClient := cluster.NewHandler(icfg)
@@ -48,34 +53,36 @@ func New(cfg *ClusterConfig) (api.CacheClientAPI, error) {
 		cancel:  cancel,
 	}
 
+	c.codecs = codecs.NewCache(ctx, c.fetchCodecs, cfg.CodecsMaxAge, cfg.CodecsMaxStale)
 	c.handler.SetOnReconnectCallback(func() {
-		c.codecs = nil
+		c.codecs.Invalidate()
 	})
 
-	var err error
-	c.codecs, err = c.fetchCodecs()
-	if err != nil {
+	if _, err := c.codecs.Refresh(ctx); err != nil {
+		c.Close()
 		return nil, types.RzError(err)
 	}
 
 	return c, nil
 }
 
-func (c *client) getCodecs() *types.Codecs {
-	if c.codecs != nil {
-		return c.codecs
+// getCodecs returns the cached codecs, refreshed within the configured
+// staleness bounds, or a CODECS_UNAVAILABLE error.
+func (c *client) getCodecs(ctx context.Context) (*types.Codecs, error) {
+	codecs, err := c.codecs.Get(ctx)
+	if err != nil {
+		return nil, types.RzError(err)
 	}
-	c.codecs, _ = c.fetchCodecs()
-	return c.codecs
+	return codecs, nil
 }
 
-func (c *client) fetchCodecs() (*types.Codecs, error) {
+func (c *client) fetchCodecs(ctx context.Context) (*types.Codecs, error) {
 	req, err := command.BuildGetCodecsPayload()
 	if err != nil {
 		return nil, types.RzError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -90,9 +97,76 @@ func (c *client) fetchCodecs() (*types.Codecs, error) {
 	return result, nil
 }
 
+// callCtx bounds a call by the caller's ctx, the client lifetime and, when
+// ctx has no deadline of its own, by cfg.Timeout.
+func (c *client) callCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if _, ok := ctx.Deadline(); ok {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+	}
+	stop := context.AfterFunc(c.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// idempotencyKey returns the caller's key for this write, or "" when the
+// caller set none, in which case no key field is sent and a non-idempotent
+// write is not resent when its outcome is unclear.
+// The key is baked into the payload, so handler retries resend it as is.
+func idempotencyKey(ctx context.Context) string {
+	key, _ := api.IdempotencyKeyFrom(ctx)
+	return key
+}
+
+// setCurrency marks every day's price in avail as being in cur.
+func setCurrency(avail []types.PropertyAvail, cur types.Currency) {
+	for i := range avail {
+		for j := range avail[i].Days {
+			avail[i].Days[j].Currency = cur
+		}
+	}
+}
+
+// today returns the current date for a call on propertyID: in the ctx
+// location if set, else the property's, else the configured Location.
+func (c *client) today(ctx context.Context, propertyID string) types.Date {
+	loc, ok := api.LocationFrom(ctx)
+	if !ok && propertyID != "" && c.cfg.PropertyLocation != nil {
+		loc = c.cfg.PropertyLocation(propertyID)
+	}
+	if loc == nil {
+		loc = c.cfg.Location
+	}
+	return types.Today(c.cfg.Clock, loc)
+}
+
+// dateChecks are the Verify options for a call on propertyID: today for
+// that call and the booking horizon, taken from the config or else from
+// the server's codecs. A ctx from api.WithAnyDate skips both.
+func (c *client) dateChecks(ctx context.Context, propertyID string) []types.VerifyOption {
+	if api.AnyDateFrom(ctx) {
+		return []types.VerifyOption{types.AnyDate()}
+	}
+	horizon := c.cfg.BookingHorizon
+	if horizon == 0 {
+		if codecs, err := c.getCodecs(ctx); err == nil {
+			horizon = codecs.BookingHorizon
+		}
+	}
+	return []types.VerifyOption{types.AsOf(c.today(ctx, propertyID)), types.Horizon(horizon)}
+}
+
+func (c *client) WatchCodecs(fn func(old, new *types.Codecs)) (stop func()) {
+	return c.codecs.Watch(fn)
+}
+
 func (c *client) Close() error {
 	c.cancel()
-	return nil
+	return c.handler.Close()
 }
 
 // --------------------------------------------------
@@ -102,20 +176,134 @@ func (c *client) Close() error {
 // --------------------------------------------------
 
 func (c *client) GetCodecs() (*types.Codecs, error) {
-	if c.codecs != nil {
-		return c.codecs, nil
+	return c.GetCodecsCtx(c.ctx)
+}
+
+func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
+	return c.SearchPropCtx(c.ctx, p)
+}
+
+func (c *client) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	return c.SearchAvailCtx(c.ctx, p)
+}
+
+func (c *client) PropExist(propertyID string) (bool, error) {
+	return c.PropExistCtx(c.ctx, propertyID)
+}
+
+func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
+	return c.PropRoomExistCtx(c.ctx, p)
+}
+
+func (c *client) PropRoomList(propertyID string) ([]string, error) {
+	return c.PropRoomListCtx(c.ctx, propertyID)
+}
+
+func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]types.Date, error) {
+	return c.PropRoomDateListCtx(c.ctx, p)
+}
+
+func (c *client) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	return c.GetPropRoomDayCtx(c.ctx, p)
+}
+
+func (c *client) SetProp(p types.SetPropPayload) error {
+	return c.SetPropCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomPkg(p types.SetRoomPkgPayload) error {
+	return c.SetRoomPkgCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.SetRoomAvlCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomAvlIf(p types.SetRoomAvlIfPayload) (uint8, error) {
+	return c.SetRoomAvlIfCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomPkgRange(p types.SetRoomPkgRangePayload) ([]types.DateResult, error) {
+	return c.SetRoomPkgRangeCtx(c.ctx, p)
+}
+
+func (c *client) DelRoomRange(p types.DelRoomRangePayload) ([]types.DateResult, error) {
+	return c.DelRoomRangeCtx(c.ctx, p)
+}
+
+func (c *client) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.IncRoomAvlCtx(c.ctx, p)
+}
+
+func (c *client) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.DecRoomAvlCtx(c.ctx, p)
+}
+
+func (c *client) DelProp(propertyID string) error {
+	return c.DelPropCtx(c.ctx, propertyID)
+}
+
+func (c *client) DelSegment(segment string) error {
+	return c.DelSegmentCtx(c.ctx, segment)
+}
+
+func (c *client) DelPropDay(p types.DelPropDayRequest) error {
+	return c.DelPropDayCtx(c.ctx, p)
+}
+
+func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
+	return c.DelPropRoomCtx(c.ctx, p)
+}
+
+func (c *client) DelRoomDay(p types.DelRoomDayRequest) error {
+	return c.DelRoomDayCtx(c.ctx, p)
+}
+
+func (c *client) GetSegments() ([]types.SegmentInfo, error) {
+	return c.GetSegmentsCtx(c.ctx)
+}
+
+// NewBatch pipelines writes to the leader; see api.Batch.
+func (c *client) NewBatch() api.Batch {
+	return c.newBatch(c.ctx)
+}
+
+// newBatch returns a batch whose dates are checked as of today for ctx.
+func (c *client) newBatch(ctx context.Context) *batch.Batch {
+	getCodecs := func() (*types.Codecs, error) { return c.getCodecs(ctx) }
+	checks := func(propertyID string) []types.VerifyOption {
+		return append(c.dateChecks(ctx, propertyID), types.PricesIn(c.cfg.Currencies.ForProperty(propertyID)))
 	}
-	var err error
-	c.codecs, err = c.fetchCodecs()
+	return batch.New(getCodecs, checks, func(ctx context.Context, payloads [][]byte, nonIdempotent []bool) ([]protocol.RawResult, []error) {
+		ctx, cancel := c.callCtx(ctx)
+		defer cancel()
+		return c.handler.ExecuteBatch(ctx, payloads, nonIdempotent)
+	})
+}
+
+// --------------------------------------------------
+//
+//	context-aware API
+//
+// --------------------------------------------------
+
+// GetCodecsCtx fetches the server's codecs and refreshes the cached ones,
+// notifying WatchCodecs callbacks if their version changed.
+func (c *client) GetCodecsCtx(ctx context.Context) (*types.Codecs, error) {
+	codecs, err := c.codecs.Refresh(ctx)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
-	return c.codecs, nil
+	return codecs, nil
 }
 
 /* ----------  READ helpers (follower)  ---------- */
-func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SearchPropCtx(ctx context.Context, p types.SearchPropPayload) ([]string, error) {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Verify(codecs); err != nil {
 		return nil, types.RzError(err)
 	}
 	req, err := command.BuildSearchPropPayload(p)
@@ -123,7 +311,7 @@ func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
 		return nil, types.RzError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -138,8 +326,12 @@ func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
 	return result, nil
 }
 
-func (c *client) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SearchAvailCtx(ctx context.Context, p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Verify(codecs, append(c.dateChecks(ctx, ""), types.PricesIn(c.cfg.Currencies.For(p.Segment)))...); err != nil {
 		return nil, types.RzError(err)
 	}
 	req, err := command.BuildSearchAvailPayload(p)
@@ -147,7 +339,7 @@ func (c *client) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail,
 		return nil, types.RzError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -155,14 +347,15 @@ func (c *client) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail,
 		return nil, types.RzError(err)
 	}
 
-	result, err := command.ParseSearchAvailResp(c.getCodecs(), resp.Status, resp.Fields)
+	result, err := command.ParseSearchAvailResp(codecs, p.Date, c.today(ctx, ""), resp.Status, resp.Fields)
 	if err != nil {
 		return result, types.RzError(err)
 	}
+	setCurrency(result, c.cfg.Currencies.For(p.Segment))
 	return result, nil
 }
 
-func (c *client) PropExist(propertyID string) (bool, error) {
+func (c *client) PropExistCtx(ctx context.Context, propertyID string) (bool, error) {
 	if strings.TrimSpace(propertyID) == "" {
 		return false, types.RzError("VALIDATION_ERROR: propertyID is required")
 	}
@@ -171,7 +364,7 @@ func (c *client) PropExist(propertyID string) (bool, error) {
 		return false, err
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -186,7 +379,7 @@ func (c *client) PropExist(propertyID string) (bool, error) {
 	return result, nil
 }
 
-func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
+func (c *client) PropRoomExistCtx(ctx context.Context, p types.PropRoomExistPayload) (bool, error) {
 	if err := p.Verify(); err != nil {
 		return false, err
 	}
@@ -195,7 +388,7 @@ func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
 		return false, err
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -210,7 +403,7 @@ func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
 	return result, nil
 }
 
-func (c *client) PropRoomList(propertyID string) ([]string, error) {
+func (c *client) PropRoomListCtx(ctx context.Context, propertyID string) ([]string, error) {
 	if strings.TrimSpace(propertyID) == "" {
 		return nil, types.RzError("VALIDATION_ERROR: propertyID is required")
 	}
@@ -219,7 +412,7 @@ func (c *client) PropRoomList(propertyID string) ([]string, error) {
 		return nil, types.RzError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -234,7 +427,7 @@ func (c *client) PropRoomList(propertyID string) ([]string, error) {
 	return result, nil
 }
 
-func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]string, error) {
+func (c *client) PropRoomDateListCtx(ctx context.Context, p types.PropRoomDateListPayload) ([]types.Date, error) {
 	if err := p.Verify(); err != nil {
 		return nil, types.RzError(err)
 	}
@@ -243,7 +436,7 @@ func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]string, er
 		return nil, types.RzError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -258,8 +451,12 @@ func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]string, er
 	return result, nil
 }
 
-func (c *client) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) GetPropRoomDayCtx(ctx context.Context, p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return types.GetRoomDayResult{}, err
+	}
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
 		return types.GetRoomDayResult{}, err
 	}
 	req, err := command.BuildGetPropRoomDayPayload(p)
@@ -267,7 +464,7 @@ func (c *client) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResu
 		return types.GetRoomDayResult{}, err
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
@@ -275,25 +472,31 @@ func (c *client) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResu
 		return types.GetRoomDayResult{}, err
 	}
 
-	result, err := command.ParseGetPropRoomDayResp(c.getCodecs(), resp.Status, resp.Fields)
+	result, err := command.ParseGetPropRoomDayResp(codecs, resp.Status, resp.Fields)
 	if err != nil {
 		return result, types.RzError(err)
 	}
+	result.Currency = c.cfg.Currencies.ForProperty(p.PropertyID)
 	return result, nil
 }
 
 /* ----------  WRITE helpers (leader)  ---------- */
 
-func (c *client) SetProp(p types.SetPropPayload) error {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SetPropCtx(ctx context.Context, p types.SetPropPayload) error {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := p.Verify(codecs); err != nil {
 		return types.RzError(err)
 	}
 	req, err := command.BuildSetPropPayload(p)
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -308,16 +511,21 @@ func (c *client) SetProp(p types.SetPropPayload) error {
 	return nil
 }
 
-func (c *client) SetRoomPkg(p types.SetRoomPkgPayload) error {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SetRoomPkgCtx(ctx context.Context, p types.SetRoomPkgPayload) error {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := p.Verify(codecs, append(c.dateChecks(ctx, p.PropertyID), types.PricesIn(c.cfg.Currencies.ForProperty(p.PropertyID)))...); err != nil {
 		return types.RzError(err)
 	}
 	req, err := command.BuildSetRoomPkgPayload(p)
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -332,16 +540,17 @@ func (c *client) SetRoomPkg(p types.SetRoomPkgPayload) error {
 	return nil
 }
 
-func (c *client) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) SetRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return 0, types.RzError(err)
 	}
 	req, err := command.BuildSetRoomAvlPayload(p)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -356,19 +565,75 @@ func (c *client) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
 	return result, nil
 }
 
-func (c *client) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) SetRoomPkgRangeCtx(ctx context.Context, p types.SetRoomPkgRangePayload) ([]types.DateResult, error) {
+	days, err := p.Days()
+	if err != nil {
+		return nil, types.RzError(err)
+	}
+	b := c.newBatch(ctx)
+	dates := make([]types.Date, len(days))
+	for i, d := range days {
+		b.SetRoomPkg(d)
+		dates[i] = d.Date
+	}
+	return b.ExecDates(ctx, dates)
+}
+
+func (c *client) DelRoomRangeCtx(ctx context.Context, p types.DelRoomRangePayload) ([]types.DateResult, error) {
+	days, err := p.Days()
+	if err != nil {
+		return nil, types.RzError(err)
+	}
+	b := c.newBatch(ctx)
+	dates := make([]types.Date, len(days))
+	for i, d := range days {
+		b.DelRoomDay(d)
+		dates[i] = d.Date
+	}
+	return b.ExecDates(ctx, dates)
+}
+
+func (c *client) SetRoomAvlIfCtx(ctx context.Context, p types.SetRoomAvlIfPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return 0, types.RzError(err)
+	}
+	req, err := command.BuildSetRoomAvlIfPayload(p)
+	if err != nil {
+		return 0, types.RzError(err)
+	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
+
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	// A blind resend of a write that already landed would report a
+	// spurious CONFLICT, so this goes through the non-idempotent path.
+	resp, err := c.handler.ExecuteNonIdempotent(ctx, req)
+	if err != nil {
+		return 0, types.RzError(err)
+	}
+
+	result, err := command.ParseSetRoomAvlIfResp(resp.Status, resp.Fields)
+	if err != nil {
+		return result, types.RzError(err)
+	}
+	return result, nil
+}
+
+func (c *client) IncRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return 0, types.RzError(err)
 	}
 	req, err := command.BuildIncRoomAvlPayload(p)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
-	resp, err := c.handler.Execute(ctx, true, req)
+	resp, err := c.handler.ExecuteNonIdempotent(ctx, req)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
@@ -380,19 +645,20 @@ func (c *client) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
 	return result, nil
 }
 
-func (c *client) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) DecRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return 0, types.RzError(err)
 	}
 	req, err := command.BuildDecRoomAvlPayload(p)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
-	resp, err := c.handler.Execute(ctx, true, req)
+	resp, err := c.handler.ExecuteNonIdempotent(ctx, req)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
@@ -404,7 +670,7 @@ func (c *client) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
 	return result, nil
 }
 
-func (c *client) DelProp(propertyID string) error {
+func (c *client) DelPropCtx(ctx context.Context, propertyID string) error {
 	if strings.TrimSpace(propertyID) == "" {
 		return types.RzError("VALIDATION_ERROR: propertyID is required")
 	}
@@ -412,8 +678,9 @@ func (c *client) DelProp(propertyID string) error {
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -428,7 +695,7 @@ func (c *client) DelProp(propertyID string) error {
 	return nil
 }
 
-func (c *client) DelSegment(segment string) error {
+func (c *client) DelSegmentCtx(ctx context.Context, segment string) error {
 	if strings.TrimSpace(segment) == "" {
 		return types.RzError("VALIDATION_ERROR: segment is required")
 	}
@@ -436,8 +703,9 @@ func (c *client) DelSegment(segment string) error {
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -452,16 +720,17 @@ func (c *client) DelSegment(segment string) error {
 	return nil
 }
 
-func (c *client) DelPropDay(p types.DelPropDayRequest) error {
-	if err := p.Verify(); err != nil {
+func (c *client) DelPropDayCtx(ctx context.Context, p types.DelPropDayRequest) error {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return types.RzError(err)
 	}
 	req, err := command.BuildDelPropDayPayload(p)
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -476,7 +745,7 @@ func (c *client) DelPropDay(p types.DelPropDayRequest) error {
 	return nil
 }
 
-func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
+func (c *client) DelPropRoomCtx(ctx context.Context, p types.DelPropRoomPayload) error {
 	if err := p.Verify(); err != nil {
 		return types.RzError(err)
 	}
@@ -484,8 +753,9 @@ func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -500,16 +770,17 @@ func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
 	return nil
 }
 
-func (c *client) DelRoomDay(p types.DelRoomDayRequest) error {
-	if err := p.Verify(); err != nil {
+func (c *client) DelRoomDayCtx(ctx context.Context, p types.DelRoomDayRequest) error {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return types.RzError(err)
 	}
 	req, err := command.BuildDelRoomDayPayload(p)
 	if err != nil {
 		return types.RzError(err)
 	}
+	req = command.AppendIdempotencyKey(req, idempotencyKey(ctx))
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, true, req)
@@ -525,13 +796,13 @@ func (c *client) DelRoomDay(p types.DelRoomDayRequest) error {
 }
 
 /* ----------  MISC  ---------- */
-func (c *client) GetSegments() ([]types.SegmentInfo, error) {
+func (c *client) GetSegmentsCtx(ctx context.Context) ([]types.SegmentInfo, error) {
 	req, err := command.BuildGetSegmentsPayload()
 	if err != nil {
 		return nil, types.RzError(err)
 	}
 
-	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	ctx, cancel := c.callCtx(ctx)
 	defer cancel()
 
 	resp, err := c.handler.Execute(ctx, false, req)
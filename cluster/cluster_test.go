@@ -0,0 +1,306 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/cluster"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+func newCluster(t *testing.T, writeRetry bool) (*roomzintest.Cluster, api.CacheClientAPI, types.UpdRoomAvlPayload) {
+	t.Helper()
+	fc, err := roomzintest.NewCluster(roomzintest.ClusterOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fc.Close() })
+
+	cfg := fc.ClusterConfig()
+	cfg.DisableWriteRetry = !writeRetry
+	c, err := cluster.New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	d := types.Today(nil, nil).AddDays(1)
+	if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+		t.Fatal(err)
+	}
+	avl := uint8(10)
+	if err := c.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Availability: &avl}); err != nil {
+		t.Fatal(err)
+	}
+	return fc, c, types.UpdRoomAvlPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Amount: 1}
+}
+
+func TestElection(t *testing.T) {
+	fc, c, inc := newCluster(t, true)
+	if err := fc.Elect(1); err != nil {
+		t.Fatal(err)
+	}
+	// The old leader answers the write with 308 and the new one the
+	// read with 405; both are followed transparently.
+	if v, err := c.IncRoomAvl(inc); err != nil || v != 11 {
+		t.Fatalf("IncRoomAvl after election = %d, %v; want 11", v, err)
+	}
+	for range 5 {
+		if ok, err := c.PropExist("hotel-1"); err != nil || !ok {
+			t.Fatalf("PropExist after election = %v, %v", ok, err)
+		}
+	}
+	if fc.Node(1).Served() == 0 {
+		t.Fatal("the new leader served nothing")
+	}
+}
+
+func TestFailover(t *testing.T) {
+	fc, c, inc := newCluster(t, true)
+	leader, err := fc.Failover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader == fc.Node(0) {
+		t.Fatal("the dead leader was promoted")
+	}
+
+	// The client may send the write on the dead leader's connection
+	// before noticing it is gone; the key lets it resend the write.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if v, err := c.IncRoomAvlCtx(api.WithIdempotencyKey(ctx, "k1"), inc); err != nil || v != 11 {
+		t.Fatalf("IncRoomAvl after failover = %d, %v; want 11", v, err)
+	}
+	if ok, err := c.PropExistCtx(ctx, "hotel-1"); err != nil || !ok {
+		t.Fatalf("PropExist after failover = %v, %v", ok, err)
+	}
+}
+
+func TestThrottling(t *testing.T) {
+	tests := []struct {
+		name       string
+		writeRetry bool
+		key        string
+		failures   int
+		wantErr    bool
+	}{
+		{name: "short storm", writeRetry: true, key: "k1", failures: 2},
+		{name: "long storm", writeRetry: true, key: "k1", failures: 50, wantErr: true},
+		{name: "write retry disabled", writeRetry: false, key: "k1", failures: 1, wantErr: true},
+		{name: "without key", writeRetry: true, failures: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, c, inc := newCluster(t, tt.writeRetry)
+			fc.FailNext("429", tt.failures)
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = api.WithIdempotencyKey(ctx, tt.key)
+			}
+			v, err := c.IncRoomAvlCtx(ctx, inc)
+			if tt.wantErr {
+				if !types.IsCluster(err) {
+					t.Fatalf("IncRoomAvl = %d, %v; want a 429", v, err)
+				}
+				return
+			}
+			if err != nil || v != 11 {
+				t.Fatalf("IncRoomAvl = %d, %v; want 11", v, err)
+			}
+		})
+	}
+}
+
+// TestBatchRetry throttles the first two commands of a batch: the
+// idempotent one is always resent, the non-idempotent one only with a key.
+func TestBatchRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		want    uint8 // availability left
+		wantErr bool  // for the DecRoomAvl
+	}{
+		{name: "without key", want: 5, wantErr: true},
+		{name: "with key", key: "k1", want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, c, dec := newCluster(t, true)
+			fc.FailNext("429", 2)
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = api.WithIdempotencyKey(ctx, tt.key)
+			}
+			set := dec
+			set.Amount = 5
+			b := c.NewBatch()
+			b.SetRoomAvl(set)
+			b.DecRoomAvl(dec)
+			res, err := b.Exec(ctx)
+			if res[0].Err != nil || res[0].Availability != 5 {
+				t.Fatalf("SetRoomAvl = %d, %v; want 5", res[0].Availability, res[0].Err)
+			}
+			if tt.wantErr {
+				if !types.IsCluster(res[1].Err) || err != res[1].Err {
+					t.Fatalf("DecRoomAvl = %v, Exec = %v; want a 429 from both", res[1].Err, err)
+				}
+			} else if res[1].Err != nil || res[1].Availability != 4 {
+				t.Fatalf("DecRoomAvl = %d, %v; want 4", res[1].Availability, res[1].Err)
+			}
+
+			day, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: dec.PropertyID, RoomType: dec.RoomType, Date: dec.Date})
+			if err != nil || day.Availability != tt.want {
+				t.Fatalf("availability = %d, %v; want %d", day.Availability, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestDroppedWrite has the leader apply DecRoomAvl and drop the connection
+// instead of replying. Without a key the client must not resend it; with
+// one the resend is answered from the server's replay cache. Either way
+// each decrement lands once.
+func TestDroppedWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		batch   bool
+		want    uint8 // availability left
+		wantErr error
+	}{
+		{name: "without key", want: 9, wantErr: types.ErrOutcomeUnknown},
+		{name: "with key", key: "k1", want: 9},
+		// The drop also fails the second decrement, which the leader
+		// never read.
+		{name: "batch without key", batch: true, want: 9, wantErr: types.ErrOutcomeUnknown},
+		{name: "batch with key", key: "k1", batch: true, want: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fc, c, dec := newCluster(t, true)
+			fc.DropNext(1)
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = api.WithIdempotencyKey(ctx, tt.key)
+			}
+			var err error
+			if tt.batch {
+				b := c.NewBatch()
+				b.DecRoomAvl(dec)
+				b.DecRoomAvl(dec)
+				_, err = b.Exec(ctx)
+			} else {
+				_, err = c.DecRoomAvlCtx(ctx, dec)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("DecRoomAvl = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("DecRoomAvl = %v", err)
+			}
+
+			day, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: dec.PropertyID, RoomType: dec.RoomType, Date: dec.Date})
+			if err != nil || day.Availability != tt.want {
+				t.Fatalf("availability = %d, %v; want %d", day.Availability, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestShutdownCancelsProbes checks that a client giving up cancels the
+// cluster probes of a leader lookup still in flight, rather than leaving
+// them to run out HttpTimeout against a seed host that hangs.
+func TestShutdownCancelsProbes(t *testing.T) {
+	started, cancelled := make(chan struct{}, 16), make(chan struct{}, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+		cancelled <- struct{}{}
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	// New waits Timeout for the leader, which never answers, then shuts
+	// the client down.
+	_, err = cluster.New(&cluster.ClusterConfig{
+		SeedHosts:   u.Hostname(),
+		APIPort:     port,
+		TCPPort:     1,
+		AuthToken:   "token",
+		Timeout:     200 * time.Millisecond,
+		HttpTimeout: time.Minute,
+	})
+	if err == nil {
+		t.Fatal("New found a leader on a hanging seed host")
+	}
+	select {
+	case <-started:
+	default:
+		t.Fatal("no probe reached the seed host")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the probe outlived the client")
+	}
+}
+
+// TestCloseReleasesProbeConns checks that a client closing, here because
+// New found no leader, closes the keep-alive connections of its cluster
+// probes instead of leaving them in a pool it no longer uses.
+func TestCloseReleasesProbeConns(t *testing.T) {
+	var open atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("unavailable"))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		switch state {
+		case http.StateNew:
+			open.Add(1)
+		case http.StateClosed, http.StateHijacked:
+			open.Add(-1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	_, err = cluster.New(&cluster.ClusterConfig{
+		SeedHosts:   u.Hostname(),
+		APIPort:     port,
+		TCPPort:     1,
+		AuthToken:   "token",
+		Timeout:     200 * time.Millisecond,
+		HttpTimeout: time.Minute,
+	})
+	if err == nil {
+		t.Fatal("New found a leader on an unavailable seed host")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for open.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d probe connections left open", open.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,7 +1,9 @@
 package cluster
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -16,7 +18,38 @@ type ClusterConfig struct {
 	Timeout        time.Duration
 	HttpTimeout    time.Duration
 	KeepAlive      time.Duration
-	MaxActiveConns int // hard cap on open TCP connections
+	MaxActiveConns int         // hard cap on open TCP connections
+	TLSConfig      *tls.Config // nil means plaintext TCP and http://
+
+	// DisableWriteRetry stops IncRoomAvl/DecRoomAvl from being resent on
+	// 503, 429 or a dropped connection, where the first attempt may already
+	// have been applied. Writes without an api.WithIdempotencyKey key are
+	// never resent in those cases.
+	DisableWriteRetry bool
+
+	// Clock tells the time for date checks; nil means types.SystemClock.
+	Clock types.Clock
+	// Location is the zone "today" is taken in; nil means UTC.
+	Location *time.Location
+	// PropertyLocation, when set, returns the zone of a property, so dates
+	// are checked against the property's local day. A nil result falls
+	// back to Location.
+	PropertyLocation func(propertyID string) *time.Location
+	// BookingHorizon is how many days past today dates may be. Zero
+	// means the horizon the server advertises in GETCODECS, or
+	// types.DefaultBookingHorizon when it advertises none.
+	BookingHorizon int
+
+	// Currencies says which currency FinalPrice values are in. Prices
+	// set with SetPrice must be in the call's currency, and results carry
+	// it. The zero value leaves prices as bare minor units.
+	Currencies types.Currencies
+
+	// CodecsMaxAge is how long fetched codecs are used before they are
+	// refreshed in the background, and CodecsMaxStale how long they may
+	// still be used while refreshing fails. Zero means 5 and 30 minutes.
+	CodecsMaxAge   time.Duration
+	CodecsMaxStale time.Duration
 }
 
 type ClusterConfigBuilder struct {
@@ -62,6 +95,66 @@ func (b *ClusterConfigBuilder) WithKeepAlive(d time.Duration) *ClusterConfigBuil
 	return b
 }
 
+// WithTLSConfig enables TLS on both the framed TCP connections and the
+// HTTP discovery API (https://). Set RootCAs for a private CA and
+// Certificates for client-certificate (mutual TLS) auth.
+func (b *ClusterConfigBuilder) WithTLSConfig(cfg *tls.Config) *ClusterConfigBuilder {
+	b.config.TLSConfig = cfg
+	return b
+}
+
+// WithWriteRetry controls automatic resending of non-idempotent writes
+// (IncRoomAvl, DecRoomAvl) on 503, 429 or a dropped connection. It is
+// enabled by default but only applies to calls carrying a key from
+// api.WithIdempotencyKey, which the server uses to apply a resent write
+// once; other such writes are never resent.
+func (b *ClusterConfigBuilder) WithWriteRetry(enabled bool) *ClusterConfigBuilder {
+	b.config.DisableWriteRetry = !enabled
+	return b
+}
+
+// WithClock sets the clock date checks use; tests pin "today" with it.
+func (b *ClusterConfigBuilder) WithClock(clock types.Clock) *ClusterConfigBuilder {
+	b.config.Clock = clock
+	return b
+}
+
+// WithLocation sets the time zone "today" is taken in for date checks.
+func (b *ClusterConfigBuilder) WithLocation(loc *time.Location) *ClusterConfigBuilder {
+	b.config.Location = loc
+	return b
+}
+
+// WithPropertyLocation looks up each property's time zone for date
+// checks, overriding WithLocation where it returns non-nil.
+func (b *ClusterConfigBuilder) WithPropertyLocation(fn func(propertyID string) *time.Location) *ClusterConfigBuilder {
+	b.config.PropertyLocation = fn
+	return b
+}
+
+// WithBookingHorizon sets how many days ahead dates may be, overriding
+// the horizon the server advertises.
+func (b *ClusterConfigBuilder) WithBookingHorizon(days int) *ClusterConfigBuilder {
+	b.config.BookingHorizon = days
+	return b
+}
+
+// WithCurrencies sets the currency of FinalPrice values, per segment or
+// for the whole client.
+func (b *ClusterConfigBuilder) WithCurrencies(c types.Currencies) *ClusterConfigBuilder {
+	b.config.Currencies = c
+	return b
+}
+
+// WithCodecsStaleness bounds how old the cached codecs may get: past
+// maxAge they are refreshed, and past maxStale a call waits for the
+// refresh and fails if it does.
+func (b *ClusterConfigBuilder) WithCodecsStaleness(maxAge, maxStale time.Duration) *ClusterConfigBuilder {
+	b.config.CodecsMaxAge = maxAge
+	b.config.CodecsMaxStale = maxStale
+	return b
+}
+
 func (b *ClusterConfigBuilder) Build() (ClusterConfig, error) {
 	if err := b.validate(); err != nil {
 		return ClusterConfig{}, types.RzError(err, types.KindClient)
@@ -83,6 +176,9 @@ func (b *ClusterConfigBuilder) validate() error {
 	if b.config.AuthToken == "" {
 		errs = append(errs, errors.New("authentication requires a token"))
 	}
+	if b.config.BookingHorizon < 0 || b.config.BookingHorizon > types.MaxBookingHorizon {
+		errs = append(errs, fmt.Errorf("booking horizon must be between 0 and %d days", types.MaxBookingHorizon))
+	}
 	if len(errs) == 0 {
 		return nil
 	}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// runFunc executes a parsed command. A non-nil result is printed even when
+// the error is set, so partial range results and the availability behind a
+// conflict still reach the user.
+type runFunc func(ctx context.Context, c api.CacheClientAPI) (any, error)
+
+// command maps one CacheClientAPI method onto a flag set. bind registers
+// flags that write straight into the method's payload and returns the call.
+// Required fields are left to the payload's own Verify. verb is the wire
+// command name the shell accepts; the range commands, which expand to one
+// wire command per day, get a name of their own.
+type command struct {
+	name string
+	verb string
+	help string
+	bind func(fs *flag.FlagSet) runFunc
+}
+
+// availability and exists tag scalar results for render.
+type (
+	availability uint8
+	exists       bool
+)
+
+var commands = []command{
+	{"codecs", "GETCODECS", "list the rate features known to the server", bindCodecs},
+	{"set-prop", "SETPROP", "create or replace a property", bindSetProp},
+	{"search-prop", "SEARCHPROP", "search properties in a segment", bindSearchProp},
+	{"search-avail", "SEARCHAVAIL", "search room availability over dates", bindSearchAvail},
+	{"set-pkg", "SETROOMPKG", "set a room's availability, price and rate features for a day", bindSetPkg},
+	{"set-pkg-range", "SETROOMPKGRANGE", "set a room package for every day in a date range", bindSetPkgRange},
+	{"set-avl", "SETROOMAVL", "set a room's availability for a day", bindUpdAvl("set")},
+	{"set-avl-if", "SETROOMAVLIF", "set availability only if it still equals -expected", bindSetAvlIf},
+	{"inc-avl", "INCROOMAVL", "increase a room's availability for a day", bindUpdAvl("inc")},
+	{"dec-avl", "DECROOMAVL", "decrease a room's availability for a day", bindUpdAvl("dec")},
+	{"prop-exist", "PROPEXIST", "check whether a property exists", bindPropExist},
+	{"room-exist", "PROPROOMEXIST", "check whether a property has a room type", bindRoomExist},
+	{"rooms", "PROPROOMLIST", "list a property's room types", bindRooms},
+	{"room-dates", "PROPROOMDATELIST", "list the dates stored for a room type", bindRoomDates},
+	{"get-room-day", "GETPROPROOMDAY", "show a room's availability, price and rate features for a day", bindGetRoomDay},
+	{"segments", "GETSEGMENTS", "list segments with their property counts", bindSegments},
+	{"del-prop", "DELPROP", "delete a property", bindDelProp},
+	{"del-segment", "DELSEGMENT", "delete a segment and all its properties", bindDelSegment},
+	{"del-prop-day", "DELPROPDAY", "delete every room of a property for a day", bindDelPropDay},
+	{"del-room", "DELPROPROOM", "delete a room type from a property", bindDelRoom},
+	{"del-room-day", "DELROOMDAY", "delete a room's data for a day", bindDelRoomDay},
+	{"del-room-range", "DELROOMRANGE", "delete a room's data for every day in a date range", bindDelRoomRange},
+	{"import", "IMPORT", "bulk load properties or room packages from CSV or JSONL", bindImport},
+	{"export", "EXPORT", "write a segment to a JSONL snapshot", bindExport},
+	{"restore", "RESTORE", "replay a JSONL snapshot into the connected server", bindRestore},
+	{"copy", "COPY", "copy a segment to another server or cluster", bindCopy},
+	{"reconcile", "RECONCILE", "bring room days in line with a desired inventory file", bindReconcile},
+}
+
+// lookupCommand finds a command by CLI name or, ignoring case, by verb.
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name || strings.EqualFold(c.verb, name) {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// cliUsage prints a command's flags in command-line form.
+func (c command) cliUsage(fs *flag.FlagSet) {
+	fmt.Fprintf(fs.Output(), "Usage: roomzin %s [flags]\n\n%s.\n\nFlags:\n", c.name, c.help)
+	fs.PrintDefaults()
+}
+
+// parse binds and parses args, rejecting stray positional arguments.
+// usage prints help for the flag set on -h or a parse error.
+func (c command) parse(args []string, out io.Writer, usage func(c command, fs *flag.FlagSet)) (runFunc, error) {
+	fs := newFlagSet(c.name, out)
+	run := c.bind(fs)
+	fs.Usage = func() { usage(c, fs) }
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		err := fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		fmt.Fprintln(out, err)
+		fs.Usage()
+		return nil, err
+	}
+	return run, nil
+}
+
+// value drops v when err is set, so failed calls print nothing.
+func value[T any](v T, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ---------- flag groups ----------
+
+func propIDFlag(fs *flag.FlagSet, p *string) {
+	fs.StringVar(p, "id", "", "property ID")
+}
+
+func roomFlags(fs *flag.FlagSet, id, room *string) {
+	propIDFlag(fs, id)
+	fs.StringVar(room, "room", "", "room type")
+}
+
+func roomDayFlags(fs *flag.FlagSet, id, room *string, date *types.Date) {
+	roomFlags(fs, id, room)
+	fs.TextVar(date, "date", types.Date{}, "date as YYYY-MM-DD")
+}
+
+func rangeFlags(fs *flag.FlagSet, from, to *types.Date, weekdays *types.WeekdayMask) {
+	fs.TextVar(from, "from", types.Date{}, "first date as YYYY-MM-DD")
+	fs.TextVar(to, "to", types.Date{}, "last date as YYYY-MM-DD, inclusive")
+	fs.Var(weekdaysVar(weekdays), "weekdays", "only these weekdays, e.g. fri,sat (default every day)")
+}
+
+// ---------- commands ----------
+
+func bindCodecs(*flag.FlagSet) runFunc {
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.GetCodecsCtx(ctx))
+	}
+}
+
+func bindSetProp(fs *flag.FlagSet) runFunc {
+	var p types.SetPropPayload
+	fs.StringVar(&p.Segment, "segment", "", "segment")
+	fs.StringVar(&p.Area, "area", "", "area")
+	propIDFlag(fs, &p.PropertyID)
+	fs.StringVar(&p.PropertyType, "type", "", "property type")
+	fs.StringVar(&p.Category, "category", "", "category")
+	fs.Var(u8Var(&p.Stars), "stars", "stars, 1-5")
+	fs.Float64Var(&p.Latitude, "lat", 0, "latitude")
+	fs.Float64Var(&p.Longitude, "lon", 0, "longitude")
+	fs.Var(listVar(&p.Amenities), "amenities", "comma separated amenities")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.SetPropCtx(ctx, p)
+	}
+}
+
+func bindSearchProp(fs *flag.FlagSet) runFunc {
+	var p types.SearchPropPayload
+	fs.StringVar(&p.Segment, "segment", "", "segment")
+	fs.Var(optStringVar(&p.Area), "area", "area")
+	fs.Var(optStringVar(&p.Type), "type", "property type")
+	fs.Var(optU8Var(&p.Stars), "stars", "stars, 1-5")
+	fs.Var(optStringVar(&p.Category), "category", "category")
+	fs.Var(optListVar(&p.Amenities), "amenities", "comma separated amenities, all required")
+	fs.Var(optF64Var(&p.Latitude), "lat", "latitude; sorts by distance when longitude is also set")
+	fs.Var(optF64Var(&p.Longitude), "lon", "longitude")
+	fs.Var(optU64Var(&p.Limit), "limit", "maximum number of results")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.SearchPropCtx(ctx, p))
+	}
+}
+
+func bindSearchAvail(fs *flag.FlagSet) runFunc {
+	var p types.SearchAvailPayload
+	fs.StringVar(&p.Segment, "segment", "", "segment")
+	fs.StringVar(&p.RoomType, "room", "", "room type")
+	fs.Var(datesVar(&p.Date), "dates", "dates, comma separated or ranges like 2026-11-01..2026-11-03")
+	fs.Var(optStringVar(&p.Area), "area", "area")
+	fs.Var(optStringVar(&p.PropertyID), "id", "property ID")
+	fs.Var(optStringVar(&p.Type), "type", "property type")
+	fs.Var(optU8Var(&p.Stars), "stars", "stars, 1-5")
+	fs.Var(optStringVar(&p.Category), "category", "category")
+	fs.Var(listVar(&p.Amenities), "amenities", "comma separated amenities, all required")
+	fs.Var(optF64Var(&p.Latitude), "lat", "latitude; sorts by distance when longitude is also set")
+	fs.Var(optF64Var(&p.Longitude), "lon", "longitude")
+	fs.Var(optU8Var(&p.Availability), "avail", "minimum availability on every date")
+	fs.Var(optU32Var(&p.FinalPrice), "price", "maximum final price on every date")
+	fs.Var(listVar(&p.RateFeature), "features", "comma separated rate features, all required")
+	fs.Var(optU64Var(&p.Limit), "limit", "maximum number of properties")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.SearchAvailCtx(ctx, p))
+	}
+}
+
+func bindSetPkg(fs *flag.FlagSet) runFunc {
+	var p types.SetRoomPkgPayload
+	roomDayFlags(fs, &p.PropertyID, &p.RoomType, &p.Date)
+	fs.Var(optU8Var(&p.Availability), "avail", "availability")
+	fs.Var(optU32Var(&p.FinalPrice), "price", "final price")
+	fs.Var(listVar(&p.RateFeature), "features", "comma separated rate features")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.SetRoomPkgCtx(ctx, p)
+	}
+}
+
+func bindSetPkgRange(fs *flag.FlagSet) runFunc {
+	var p types.SetRoomPkgRangePayload
+	roomFlags(fs, &p.PropertyID, &p.RoomType)
+	rangeFlags(fs, &p.From, &p.To, &p.Weekdays)
+	fs.Var(optU8Var(&p.Availability), "avail", "availability")
+	fs.Var(optU32Var(&p.FinalPrice), "price", "final price")
+	fs.Var(listVar(&p.RateFeature), "features", "comma separated rate features")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		res, err := c.SetRoomPkgRangeCtx(ctx, p)
+		if len(res) == 0 {
+			return nil, err
+		}
+		return res, err
+	}
+}
+
+// bindUpdAvl serves set-avl, inc-avl and dec-avl, which share a payload.
+func bindUpdAvl(op string) func(fs *flag.FlagSet) runFunc {
+	return func(fs *flag.FlagSet) runFunc {
+		var p types.UpdRoomAvlPayload
+		roomDayFlags(fs, &p.PropertyID, &p.RoomType, &p.Date)
+		fs.Var(u8Var(&p.Amount), "amount", "amount")
+		return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+			call := c.SetRoomAvlCtx
+			switch op {
+			case "inc":
+				call = c.IncRoomAvlCtx
+			case "dec":
+				call = c.DecRoomAvlCtx
+			}
+			n, err := call(ctx, p)
+			return value(availability(n), err)
+		}
+	}
+}
+
+func bindSetAvlIf(fs *flag.FlagSet) runFunc {
+	var p types.SetRoomAvlIfPayload
+	roomDayFlags(fs, &p.PropertyID, &p.RoomType, &p.Date)
+	fs.Var(u8Var(&p.Expected), "expected", "availability the write is conditional on")
+	fs.Var(u8Var(&p.Amount), "amount", "new availability")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		n, err := c.SetRoomAvlIfCtx(ctx, p)
+		if err != nil && !types.IsConflict(err) {
+			return nil, err
+		}
+		return availability(n), err
+	}
+}
+
+func bindPropExist(fs *flag.FlagSet) runFunc {
+	var id string
+	propIDFlag(fs, &id)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		ok, err := c.PropExistCtx(ctx, id)
+		return value(exists(ok), err)
+	}
+}
+
+func bindRoomExist(fs *flag.FlagSet) runFunc {
+	var p types.PropRoomExistPayload
+	roomFlags(fs, &p.PropertyID, &p.RoomType)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		ok, err := c.PropRoomExistCtx(ctx, p)
+		return value(exists(ok), err)
+	}
+}
+
+func bindRooms(fs *flag.FlagSet) runFunc {
+	var id string
+	propIDFlag(fs, &id)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.PropRoomListCtx(ctx, id))
+	}
+}
+
+func bindRoomDates(fs *flag.FlagSet) runFunc {
+	var p types.PropRoomDateListPayload
+	roomFlags(fs, &p.PropertyID, &p.RoomType)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.PropRoomDateListCtx(ctx, p))
+	}
+}
+
+func bindGetRoomDay(fs *flag.FlagSet) runFunc {
+	var p types.GetRoomDayRequest
+	roomDayFlags(fs, &p.PropertyID, &p.RoomType, &p.Date)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.GetPropRoomDayCtx(ctx, p))
+	}
+}
+
+func bindSegments(*flag.FlagSet) runFunc {
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return value(c.GetSegmentsCtx(ctx))
+	}
+}
+
+func bindDelProp(fs *flag.FlagSet) runFunc {
+	var id string
+	propIDFlag(fs, &id)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.DelPropCtx(ctx, id)
+	}
+}
+
+func bindDelSegment(fs *flag.FlagSet) runFunc {
+	var segment string
+	fs.StringVar(&segment, "segment", "", "segment")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.DelSegmentCtx(ctx, segment)
+	}
+}
+
+func bindDelPropDay(fs *flag.FlagSet) runFunc {
+	var p types.DelPropDayRequest
+	propIDFlag(fs, &p.PropertyID)
+	fs.TextVar(&p.Date, "date", types.Date{}, "date as YYYY-MM-DD")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.DelPropDayCtx(ctx, p)
+	}
+}
+
+func bindDelRoom(fs *flag.FlagSet) runFunc {
+	var p types.DelPropRoomPayload
+	roomFlags(fs, &p.PropertyID, &p.RoomType)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.DelPropRoomCtx(ctx, p)
+	}
+}
+
+func bindDelRoomDay(fs *flag.FlagSet) runFunc {
+	var p types.DelRoomDayRequest
+	roomDayFlags(fs, &p.PropertyID, &p.RoomType, &p.Date)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		return nil, c.DelRoomDayCtx(ctx, p)
+	}
+}
+
+func bindDelRoomRange(fs *flag.FlagSet) runFunc {
+	var p types.DelRoomRangePayload
+	roomFlags(fs, &p.PropertyID, &p.RoomType)
+	rangeFlags(fs, &p.From, &p.To, &p.Weekdays)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		res, err := c.DelRoomRangeCtx(ctx, p)
+		if len(res) == 0 {
+			return nil, err
+		}
+		return res, err
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// newFlagSet returns a flag set that reports errors instead of exiting,
+// so both the CLI and the shell can use it.
+func newFlagSet(name string, out io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(out)
+	return fs
+}
+
+// parseUint parses an unsigned flag value that must fit in bits.
+func parseUint(s string, bits int) (uint64, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 10, bits)
+	if err != nil {
+		var ne *strconv.NumError
+		if errors.As(err, &ne) && errors.Is(ne.Err, strconv.ErrRange) {
+			return 0, fmt.Errorf("%s out of range (max %d)", s, uint64(1)<<bits-1)
+		}
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return v, nil
+}
+
+type u8Value struct{ p *uint8 }
+
+func u8Var(p *uint8) *u8Value { return &u8Value{p} }
+
+func (v *u8Value) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v.p))
+}
+
+func (v *u8Value) Set(s string) error {
+	n, err := parseUint(s, 8)
+	*v.p = uint8(n)
+	return err
+}
+
+// optU8Value fills an optional *uint8 payload field once the flag is set.
+type optU8Value struct{ p **uint8 }
+
+func optU8Var(p **uint8) *optU8Value { return &optU8Value{p} }
+
+func (v *optU8Value) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.Itoa(int(**v.p))
+}
+
+func (v *optU8Value) Set(s string) error {
+	n, err := parseUint(s, 8)
+	if err != nil {
+		return err
+	}
+	u := uint8(n)
+	*v.p = &u
+	return nil
+}
+
+type optU32Value struct{ p **uint32 }
+
+func optU32Var(p **uint32) *optU32Value { return &optU32Value{p} }
+
+func (v *optU32Value) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(**v.p), 10)
+}
+
+func (v *optU32Value) Set(s string) error {
+	n, err := parseUint(s, 32)
+	if err != nil {
+		return err
+	}
+	u := uint32(n)
+	*v.p = &u
+	return nil
+}
+
+type optU64Value struct{ p **uint64 }
+
+func optU64Var(p **uint64) *optU64Value { return &optU64Value{p} }
+
+func (v *optU64Value) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.FormatUint(**v.p, 10)
+}
+
+func (v *optU64Value) Set(s string) error {
+	n, err := parseUint(s, 64)
+	if err != nil {
+		return err
+	}
+	*v.p = &n
+	return nil
+}
+
+type optF64Value struct{ p **float64 }
+
+func optF64Var(p **float64) *optF64Value { return &optF64Value{p} }
+
+func (v *optF64Value) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(**v.p, 'f', -1, 64)
+}
+
+func (v *optF64Value) Set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v.p = &f
+	return nil
+}
+
+type optStringValue struct{ p **string }
+
+func optStringVar(p **string) *optStringValue { return &optStringValue{p} }
+
+func (v *optStringValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return **v.p
+}
+
+func (v *optStringValue) Set(s string) error {
+	*v.p = &s
+	return nil
+}
+
+// listValue collects comma separated values; repeating the flag appends.
+type listValue struct{ p *[]string }
+
+func listVar(p *[]string) *listValue { return &listValue{p} }
+
+func (v *listValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strings.Join(*v.p, ",")
+}
+
+func (v *listValue) Set(s string) error {
+	*v.p = append(*v.p, splitList(s)...)
+	return nil
+}
+
+// mapValue collects comma separated old=new pairs; it may be repeated.
+type mapValue struct{ p *map[string]string }
+
+func mapVar(p *map[string]string) *mapValue { return &mapValue{p} }
+
+func (v *mapValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*v.p))
+	for k, val := range *v.p {
+		pairs = append(pairs, k+"="+val)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v *mapValue) Set(s string) error {
+	if *v.p == nil {
+		*v.p = make(map[string]string)
+	}
+	for _, pair := range splitList(s) {
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return fmt.Errorf("invalid mapping %q, want old=new", pair)
+		}
+		(*v.p)[from] = to
+	}
+	return nil
+}
+
+// optListValue fills an optional *[]string payload field.
+type optListValue struct{ p **[]string }
+
+func optListVar(p **[]string) *optListValue { return &optListValue{p} }
+
+func (v *optListValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return strings.Join(**v.p, ",")
+}
+
+func (v *optListValue) Set(s string) error {
+	if *v.p == nil {
+		*v.p = new([]string)
+	}
+	**v.p = append(**v.p, splitList(s)...)
+	return nil
+}
+
+// datesValue accepts comma separated dates and inclusive ranges such as
+// "2026-11-01..2026-11-03".
+type datesValue struct{ p *[]types.Date }
+
+func datesVar(p *[]types.Date) *datesValue { return &datesValue{p} }
+
+func (v *datesValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	s := make([]string, len(*v.p))
+	for i, d := range *v.p {
+		s[i] = d.String()
+	}
+	return strings.Join(s, ",")
+}
+
+func (v *datesValue) Set(s string) error {
+	dates, err := expandDateList(s)
+	if err != nil {
+		return err
+	}
+	*v.p = append(*v.p, dates...)
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// maxDateRange bounds a..b expansion to the longest booking horizon.
+const maxDateRange = types.MaxBookingHorizon + 1
+
+func expandDateList(s string) ([]types.Date, error) {
+	var out []types.Date
+	for _, part := range splitList(s) {
+		from, to, isRange := strings.Cut(part, "..")
+		if !isRange {
+			d, err := types.ParseDate(part)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, d)
+			continue
+		}
+		start, err := types.ParseDate(strings.TrimSpace(from))
+		if err != nil {
+			return nil, err
+		}
+		end, err := types.ParseDate(strings.TrimSpace(to))
+		if err != nil {
+			return nil, err
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("range %s ends before it starts", part)
+		}
+		for d := range types.DateRange(start, end) {
+			if len(out) >= maxDateRange {
+				return nil, fmt.Errorf("more than %d dates", maxDateRange)
+			}
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+type weekdaysValue struct{ p *types.WeekdayMask }
+
+func weekdaysVar(p *types.WeekdayMask) *weekdaysValue { return &weekdaysValue{p} }
+
+func (v *weekdaysValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return v.p.String()
+}
+
+func (v *weekdaysValue) Set(s string) error {
+	m, err := types.ParseWeekdays(s)
+	*v.p = m
+	return err
+}
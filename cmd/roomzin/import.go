@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/importer"
+)
+
+func bindImport(fs *flag.FlagSet) runFunc {
+	var (
+		file, kind, format, rejects string
+		concurrency                 int
+	)
+	fs.StringVar(&file, "file", "", "CSV or JSONL input file")
+	fs.StringVar(&kind, "kind", "", "row kind: property or package")
+	fs.StringVar(&format, "format", "", "csv or jsonl (default from the file extension)")
+	fs.IntVar(&concurrency, "concurrency", importer.DefaultConcurrency, "writes in flight")
+	fs.StringVar(&rejects, "rejects", "", "reject file, one JSON line per failed row (default <file>.rejects.jsonl)")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		opts := importer.Options{Concurrency: concurrency}
+		switch kind {
+		case "property":
+			opts.Kind = importer.Properties
+		case "package":
+			opts.Kind = importer.RoomPackages
+		default:
+			return nil, errors.New("-kind must be property or package")
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+		}
+		switch format {
+		case "csv":
+			opts.Format = importer.CSV
+		case "jsonl", "ndjson":
+			opts.Format = importer.JSONL
+		default:
+			return nil, errors.New("-format must be csv or jsonl")
+		}
+
+		in, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+		if rejects == "" {
+			rejects = file + ".rejects.jsonl"
+		}
+		rw := &lazyFile{path: rejects}
+		defer rw.Close()
+		opts.Rejects = rw
+
+		sum, err := importer.Import(ctx, c, in, opts)
+		if err != nil && sum.Rows == 0 {
+			return nil, err
+		}
+		if err == nil && sum.Rejected > 0 {
+			err = fmt.Errorf("%d of %d rows rejected, see %s", sum.Rejected, sum.Rows, rejects)
+		}
+		return sum, err
+	}
+}
+
+// lazyFile creates its file on the first write, so a clean import leaves
+// no empty reject file behind.
+type lazyFile struct {
+	path string
+	f    *os.File
+}
+
+func (l *lazyFile) Write(p []byte) (int, error) {
+	if l.f == nil {
+		f, err := os.Create(l.path)
+		if err != nil {
+			return 0, err
+		}
+		l.f = f
+	}
+	return l.f.Write(p)
+}
+
+func (l *lazyFile) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
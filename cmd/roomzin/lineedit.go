@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errInterrupt is returned by readLine when the user presses Ctrl-C.
+var errInterrupt = errors.New("interrupted")
+
+// lineReader reads shell input one line at a time.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+	addHistory(line string)
+}
+
+// plainReader reads lines from a pipe or file, without editing.
+type plainReader struct {
+	in *bufio.Reader
+}
+
+func (r *plainReader) readLine(string) (string, error) {
+	line, err := r.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (r *plainReader) addHistory(string) {}
+
+// completeFunc returns completions for the word ending at the end of
+// prefix: the byte offset where that word starts and the candidates that
+// replace it.
+type completeFunc func(prefix string) (start int, cands []string)
+
+// editor is a minimal emacs-style line editor for a terminal in raw mode:
+// cursor movement, history on the arrow keys and Tab completion.
+type editor struct {
+	fd       uintptr
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete completeFunc
+}
+
+func newEditor(in *os.File, out io.Writer, history []string, complete completeFunc) *editor {
+	return &editor{fd: in.Fd(), in: bufio.NewReader(in), out: out, history: history, complete: complete}
+}
+
+func (e *editor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// lineState is the line being edited.
+type lineState struct {
+	buf []rune
+	pos int
+}
+
+func (s *lineState) insert(rs []rune) {
+	s.buf = append(s.buf[:s.pos], append(rs, s.buf[s.pos:]...)...)
+	s.pos += len(rs)
+}
+
+func (s *lineState) set(line string) {
+	s.buf = []rune(line)
+	s.pos = len(s.buf)
+}
+
+func (s *lineState) deleteRange(from, to int) {
+	s.buf = append(s.buf[:from], s.buf[to:]...)
+	s.pos = from
+}
+
+func (e *editor) readLine(prompt string) (string, error) {
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	var (
+		ls    lineState
+		hidx  = len(e.history)
+		draft string // the unsent line while browsing history
+	)
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(ls.buf))
+		if back := len(ls.buf) - ls.pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	historyMove := func(delta int) {
+		next := hidx + delta
+		if next < 0 || next > len(e.history) {
+			return
+		}
+		if hidx == len(e.history) {
+			draft = string(ls.buf)
+		}
+		hidx = next
+		if hidx == len(e.history) {
+			ls.set(draft)
+		} else {
+			ls.set(e.history[hidx])
+		}
+	}
+
+	redraw()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(ls.buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupt
+		case 4: // Ctrl-D
+			if len(ls.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if ls.pos < len(ls.buf) {
+				ls.deleteRange(ls.pos, ls.pos+1)
+			}
+		case 127, 8: // Backspace
+			if ls.pos > 0 {
+				ls.deleteRange(ls.pos-1, ls.pos)
+			}
+		case 1: // Ctrl-A
+			ls.pos = 0
+		case 5: // Ctrl-E
+			ls.pos = len(ls.buf)
+		case 2: // Ctrl-B
+			ls.pos = max(ls.pos-1, 0)
+		case 6: // Ctrl-F
+			ls.pos = min(ls.pos+1, len(ls.buf))
+		case 11: // Ctrl-K
+			ls.buf = ls.buf[:ls.pos]
+		case 21: // Ctrl-U
+			ls.deleteRange(0, ls.pos)
+		case 23: // Ctrl-W
+			from := ls.pos
+			for from > 0 && ls.buf[from-1] == ' ' {
+				from--
+			}
+			for from > 0 && ls.buf[from-1] != ' ' {
+				from--
+			}
+			ls.deleteRange(from, ls.pos)
+		case 12: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case 16: // Ctrl-P
+			historyMove(-1)
+		case 14: // Ctrl-N
+			historyMove(1)
+		case '\t':
+			e.tab(&ls)
+		case 27: // escape sequence
+			switch e.readEscape() {
+			case "A":
+				historyMove(-1)
+			case "B":
+				historyMove(1)
+			case "C":
+				ls.pos = min(ls.pos+1, len(ls.buf))
+			case "D":
+				ls.pos = max(ls.pos-1, 0)
+			case "H", "1~", "7~":
+				ls.pos = 0
+			case "F", "4~", "8~":
+				ls.pos = len(ls.buf)
+			case "3~":
+				if ls.pos < len(ls.buf) {
+					ls.deleteRange(ls.pos, ls.pos+1)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				ls.insert([]rune{r})
+			}
+		}
+		redraw()
+	}
+}
+
+// readEscape consumes the rest of an ESC [ or ESC O sequence and returns
+// its final part, e.g. "A" for the up arrow or "3~" for Delete.
+func (e *editor) readEscape() string {
+	r, _, err := e.in.ReadRune()
+	if err != nil || (r != '[' && r != 'O') {
+		return ""
+	}
+	var seq []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq = append(seq, r)
+		if r < '0' || r > '9' {
+			return string(seq)
+		}
+	}
+}
+
+// tab completes the word before the cursor. A unique candidate is taken
+// as is; several are narrowed to their common prefix, and listed when that
+// adds nothing.
+func (e *editor) tab(ls *lineState) {
+	if e.complete == nil {
+		return
+	}
+	prefix := string(ls.buf[:ls.pos])
+	start, cands := e.complete(prefix)
+	if len(cands) == 0 {
+		fmt.Fprint(e.out, "\a")
+		return
+	}
+	word := prefix[start:]
+	insert := cands[0]
+	if len(cands) > 1 {
+		insert = commonPrefix(cands)
+		if utf8.RuneCountInString(insert) <= utf8.RuneCountInString(word) {
+			list := make([]string, len(cands))
+			for i, c := range cands {
+				list[i] = strings.TrimSpace(c)
+			}
+			fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(list, "  "))
+			return
+		}
+	}
+	startRune := utf8.RuneCountInString(prefix[:start])
+	ls.deleteRange(startRune, ls.pos)
+	ls.insert([]rune(insert))
+}
+
+func commonPrefix(ss []string) string {
+	p := ss[0]
+	for _, s := range ss[1:] {
+		for !strings.HasPrefix(s, p) {
+			_, size := utf8.DecodeLastRuneInString(p)
+			p = p[:len(p)-size]
+		}
+	}
+	return p
+}
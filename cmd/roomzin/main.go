@@ -0,0 +1,292 @@
+// Command roomzin runs Roomzin commands from the terminal.
+//
+// Connection flags come before the command name; command flags after it:
+//
+//	roomzin -host 10.0.0.5 -port 7777 -token s3cret codecs
+//	roomzin -seeds 10.0.0.5,10.0.0.6 -port 7777 -api-port 8080 -token s3cret \
+//		search-avail -segment paris -room dbl -dates 2026-11-01..2026-11-03
+//	roomzin -o json get-room-day -id hotel-1 -room dbl -date 2026-11-01
+//
+// Setting -seeds connects through the cluster client, which discovers the
+// leader and followers; otherwise -host names a single node. ROOMZIN_HOST,
+// ROOMZIN_PORT, ROOMZIN_TOKEN, ROOMZIN_SEEDS and ROOMZIN_API_PORT supply
+// defaults for the matching flags.
+//
+// Dates may not be in the past or beyond the booking horizon the server
+// advertises, a year by default. -tz (or ROOMZIN_TZ) names the time zone
+// "today" is taken in, UTC by default.
+//
+// Results print as a table, or as JSON with -o json. The exit status is 0
+// on success, 1 when the command fails and 2 on a usage error.
+//
+// "roomzin shell" logs in once and reads commands interactively, written
+// as the wire command name followed by key=value arguments:
+//
+//	roomzin> SEARCHAVAIL segment=paris room=dbl dates=2026-11-01..2026-11-03
+//
+// The keys are the command's flag names. The shell keeps a history file
+// and completes command names, argument keys and rate features on Tab.
+//
+// "roomzin copy" reads a segment from the connection above and writes it
+// to a second one, named by the same flags with a "to-" prefix and
+// defaulted from ROOMZIN_TARGET_HOST, ROOMZIN_TARGET_PORT and so on:
+//
+//	roomzin -host prod-1 -token s3cret copy -segment paris -to-host staging-1 -to-token t0ken
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/cluster"
+	"github.com/roomzin/roomzin-go/single"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options holds the connection and output flags shared by every command.
+type options struct {
+	host     string
+	port     int
+	apiPort  int
+	seeds    string
+	token    string
+	timeout  time.Duration
+	output   string
+	tls      bool
+	tlsCA    string
+	tlsCert  string
+	tlsKey   string
+	tlsName  string
+	insecure bool
+	tz       string
+	loc      *time.Location // loaded from tz by validate
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	o.registerConn(fs, "", "ROOMZIN_")
+	fs.StringVar(&o.output, "o", "table", "output format: table or json")
+	fs.StringVar(&o.tz, "tz", envString("ROOMZIN_TZ", ""), "IANA time zone whose today bounds dates, e.g. Europe/Paris (default UTC)")
+}
+
+// registerConn registers the connection flags, each name prefixed with
+// prefix and each environment default with envPrefix, so a command can
+// take a second connection next to the global one.
+func (o *options) registerConn(fs *flag.FlagSet, prefix, envPrefix string) {
+	fs.StringVar(&o.host, prefix+"host", envString(envPrefix+"HOST", "127.0.0.1"), "single node address")
+	fs.IntVar(&o.port, prefix+"port", envInt(envPrefix+"PORT", 7777), "framed protocol TCP port")
+	fs.StringVar(&o.seeds, prefix+"seeds", envString(envPrefix+"SEEDS", ""), "comma separated cluster seed hosts; enables cluster mode")
+	fs.IntVar(&o.apiPort, prefix+"api-port", envInt(envPrefix+"API_PORT", 0), "cluster HTTP discovery port")
+	fs.StringVar(&o.token, prefix+"token", envString(envPrefix+"TOKEN", ""), "auth token")
+	fs.DurationVar(&o.timeout, prefix+"timeout", 2*time.Second, "per-request timeout")
+	fs.BoolVar(&o.tls, prefix+"tls", false, "connect over TLS")
+	fs.StringVar(&o.tlsCA, prefix+"tls-ca", "", "PEM file with the CA that signed the server certificate")
+	fs.StringVar(&o.tlsCert, prefix+"tls-cert", "", "PEM client certificate for mutual TLS")
+	fs.StringVar(&o.tlsKey, prefix+"tls-key", "", "PEM client key for mutual TLS")
+	fs.StringVar(&o.tlsName, prefix+"tls-server-name", "", "expected server certificate name, when it differs from the host")
+	fs.BoolVar(&o.insecure, prefix+"tls-insecure", false, "skip server certificate verification (testing only)")
+}
+
+func (o *options) validate() error {
+	if o.output != "table" && o.output != "json" {
+		return fmt.Errorf("-o must be table or json, got %q", o.output)
+	}
+	if o.tz != "" {
+		loc, err := time.LoadLocation(o.tz)
+		if err != nil {
+			return fmt.Errorf("-tz: %v", err)
+		}
+		o.loc = loc
+	}
+	return o.validateConn("")
+}
+
+func (o *options) validateConn(prefix string) error {
+	if (o.tlsCert == "") != (o.tlsKey == "") {
+		return fmt.Errorf("-%stls-cert and -%stls-key must be set together", prefix, prefix)
+	}
+	return nil
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts options
+	fs := newFlagSet("roomzin", stderr)
+	opts.register(fs)
+	fs.Usage = func() { printUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(stderr, "roomzin:", err)
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		printUsage(fs)
+		return exitUsage
+	}
+
+	name, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch name {
+	case "help":
+		printUsage(fs)
+		return exitOK
+	case "shell":
+		return runShell(&opts, cmdArgs, stdin, stdout, stderr)
+	}
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Fprintf(stderr, "roomzin: unknown command %q\n", name)
+		printUsage(fs)
+		return exitUsage
+	}
+	exec, err := cmd.parse(cmdArgs, stderr, command.cliUsage)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	client, err := connect(&opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "roomzin:", err)
+		return exitError
+	}
+	defer client.Close()
+
+	ctx, stop := signal.NotifyContext(opts.callCtx(), os.Interrupt)
+	defer stop()
+	res, err := exec(ctx, client)
+	if res != nil || err == nil {
+		if rerr := render(stdout, opts.output, res); rerr != nil {
+			fmt.Fprintln(stderr, "roomzin:", rerr)
+			return exitError
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "roomzin:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// callCtx is the base context of a command: it carries the -tz location,
+// so every client and every tool it runs checks dates against that zone.
+func (o *options) callCtx() context.Context {
+	if o.loc == nil {
+		return context.Background()
+	}
+	return api.WithLocation(context.Background(), o.loc)
+}
+
+// connect builds a cluster client when seeds are given, a single node
+// client otherwise. Tests swap it for an in-memory client.
+var connect = func(o *options) (api.CacheClientAPI, error) {
+	tlsCfg, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if o.seeds != "" {
+		cfg, err := cluster.NewConfigBuilder().
+			WithSeedHosts(o.seeds).
+			WithTCPPort(o.port).
+			WithAPIPort(o.apiPort).
+			WithToken(o.token).
+			WithTimeout(o.timeout).
+			WithTLSConfig(tlsCfg).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		cfg.HttpTimeout = o.timeout
+		return cluster.New(&cfg)
+	}
+	cfg, err := single.NewConfigBuilder().
+		WithHost(o.host).
+		WithTCPPort(o.port).
+		WithToken(o.token).
+		WithTimeout(o.timeout).
+		WithTLSConfig(tlsCfg).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	return single.New(&cfg)
+}
+
+// tlsConfig returns nil unless one of the TLS flags is set.
+func (o *options) tlsConfig() (*tls.Config, error) {
+	if !o.tls && o.tlsCA == "" && o.tlsCert == "" && !o.insecure {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.tlsName,
+		InsecureSkipVerify: o.insecure,
+	}
+	if o.tlsCA != "" {
+		pem, err := os.ReadFile(o.tlsCA)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.tlsCA)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.tlsCert, o.tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func printUsage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprint(w, "Usage: roomzin [flags] <command> [command flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.help)
+	}
+	fmt.Fprintf(w, "  %-16s %s\n", "shell", "start an interactive session")
+	fmt.Fprint(w, "\nRun 'roomzin <command> -h' for command flags.\n\nFlags:\n")
+	fs.PrintDefaults()
+}
+
+func envString(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// keepOpen lets one in-memory client outlive the runs that close it.
+type keepOpen struct{ api.CacheClientAPI }
+
+func (keepOpen) Close() error { return nil }
+
+// useMemClient makes every run connect to one in-memory client, and
+// returns it and the options the last run connected with.
+func useMemClient(t *testing.T) (api.CacheClientAPI, *options) {
+	t.Helper()
+	c, err := roomzintest.NewMemClient(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	var last options
+	prev := connect
+	connect = func(o *options) (api.CacheClientAPI, error) {
+		last = *o
+		return keepOpen{c}, nil
+	}
+	t.Cleanup(func() { connect = prev })
+	return c, &last
+}
+
+func runArgs(args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(args, strings.NewReader(""), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	prev := connect
+	connect = func(*options) (api.CacheClientAPI, error) {
+		t.Fatal("connected on a usage error")
+		return nil, nil
+	}
+	t.Cleanup(func() { connect = prev })
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "no command", args: nil, want: exitUsage},
+		{name: "help", args: []string{"help"}, want: exitOK},
+		{name: "-h", args: []string{"-h"}, want: exitOK},
+		{name: "command -h", args: []string{"set-prop", "-h"}, want: exitOK},
+		{name: "unknown command", args: []string{"book"}, want: exitUsage},
+		{name: "unknown flag", args: []string{"-colour", "red", "codecs"}, want: exitUsage},
+		{name: "bad output", args: []string{"-o", "yaml", "codecs"}, want: exitUsage},
+		{name: "bad time zone", args: []string{"-tz", "Mars/Olympus", "codecs"}, want: exitUsage},
+		{name: "cert without key", args: []string{"-tls-cert", "c.pem", "codecs"}, want: exitUsage},
+		{name: "unknown command flag", args: []string{"prop-exist", "-name", "x"}, want: exitUsage},
+		{name: "stray argument", args: []string{"prop-exist", "-id", "hotel-1", "extra"}, want: exitUsage},
+		{name: "out of range", args: []string{"set-prop", "-stars", "300"}, want: exitUsage},
+		{name: "bad date", args: []string{"get-room-day", "-id", "h", "-room", "dbl", "-date", "tomorrow"}, want: exitUsage},
+		{name: "bad weekday", args: []string{"del-room-range", "-weekdays", "fri,someday"}, want: exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := runArgs(tt.args...); code != tt.want {
+				t.Fatalf("run(%q) = %d, want %d", tt.args, code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCommands(t *testing.T) {
+	c, opts := useMemClient(t)
+	d := types.Today(nil, nil).AddDays(2)
+
+	code, out, errOut := runArgs("-host", "10.0.0.5", "-port", "7000", "-token", "s3cret",
+		"set-prop", "-segment", "paris", "-area", "marais", "-id", "hotel-1", "-type", "hotel", "-category", "city", "-stars", "4")
+	if code != exitOK || strings.TrimSpace(out) != "OK" {
+		t.Fatalf("set-prop = %d, %q, %q", code, out, errOut)
+	}
+	if opts.host != "10.0.0.5" || opts.port != 7000 || opts.token != "s3cret" {
+		t.Fatalf("connected with %+v", *opts)
+	}
+	if ok, err := c.PropExist("hotel-1"); err != nil || !ok {
+		t.Fatalf("PropExist after set-prop = %v, %v", ok, err)
+	}
+
+	// Verbs work as command names too.
+	code, _, errOut = runArgs("SETROOMPKG", "-id", "hotel-1", "-room", "dbl", "-date", d.String(), "-avail", "3", "-price", "15000", "-features", "breakfast,free_cancellation")
+	if code != exitOK {
+		t.Fatalf("SETROOMPKG = %d, %q", code, errOut)
+	}
+
+	code, out, _ = runArgs("inc-avl", "-id", "hotel-1", "-room", "dbl", "-date", d.String(), "-amount", "2")
+	if code != exitOK || !strings.Contains(out, "AVAILABILITY\n5") {
+		t.Fatalf("inc-avl = %d, %q", code, out)
+	}
+
+	code, out, _ = runArgs("-o", "json", "get-room-day", "-id", "hotel-1", "-room", "dbl", "-date", d.String())
+	var day roomDayJSON
+	if err := json.Unmarshal([]byte(out), &day); code != exitOK || err != nil {
+		t.Fatalf("get-room-day = %d, %q, %v", code, out, err)
+	}
+	if day.PropertyID != "hotel-1" || day.Date != d || day.Availability != 5 || day.FinalPrice != 15000 || strings.Join(day.RateFeatures, ",") != "breakfast,free_cancellation" {
+		t.Fatalf("get-room-day = %+v", day)
+	}
+
+	// A failing command prints the error and exits 1.
+	code, out, errOut = runArgs("get-room-day", "-id", "hotel-1", "-room", "sgl", "-date", d.String())
+	if code != exitError || out != "" || !strings.HasPrefix(errOut, "roomzin: ") {
+		t.Fatalf("get-room-day on a missing room = %d, %q, %q", code, out, errOut)
+	}
+
+	// A range with a failed day prints every day, then fails.
+	code, out, errOut = runArgs("-o", "json", "del-room-range", "-id", "hotel-1", "-room", "dbl", "-from", d.String(), "-to", d.AddDays(1).String())
+	var days []dateResultJSON
+	if err := json.Unmarshal([]byte(out), &days); code != exitError || err != nil || errOut == "" {
+		t.Fatalf("del-room-range = %d, %q, %q, %v", code, out, errOut, err)
+	}
+	if len(days) != 2 || days[0].Error != "" || days[1].Error == "" {
+		t.Fatalf("del-room-range = %+v, want the first day deleted and the second not found", days)
+	}
+}
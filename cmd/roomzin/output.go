@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/roomzin/roomzin-go/importer"
+	"github.com/roomzin/roomzin-go/reconcile"
+	"github.com/roomzin/roomzin-go/snapshot"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// render prints a command result as a table or as JSON. A nil result is a
+// write that succeeded.
+func render(w io.Writer, format string, res any) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonView(res))
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	writeTable(tw, res)
+	return tw.Flush()
+}
+
+func writeTable(w io.Writer, res any) {
+	switch v := res.(type) {
+	case nil:
+		fmt.Fprintln(w, "OK")
+	case availability:
+		fmt.Fprintf(w, "AVAILABILITY\n%d\n", v)
+	case exists:
+		fmt.Fprintf(w, "EXISTS\n%t\n", v)
+	case []string:
+		for _, s := range v {
+			fmt.Fprintln(w, s)
+		}
+	case []types.Date:
+		for _, d := range v {
+			fmt.Fprintln(w, d)
+		}
+	case *types.Codecs:
+		fmt.Fprintln(w, "BIT\tRATE FEATURE")
+		for i, f := range v.RateFeatures {
+			fmt.Fprintf(w, "%d\t%s\n", i, f)
+		}
+		fmt.Fprintf(w, "\nversion: %s\n", v.Version)
+		if v.BookingHorizon > 0 {
+			fmt.Fprintf(w, "booking horizon: %d days\n", v.BookingHorizon)
+		}
+		for _, d := range []struct {
+			name string
+			list []string
+		}{{"amenities", v.Amenities}, {"property types", v.PropertyTypes}, {"categories", v.Categories}} {
+			if len(d.list) > 0 {
+				fmt.Fprintf(w, "%s: %s\n", d.name, strings.Join(d.list, ", "))
+			}
+		}
+	case types.GetRoomDayResult:
+		fmt.Fprintln(w, "PROPERTY\tDATE\tAVAIL\tPRICE\tFEATURES")
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", v.PropertyID, v.Date, v.Availability, v.FinalPrice, features(v.RateFeature))
+	case []types.PropertyAvail:
+		fmt.Fprintln(w, "PROPERTY\tDATE\tAVAIL\tPRICE\tFEATURES")
+		for _, p := range v {
+			for _, d := range p.Days {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", p.PropertyID, d.Date, d.Availability, d.FinalPrice, features(d.RateFeature))
+			}
+		}
+	case []types.SegmentInfo:
+		fmt.Fprintln(w, "SEGMENT\tPROPERTIES")
+		for _, s := range v {
+			fmt.Fprintf(w, "%s\t%d\n", s.Segment, s.PropCount)
+		}
+	case importer.Summary:
+		fmt.Fprintln(w, "ROWS\tIMPORTED\tREJECTED")
+		fmt.Fprintf(w, "%d\t%d\t%d\n", v.Rows, v.Imported, v.Rejected)
+	case snapshot.Trailer:
+		fmt.Fprintln(w, "PROPERTIES\tROOM TYPES\tROOM DAYS")
+		fmt.Fprintf(w, "%d\t%d\t%d\n", v.Properties, v.RoomTypes, v.RoomDays)
+	case snapshot.RestoreStats:
+		fmt.Fprintln(w, "PROPERTIES\tMISSING\tROOM DAYS\tSKIPPED\tREJECTED")
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\n", v.Properties, v.Missing, v.RoomDays, v.Skipped, v.Rejected)
+	case []types.DateResult:
+		fmt.Fprintln(w, "DATE\tRESULT")
+		for _, r := range v {
+			fmt.Fprintf(w, "%s\t%s\n", r.Date, errText(r.Err, "OK"))
+		}
+	case reconcileResult:
+		ok := "OK"
+		if v.dryRun {
+			ok = "PLANNED"
+		}
+		fmt.Fprintln(w, "ACTION\tPROPERTY\tROOM\tDATE\tCHANGE\tRESULT")
+		for _, ch := range v.changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", ch.Action, ch.PropertyID, ch.RoomType, ch.Date, describe(ch), errText(ch.Err, ok))
+		}
+		r := v.report
+		fmt.Fprintf(w, "\n%d rooms: %d unchanged, %d created, %d updated, %d deleted, %d failed\n", r.Rooms, r.Unchanged, r.Created, r.Updated, r.Deleted, r.Failed)
+	default:
+		fmt.Fprintf(w, "%v\n", v)
+	}
+}
+
+func features(fs []string) string {
+	if len(fs) == 0 {
+		return "-"
+	}
+	return strings.Join(fs, ",")
+}
+
+func errText(err error, ok string) string {
+	if err == nil {
+		return ok
+	}
+	return err.Error()
+}
+
+// JSON views use snake_case keys and render errors as strings.
+type (
+	codecsJSON struct {
+		Version        string   `json:"version"`
+		RateFeatures   []string `json:"rate_features"`
+		Amenities      []string `json:"amenities,omitempty"`
+		PropertyTypes  []string `json:"property_types,omitempty"`
+		Categories     []string `json:"categories,omitempty"`
+		BookingHorizon int      `json:"booking_horizon,omitempty"`
+	}
+	dayJSON struct {
+		Date         types.Date `json:"date"`
+		Availability uint8      `json:"availability"`
+		FinalPrice   uint32     `json:"final_price"`
+		RateFeatures []string   `json:"rate_features"`
+	}
+	roomDayJSON struct {
+		PropertyID string `json:"property_id"`
+		dayJSON
+	}
+	propertyAvailJSON struct {
+		PropertyID string    `json:"property_id"`
+		Days       []dayJSON `json:"days"`
+	}
+	segmentJSON struct {
+		Segment       string `json:"segment"`
+		PropertyCount uint32 `json:"property_count"`
+	}
+	dateResultJSON struct {
+		Date  types.Date `json:"date"`
+		Error string     `json:"error,omitempty"`
+	}
+	changeJSON struct {
+		Action     reconcile.Action `json:"action"`
+		PropertyID string           `json:"property_id"`
+		RoomType   string           `json:"room_type"`
+		Date       types.Date       `json:"date"`
+		Fields     []string         `json:"fields,omitempty"`
+		Before     *reconcile.Day   `json:"before,omitempty"`
+		After      *reconcile.Day   `json:"after,omitempty"`
+		Error      string           `json:"error,omitempty"`
+	}
+	reconcileJSON struct {
+		DryRun  bool             `json:"dry_run"`
+		Report  reconcile.Report `json:"report"`
+		Changes []changeJSON     `json:"changes"`
+	}
+)
+
+func newDayJSON(date types.Date, avl uint8, price uint32, fs []string) dayJSON {
+	if fs == nil {
+		fs = []string{}
+	}
+	return dayJSON{Date: date, Availability: avl, FinalPrice: price, RateFeatures: fs}
+}
+
+func jsonView(res any) any {
+	switch v := res.(type) {
+	case nil:
+		return map[string]string{"status": "OK"}
+	case availability:
+		return map[string]uint8{"availability": uint8(v)}
+	case exists:
+		return map[string]bool{"exists": bool(v)}
+	case []string:
+		if v == nil {
+			return []string{}
+		}
+		return v
+	case []types.Date:
+		if v == nil {
+			return []types.Date{}
+		}
+		return v
+	case *types.Codecs:
+		return codecsJSON{v.Version, v.RateFeatures, v.Amenities, v.PropertyTypes, v.Categories, v.BookingHorizon}
+	case types.GetRoomDayResult:
+		return roomDayJSON{v.PropertyID, newDayJSON(v.Date, v.Availability, v.FinalPrice, v.RateFeature)}
+	case []types.PropertyAvail:
+		out := make([]propertyAvailJSON, len(v))
+		for i, p := range v {
+			out[i] = propertyAvailJSON{PropertyID: p.PropertyID, Days: make([]dayJSON, len(p.Days))}
+			for j, d := range p.Days {
+				out[i].Days[j] = newDayJSON(d.Date, d.Availability, d.FinalPrice, d.RateFeature)
+			}
+		}
+		return out
+	case []types.SegmentInfo:
+		out := make([]segmentJSON, len(v))
+		for i, s := range v {
+			out[i] = segmentJSON{s.Segment, s.PropCount}
+		}
+		return out
+	case []types.DateResult:
+		out := make([]dateResultJSON, len(v))
+		for i, r := range v {
+			out[i] = dateResultJSON{Date: r.Date, Error: errText(r.Err, "")}
+		}
+		return out
+	case reconcileResult:
+		out := reconcileJSON{DryRun: v.dryRun, Report: v.report, Changes: make([]changeJSON, len(v.changes))}
+		for i, ch := range v.changes {
+			out.Changes[i] = changeJSON{ch.Action, ch.PropertyID, ch.RoomType, ch.Date, ch.Fields, ch.Before, ch.After, errText(ch.Err, "")}
+		}
+		return out
+	default:
+		return v
+	}
+}
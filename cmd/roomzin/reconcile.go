@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/reconcile"
+)
+
+// reconcileResult is the outcome of the reconcile command.
+type reconcileResult struct {
+	dryRun  bool
+	report  reconcile.Report
+	changes []reconcile.Change
+}
+
+func bindReconcile(fs *flag.FlagSet) runFunc {
+	var (
+		file string
+		opts reconcile.Options
+	)
+	fs.StringVar(&file, "file", "", "desired inventory, one JSON record per line")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "report the changes without writing")
+	fs.BoolVar(&opts.KeepExtra, "keep-extra", false, "keep stored days the file does not list")
+	fs.IntVar(&opts.Concurrency, "concurrency", reconcile.DefaultConcurrency, "reads in flight")
+	fs.IntVar(&opts.BatchSize, "batch", reconcile.DefaultBatchSize, "writes per pipelined batch")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		in, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+
+		res := reconcileResult{dryRun: opts.DryRun}
+		opts.OnChange = func(ch reconcile.Change) { res.changes = append(res.changes, ch) }
+		res.report, err = reconcile.Reconcile(ctx, c, reconcile.JSONL(in), opts)
+		if err == nil && res.report.Failed > 0 {
+			err = fmt.Errorf("%d changes failed", res.report.Failed)
+		}
+		return res, err
+	}
+}
+
+// describe summarises what a change writes.
+func describe(ch reconcile.Change) string {
+	switch ch.Action {
+	case reconcile.Create:
+		a := ch.After
+		return fmt.Sprintf("avail=%d price=%d features=%s", a.Availability, a.FinalPrice, features(a.RateFeatures))
+	case reconcile.Update:
+		if ch.Err != nil {
+			return "-"
+		}
+		var parts []string
+		for _, f := range ch.Fields {
+			switch f {
+			case "availability":
+				parts = append(parts, fmt.Sprintf("avail %d->%d", ch.Before.Availability, ch.After.Availability))
+			case "final_price":
+				parts = append(parts, fmt.Sprintf("price %d->%d", ch.Before.FinalPrice, ch.After.FinalPrice))
+			case "rate_features":
+				parts = append(parts, fmt.Sprintf("features %s->%s", features(ch.Before.RateFeatures), features(ch.After.RateFeatures)))
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	return "-"
+}
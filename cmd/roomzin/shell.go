@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+const (
+	shellPrompt    = "roomzin> "
+	maxHistoryLen  = 1000
+	historyEnvPath = "ROOMZIN_HISTORY"
+)
+
+// shellMeta are the shell's own commands, next to the wire verbs.
+var shellMeta = []string{"HELP", "OUTPUT", "EXIT", "QUIT"}
+
+// shell is an interactive session on one logged-in client. Commands run
+// through the same table, and so the same client calls and internal/command
+// payload builders, as the one-shot CLI.
+type shell struct {
+	client   api.CacheClientAPI
+	out      io.Writer
+	errOut   io.Writer
+	format   string
+	base     context.Context // parent of every command's ctx
+	features []string        // rate features offered by Tab after features=
+}
+
+func runShell(opts *options, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("shell", stderr)
+	histPath := fs.String("history", defaultHistoryPath(), "history file; empty keeps history in memory only")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "Usage: roomzin [flags] shell [-history file]\n\nStart an interactive session.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+
+	client, err := connect(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "roomzin:", err)
+		return exitError
+	}
+	defer client.Close()
+
+	sh := &shell{client: client, out: stdout, errOut: stderr, format: opts.output, base: opts.callCtx()}
+	sh.loadFeatures()
+
+	var lr lineReader
+	tty, _ := stdin.(*os.File)
+	interactive := tty != nil && isTerminal(tty.Fd())
+	if interactive {
+		ed := newEditor(tty, stdout, loadHistory(*histPath), sh.complete)
+		defer func() { saveHistory(*histPath, ed.history) }()
+		lr = ed
+		fmt.Fprintln(stdout, "Connected. Type HELP for commands, Tab to complete, EXIT or Ctrl-D to quit.")
+	} else {
+		lr = &plainReader{in: bufio.NewReader(stdin)}
+	}
+
+	failed := false
+	for {
+		line, err := lr.readLine(shellPrompt)
+		if errors.Is(err, errInterrupt) {
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				fmt.Fprintln(stderr, "roomzin:", err)
+				return exitError
+			}
+			break
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lr.addHistory(line)
+		quit, ok := sh.exec(line)
+		failed = failed || !ok
+		if quit {
+			break
+		}
+	}
+	// A script piped into the shell reports failure like the one-shot CLI.
+	if failed && !interactive {
+		return exitError
+	}
+	return exitOK
+}
+
+// loadFeatures fetches the codecs once for completion; the shell works
+// without them.
+func (s *shell) loadFeatures() {
+	codecs, err := s.client.GetCodecs()
+	if err != nil {
+		fmt.Fprintln(s.errOut, "warning: cannot load codecs for completion:", err)
+		return
+	}
+	s.features = codecs.RateFeatures
+}
+
+// exec runs one input line and reports whether the shell should stop and
+// whether the line succeeded.
+func (s *shell) exec(line string) (quit, ok bool) {
+	words, err := splitWords(line)
+	if err != nil {
+		fmt.Fprintln(s.errOut, "(error)", err)
+		return false, false
+	}
+	verb, rest := words[0], words[1:]
+	switch strings.ToUpper(verb) {
+	case "EXIT", "QUIT":
+		return true, true
+	case "HELP":
+		return false, s.help(rest)
+	case "OUTPUT":
+		if len(rest) != 1 || (rest[0] != "table" && rest[0] != "json") {
+			fmt.Fprintln(s.errOut, "usage: OUTPUT table|json")
+			return false, false
+		}
+		s.format = rest[0]
+		return false, true
+	}
+
+	cmd, found := lookupCommand(verb)
+	if !found {
+		fmt.Fprintf(s.errOut, "(error) unknown command %q, try HELP\n", verb)
+		return false, false
+	}
+	args, err := flagArgs(cmd, rest)
+	if err != nil {
+		fmt.Fprintln(s.errOut, "(error)", err)
+		return false, false
+	}
+	run, err := cmd.parse(args, s.errOut, shellUsage)
+	if err != nil {
+		return false, errors.Is(err, flag.ErrHelp)
+	}
+
+	// Ctrl-C cancels the running command, not the session.
+	ctx, stop := signal.NotifyContext(s.base, os.Interrupt)
+	res, err := run(ctx, s.client)
+	stop()
+
+	if codecs, isCodecs := res.(*types.Codecs); isCodecs {
+		s.features = codecs.RateFeatures
+	}
+	if res != nil || err == nil {
+		if rerr := render(s.out, s.format, res); rerr != nil {
+			fmt.Fprintln(s.errOut, "(error)", rerr)
+			return false, false
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(s.errOut, "(error)", err)
+		return false, false
+	}
+	return false, true
+}
+
+func (s *shell) help(args []string) bool {
+	if len(args) == 0 {
+		fmt.Fprintln(s.out, "Commands (arguments are key=value):")
+		for _, c := range commands {
+			fmt.Fprintf(s.out, "  %-18s %s\n", c.verb, c.help)
+		}
+		fmt.Fprintf(s.out, "  %-18s %s\n", "HELP <command>", "show a command's keys")
+		fmt.Fprintf(s.out, "  %-18s %s\n", "OUTPUT table|json", "switch the result format")
+		fmt.Fprintf(s.out, "  %-18s %s\n", "EXIT", "end the session")
+		return true
+	}
+	cmd, found := lookupCommand(args[0])
+	if !found {
+		fmt.Fprintf(s.errOut, "(error) unknown command %q\n", args[0])
+		return false
+	}
+	fs := newFlagSet(cmd.name, s.out)
+	cmd.bind(fs)
+	shellUsage(cmd, fs)
+	return true
+}
+
+// shellUsage prints a command's flags as the key=value arguments the
+// shell takes.
+func shellUsage(c command, fs *flag.FlagSet) {
+	w := fs.Output()
+	if commandKeys(c) == nil {
+		fmt.Fprintf(w, "Usage: %s\n\n%s.\n", c.verb, c.help)
+		return
+	}
+	fmt.Fprintf(w, "Usage: %s key=value ...\n\n%s.\n\nKeys:\n", c.verb, c.help)
+	fs.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(w, "  %-10s %s\n", f.Name, f.Usage)
+	})
+}
+
+// flagArgs turns key=value words into -key=value flags for cmd.
+func flagArgs(cmd command, words []string) ([]string, error) {
+	keys := commandKeys(cmd)
+	args := make([]string, len(words))
+	for i, w := range words {
+		key, val, ok := strings.Cut(w, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", w)
+		}
+		if !slices.Contains(keys, key) {
+			return nil, fmt.Errorf("%s has no key %q, see HELP %s", cmd.verb, key, cmd.verb)
+		}
+		args[i] = "-" + key + "=" + val
+	}
+	return args, nil
+}
+
+// commandKeys lists the argument keys cmd accepts, sorted.
+func commandKeys(cmd command) []string {
+	var keys []string
+	fs := newFlagSet(cmd.name, io.Discard)
+	cmd.bind(fs)
+	fs.VisitAll(func(f *flag.Flag) { keys = append(keys, f.Name) })
+	return keys
+}
+
+// splitWords splits a line on spaces. Single or double quotes group a value
+// with spaces, e.g. area="Latin Quarter", and a backslash escapes the next
+// character.
+func splitWords(line string) ([]string, error) {
+	var (
+		words []string
+		cur   strings.Builder
+		quote rune
+		inTok bool
+		esc   bool
+	)
+	for _, r := range line {
+		switch {
+		case esc:
+			cur.WriteRune(r)
+			esc = false
+		case r == '\\':
+			esc, inTok = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inTok = r, true
+		case r == ' ' || r == '\t':
+			if inTok {
+				words = append(words, cur.String())
+				cur.Reset()
+				inTok = false
+			}
+		default:
+			cur.WriteRune(r)
+			inTok = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inTok {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// complete offers command names for the first word, the command's keys
+// for later words and, after features=, the codec rate features not yet
+// listed.
+func (s *shell) complete(prefix string) (int, []string) {
+	start := strings.LastIndexAny(prefix, " \t") + 1
+	word := prefix[start:]
+	words := strings.Fields(prefix[:start])
+	if len(words) == 0 {
+		names := slices.Clone(shellMeta)
+		for _, c := range commands {
+			names = append(names, c.verb)
+		}
+		return start, withSuffix(matchPrefix(word, names), " ")
+	}
+
+	cmd, found := lookupCommand(words[0])
+	if !found {
+		return start, nil
+	}
+	if key, val, isValue := strings.Cut(word, "="); isValue {
+		if key != "features" {
+			return start, nil
+		}
+		listed := strings.Split(val, ",")
+		last := listed[len(listed)-1]
+		var cands []string
+		for _, f := range matchPrefix(last, s.features) {
+			if !slices.Contains(listed[:len(listed)-1], f) {
+				cands = append(cands, f)
+			}
+		}
+		return start + len(word) - len(last), cands
+	}
+
+	return start, withSuffix(matchPrefix(word, commandKeys(cmd)), "=")
+}
+
+// matchPrefix returns the candidates starting with word, ignoring case.
+func matchPrefix(word string, cands []string) []string {
+	var out []string
+	for _, c := range cands {
+		if len(c) >= len(word) && strings.EqualFold(c[:len(word)], word) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func withSuffix(ss []string, suffix string) []string {
+	for i := range ss {
+		ss[i] += suffix
+	}
+	return ss
+}
+
+func defaultHistoryPath() string {
+	if p, ok := os.LookupEnv(historyEnvPath); ok {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".roomzin_history")
+}
+
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > maxHistoryLen {
+		lines = lines[len(lines)-maxHistoryLen:]
+	}
+	return lines
+}
+
+func saveHistory(path string, lines []string) {
+	if path == "" || len(lines) == 0 {
+		return
+	}
+	if len(lines) > maxHistoryLen {
+		lines = lines[len(lines)-maxHistoryLen:]
+	}
+	_ = os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+}
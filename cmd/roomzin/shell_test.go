@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "PROPEXIST id=hotel-1", want: []string{"PROPEXIST", "id=hotel-1"}},
+		{line: "  SETPROP \t id=a  ", want: []string{"SETPROP", "id=a"}},
+		{line: `SETPROP area="Latin Quarter" id='h 1'`, want: []string{"SETPROP", "area=Latin Quarter", "id=h 1"}},
+		{line: `SETPROP area=Latin\ Quarter`, want: []string{"SETPROP", "area=Latin Quarter"}},
+		{line: `SETPROP id=""`, want: []string{"SETPROP", "id="}},
+		{line: `SETPROP area="Latin`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitWords(tt.line)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, %v", tt.line, got, err)
+		}
+	}
+}
+
+func TestFlagArgs(t *testing.T) {
+	cmd, _ := lookupCommand("setroompkg")
+	got, err := flagArgs(cmd, []string{"id=hotel-1", "features=breakfast,spa", "avail="})
+	if want := []string{"-id=hotel-1", "-features=breakfast,spa", "-avail="}; err != nil || !slices.Equal(got, want) {
+		t.Fatalf("flagArgs = %q, %v; want %q", got, err, want)
+	}
+	for _, words := range [][]string{{"id"}, {"=hotel-1"}, {"name=x"}} {
+		if _, err := flagArgs(cmd, words); err == nil {
+			t.Errorf("flagArgs(%q) accepted", words)
+		}
+	}
+}
+
+func TestShellScript(t *testing.T) {
+	c, _ := useMemClient(t)
+	d := types.Today(nil, nil).AddDays(2)
+	script := strings.Join([]string{
+		"# comments and blank lines are skipped",
+		"",
+		`SETPROP segment=paris area="Latin Quarter" id=hotel-1 type=hotel category=city stars=4`,
+		"setroompkg id=hotel-1 room=dbl date=" + d.String() + " avail=2",
+		"BOOK id=hotel-1",
+		"INCROOMAVL id=hotel-1 room=dbl date=" + d.String() + " amount=1",
+		"OUTPUT json",
+		"GETPROPROOMDAY id=hotel-1 room=dbl date=" + d.String(),
+		"EXIT",
+		"DELPROP id=hotel-1",
+	}, "\n")
+	var out, errOut bytes.Buffer
+	code := run([]string{"shell", "-history", ""}, strings.NewReader(script), &out, &errOut)
+
+	// The unknown verb fails the script but not the lines after it.
+	if code != exitError || !strings.Contains(errOut.String(), `unknown command "BOOK"`) {
+		t.Fatalf("shell = %d, stderr %q", code, errOut.String())
+	}
+	if !strings.HasPrefix(out.String(), "OK\nOK\nAVAILABILITY\n3\n") {
+		t.Fatalf("table output %q", out.String())
+	}
+	var day roomDayJSON
+	if err := json.Unmarshal([]byte(out.String()[strings.Index(out.String(), "{"):]), &day); err != nil || day.Availability != 3 {
+		t.Fatalf("json output %q: %v", out.String(), err)
+	}
+	// Nothing runs after EXIT.
+	if ok, err := c.PropExist("hotel-1"); err != nil || !ok {
+		t.Fatalf("PropExist after EXIT = %v, %v", ok, err)
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if code := run([]string{"shell", "-history", ""}, strings.NewReader("PROPEXIST id=hotel-1\n"), &out, &errOut); code != exitOK || out.String() != "EXISTS\ntrue\n" {
+		t.Fatalf("shell = %d, %q, %q", code, out.String(), errOut.String())
+	}
+}
+
+func TestShellComplete(t *testing.T) {
+	sh := &shell{features: []string{"breakfast", "half_board", "free_cancellation"}}
+	tests := []struct {
+		prefix    string
+		wantStart int
+		want      []string
+	}{
+		{prefix: "PROPE", want: []string{"PROPEXIST "}},
+		{prefix: "ex", want: []string{"EXIT ", "EXPORT "}},
+		{prefix: "propexist ", wantStart: 10, want: []string{"id="}},
+		{prefix: "SETROOMPKG features=breakfast,", wantStart: 30, want: []string{"half_board", "free_cancellation"}},
+		{prefix: "SETROOMPKG features=b", wantStart: 20, want: []string{"breakfast"}},
+		{prefix: "SETROOMPKG room=", wantStart: 11},
+		{prefix: "NOPE i", wantStart: 5},
+	}
+	for _, tt := range tests {
+		start, got := sh.complete(tt.prefix)
+		if start != tt.wantStart || !slices.Equal(got, tt.want) {
+			t.Errorf("complete(%q) = %d, %q; want %d, %q", tt.prefix, start, got, tt.wantStart, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/snapshot"
+)
+
+func bindExport(fs *flag.FlagSet) runFunc {
+	var (
+		segment, out string
+		concurrency  int
+	)
+	fs.StringVar(&segment, "segment", "", "segment to export")
+	fs.StringVar(&out, "out", "", "snapshot file (default <segment>.snapshot.jsonl)")
+	fs.IntVar(&concurrency, "concurrency", snapshot.DefaultConcurrency, "reads in flight")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		if out == "" {
+			out = segment + ".snapshot.jsonl"
+		}
+		// Write next to the target and rename on success, so an
+		// interrupted export never leaves a partial file under its name.
+		f, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".*.tmp")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+
+		counts, err := snapshot.Export(ctx, c, segment, f, snapshot.ExportOptions{Concurrency: concurrency})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return counts, os.Rename(f.Name(), out)
+	}
+}
+
+// restoreFlags are shared by restore and copy.
+func restoreFlags(fs *flag.FlagSet, opts *snapshot.RestoreOptions, rejects *string) {
+	fs.IntVar(&opts.BatchSize, "batch", snapshot.DefaultBatchSize, "writes per pipelined batch")
+	fs.Var(mapVar(&opts.FeatureMap), "map", "rename rate features, as old=new,... (repeatable)")
+	fs.BoolVar(&opts.DropUnknownFeatures, "drop-unknown-features", false, "drop rate features the target lacks instead of rejecting the day")
+	fs.StringVar(rejects, "rejects", "", "reject file, one JSON line per failed record")
+	// restoreResult reports missing properties along with the reject file.
+	opts.AllowMissing = true
+}
+
+func bindRestore(fs *flag.FlagSet) runFunc {
+	var (
+		file, rejects string
+		opts          snapshot.RestoreOptions
+	)
+	fs.StringVar(&file, "file", "", "snapshot file")
+	restoreFlags(fs, &opts, &rejects)
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		in, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+		if rejects == "" {
+			rejects = file + ".rejects.jsonl"
+		}
+		return restoreResult(rejects, func(rw *lazyFile) (snapshot.RestoreStats, error) {
+			opts.Rejects = rw
+			return snapshot.Restore(ctx, c, in, opts)
+		})
+	}
+}
+
+func bindCopy(fs *flag.FlagSet) runFunc {
+	var (
+		segment, rejects string
+		to               options
+		opts             snapshot.CopyOptions
+	)
+	fs.StringVar(&segment, "segment", "", "segment to copy")
+	fs.IntVar(&opts.Concurrency, "concurrency", snapshot.DefaultConcurrency, "reads in flight")
+	restoreFlags(fs, &opts.RestoreOptions, &rejects)
+	to.registerConn(fs, "to-", "ROOMZIN_TARGET_")
+	return func(ctx context.Context, c api.CacheClientAPI) (any, error) {
+		if err := to.validateConn("to-"); err != nil {
+			return nil, err
+		}
+		dst, err := connect(&to)
+		if err != nil {
+			return nil, fmt.Errorf("target: %w", err)
+		}
+		defer dst.Close()
+		if rejects == "" {
+			rejects = segment + ".copy.rejects.jsonl"
+		}
+		return restoreResult(rejects, func(rw *lazyFile) (snapshot.RestoreStats, error) {
+			opts.Rejects = rw
+			return snapshot.Copy(ctx, c, dst, segment, opts)
+		})
+	}
+}
+
+// restoreResult runs a restore with a lazily created reject file and
+// turns skipped records into an error, keeping the stats either way.
+func restoreResult(rejects string, restore func(*lazyFile) (snapshot.RestoreStats, error)) (any, error) {
+	rw := &lazyFile{path: rejects}
+	defer rw.Close()
+	stats, err := restore(rw)
+	if err != nil && stats == (snapshot.RestoreStats{}) {
+		return nil, err
+	}
+	if err == nil && stats.Missing+stats.Rejected > 0 {
+		err = fmt.Errorf("%d properties missing from the target and %d room days rejected, see %s", stats.Missing, stats.Rejected, rejects)
+	}
+	return stats, err
+}
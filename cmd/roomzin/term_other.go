@@ -0,0 +1,13 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// Line editing needs termios; elsewhere the shell reads plain lines.
+
+func isTerminal(uintptr) bool { return false }
+
+func makeRaw(uintptr) (func() error, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
@@ -0,0 +1,245 @@
+// Package importer bulk loads properties and room packages from CSV or
+// JSONL into Roomzin.
+//
+// Rows are streamed, so inputs of any size run in constant memory. Each row
+// is written with the client, which verifies it as it verifies every call;
+// rows that fail to parse, to verify or on the server are written to a
+// reject log with their line number and the RoomzinError, and the import
+// carries on.
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Format is the input encoding.
+type Format uint8
+
+const (
+	CSV   Format = iota // header row, then one row per record
+	JSONL               // one JSON object per line
+)
+
+// Kind is what the rows describe.
+type Kind uint8
+
+const (
+	Properties   Kind = iota // PropertyRow, sent with SetProp
+	RoomPackages             // RoomPackageRow, sent with SetRoomPkg
+)
+
+// DefaultConcurrency is the number of writes in flight when
+// Options.Concurrency is zero.
+const DefaultConcurrency = 8
+
+// PropertyRow is one property. The JSON keys double as CSV column names;
+// amenities is a comma separated cell in CSV.
+type PropertyRow struct {
+	Segment      string   `json:"segment"`
+	Area         string   `json:"area"`
+	PropertyID   string   `json:"property_id"`
+	PropertyType string   `json:"property_type"`
+	Category     string   `json:"category"`
+	Stars        uint8    `json:"stars"`
+	Latitude     float64  `json:"latitude"`
+	Longitude    float64  `json:"longitude"`
+	Amenities    []string `json:"amenities"`
+}
+
+// Payload converts the row for SetProp.
+func (r PropertyRow) Payload() types.SetPropPayload {
+	return types.SetPropPayload{
+		Segment:      r.Segment,
+		Area:         r.Area,
+		PropertyID:   r.PropertyID,
+		PropertyType: r.PropertyType,
+		Category:     r.Category,
+		Stars:        r.Stars,
+		Latitude:     r.Latitude,
+		Longitude:    r.Longitude,
+		Amenities:    r.Amenities,
+	}
+}
+
+// RoomPackageRow is one room day. A missing availability or final_price
+// leaves the stored value alone. A missing rate_features does too, while
+// an empty one ([] in JSON, an empty cell in CSV) clears the features.
+type RoomPackageRow struct {
+	PropertyID   string     `json:"property_id"`
+	RoomType     string     `json:"room_type"`
+	Date         types.Date `json:"date"`
+	Availability *uint8     `json:"availability,omitempty"`
+	FinalPrice   *uint32    `json:"final_price,omitempty"`
+	RateFeatures []string   `json:"rate_features"`
+}
+
+// Payload converts the row for SetRoomPkg.
+func (r RoomPackageRow) Payload() types.SetRoomPkgPayload {
+	return types.SetRoomPkgPayload{
+		PropertyID:   r.PropertyID,
+		RoomType:     r.RoomType,
+		Date:         r.Date,
+		Availability: r.Availability,
+		FinalPrice:   r.FinalPrice,
+		RateFeature:  r.RateFeatures,
+	}
+}
+
+// Options configures an import.
+type Options struct {
+	Format      Format
+	Kind        Kind
+	Concurrency int       // writes in flight; default DefaultConcurrency
+	Rejects     io.Writer // receives one Reject per line as JSON; optional
+}
+
+// Reject is one failed row as written to Options.Rejects. Rejects are
+// written as rows finish, so they need not be in line order.
+type Reject struct {
+	Line int    `json:"line"` // 1-based input line the row starts on
+	Code string `json:"code"` // RoomzinError code, e.g. "VALIDATION_ERROR"
+	Msg  string `json:"message"`
+}
+
+// Summary counts the rows of an import.
+type Summary struct {
+	Rows     int `json:"rows"`
+	Imported int `json:"imported"`
+	Rejected int `json:"rejected"`
+}
+
+// job is one parsed row: send writes it, err is set when parsing failed.
+type job struct {
+	line int
+	send func(ctx context.Context) error
+	err  error
+}
+
+// Import reads every row from r and writes it with c. Row failures are
+// counted and logged to opts.Rejects; the returned error is reserved for
+// problems that stop the import: unreadable input, a bad CSV header, a
+// failed reject write or ctx ending.
+func Import(ctx context.Context, c api.CacheClientAPIContext, r io.Reader, opts Options) (Summary, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Kind != Properties && opts.Kind != RoomPackages {
+		return Summary{}, types.RzError(fmt.Errorf("importer: unknown kind %d", opts.Kind), types.KindClient)
+	}
+	var (
+		rows, imported atomic.Int64
+		rejects        = &rejectLog{w: opts.Rejects}
+		jobs           = make(chan job)
+		wg             sync.WaitGroup
+	)
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				err := j.err
+				if err == nil {
+					err = j.send(ctx)
+				}
+				if err != nil {
+					rejects.add(j.line, err)
+					continue
+				}
+				imported.Add(1)
+			}
+		}()
+	}
+
+	yield := func(j job) bool {
+		rows.Add(1)
+		select {
+		case jobs <- j:
+			return true
+		case <-ctx.Done():
+			rows.Add(-1)
+			return false
+		}
+	}
+	newJob := jobMaker(c)
+	var readErr error
+	switch opts.Format {
+	case CSV:
+		readErr = readCSV(r, opts.Kind, newJob, yield)
+	case JSONL:
+		readErr = readJSONL(r, opts.Kind, newJob, yield)
+	default:
+		readErr = types.RzError(fmt.Errorf("importer: unknown format %d", opts.Format), types.KindClient)
+	}
+	close(jobs)
+	wg.Wait()
+
+	sum := Summary{Rows: int(rows.Load()), Imported: int(imported.Load()), Rejected: rejects.count}
+	switch {
+	case readErr != nil:
+		return sum, readErr
+	case ctx.Err() != nil:
+		return sum, ctx.Err()
+	case rejects.err != nil:
+		return sum, fmt.Errorf("importer: writing rejects: %w", rejects.err)
+	}
+	return sum, nil
+}
+
+// jobMaker returns the function turning a decoded row into a job that
+// sends it. The client verifies the payload, with the codecs, clock and
+// zones it is configured with.
+func jobMaker(c api.CacheClientAPIContext) func(line int, row any) job {
+	return func(line int, row any) job {
+		switch row := row.(type) {
+		case PropertyRow:
+			p := row.Payload()
+			return job{line: line, send: func(ctx context.Context) error { return c.SetPropCtx(ctx, p) }}
+		case RoomPackageRow:
+			p := row.Payload()
+			return job{line: line, send: func(ctx context.Context) error { return c.SetRoomPkgCtx(ctx, p) }}
+		}
+		return job{line: line, err: fmt.Errorf("importer: unexpected row %T", row)}
+	}
+}
+
+// rejectLog serialises reject writes from the workers. After the first
+// write error it keeps counting but stops writing.
+type rejectLog struct {
+	mu    sync.Mutex
+	w     io.Writer
+	count int
+	err   error
+}
+
+func (l *rejectLog) add(line int, err error) {
+	rz := types.RzError(err)
+	rej := Reject{Line: line, Code: rz.Code, Msg: strings.TrimSpace(rz.Msg)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.count++
+	if l.w == nil || l.err != nil {
+		return
+	}
+	b, merr := json.Marshal(rej)
+	if merr != nil {
+		l.err = merr
+		return
+	}
+	_, l.err = l.w.Write(append(b, '\n'))
+}
+
+// rowError marks a row that could not be decoded.
+func rowError(format string, args ...any) error {
+	return types.RzError(errors.New("VALIDATION_ERROR: " + fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,219 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/importer"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+func newClient(t *testing.T) api.CacheClientAPI {
+	t.Helper()
+	c, err := roomzintest.NewMemClient(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func rejects(t *testing.T, log *bytes.Buffer) []importer.Reject {
+	t.Helper()
+	var out []importer.Reject
+	dec := json.NewDecoder(log)
+	for dec.More() {
+		var r importer.Reject
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, r)
+	}
+	slices.SortFunc(out, func(a, b importer.Reject) int { return a.Line - b.Line })
+	return out
+}
+
+func TestImportCSV(t *testing.T) {
+	c := newClient(t)
+	d := types.Today(nil, nil).AddDays(3)
+
+	// Columns in any order, a BOM, and a quoted list cell.
+	props := "\ufeffproperty_id,segment,area,property_type,category,stars,amenities\n" +
+		"hotel-1,seg,north,hotel,city,4,\"wifi, pool\"\n" +
+		"hotel-2,seg,south,hotel,city,3,\n"
+	sum, err := importer.Import(context.Background(), c, strings.NewReader(props), importer.Options{Format: importer.CSV, Kind: importer.Properties})
+	if err != nil || sum != (importer.Summary{Rows: 2, Imported: 2}) {
+		t.Fatalf("Import properties = %+v, %v", sum, err)
+	}
+	for _, id := range []string{"hotel-1", "hotel-2"} {
+		if ok, err := c.PropExist(id); err != nil || !ok {
+			t.Fatalf("PropExist(%s) = %v, %v", id, ok, err)
+		}
+	}
+
+	// A missing final_price leaves the price alone, an empty
+	// rate_features cell clears the features.
+	pkgs := "property_id,room_type,date,availability,final_price,rate_features\n" +
+		fmt.Sprintf("hotel-1,dbl,%s,5,12000,\"breakfast,half_board\"\n", d) +
+		fmt.Sprintf("hotel-2,dbl,%s,2,,\n", d)
+	sum, err = importer.Import(context.Background(), c, strings.NewReader(pkgs), importer.Options{Format: importer.CSV, Kind: importer.RoomPackages})
+	if err != nil || sum != (importer.Summary{Rows: 2, Imported: 2}) {
+		t.Fatalf("Import packages = %+v, %v", sum, err)
+	}
+	day, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: d})
+	if err != nil || day.Availability != 5 || day.FinalPrice != 12000 || !slices.Equal(day.RateFeature, []string{"breakfast", "half_board"}) {
+		t.Fatalf("hotel-1 = %+v, %v", day, err)
+	}
+	day, err = c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-2", RoomType: "dbl", Date: d})
+	if err != nil || day.Availability != 2 || day.FinalPrice != 0 || len(day.RateFeature) != 0 {
+		t.Fatalf("hotel-2 = %+v, %v", day, err)
+	}
+}
+
+func TestImportCSVHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "unknown column", header: "property_id,room_type,date,colour"},
+		{name: "duplicate column", header: "property_id,room_type,date,date"},
+		{name: "missing column", header: "property_id,room_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importer.Import(context.Background(), newClient(t), strings.NewReader(tt.header+"\n"), importer.Options{Format: importer.CSV, Kind: importer.RoomPackages})
+			if !types.IsClient(err) {
+				t.Fatalf("Import = %v, want a header error", err)
+			}
+		})
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	c := newClient(t)
+	if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+		t.Fatal(err)
+	}
+	d := types.Today(nil, nil).AddDays(3)
+
+	in := "property_id,room_type,date,availability,rate_features\n" +
+		fmt.Sprintf("hotel-1,dbl,%s,1,\n", d) + // line 2: fine
+		fmt.Sprintf("hotel-1,dbl,%s,lots,\n", d) + // line 3: does not parse
+		"hotel-1,dbl\n" + // line 4: wrong field count
+		fmt.Sprintf("hotel-1,dbl,%s,1,\n", types.Today(nil, nil).AddDays(-1)) + // line 5: past date
+		fmt.Sprintf("hotel-1,dbl,%s,1,spa\n", d) + // line 6: unknown rate feature
+		fmt.Sprintf("hotel-1,sgl,%s,2,\n", d) // line 7: fine
+	var log bytes.Buffer
+	sum, err := importer.Import(context.Background(), c, strings.NewReader(in), importer.Options{Format: importer.CSV, Kind: importer.RoomPackages, Rejects: &log})
+	if err != nil || sum != (importer.Summary{Rows: 6, Imported: 2, Rejected: 4}) {
+		t.Fatalf("Import = %+v, %v", sum, err)
+	}
+	var lines []int
+	for _, r := range rejects(t, &log) {
+		if r.Code != "VALIDATION_ERROR" || r.Msg == "" {
+			t.Fatalf("reject %+v, want a VALIDATION_ERROR with a message", r)
+		}
+		lines = append(lines, r.Line)
+	}
+	if want := []int{3, 4, 5, 6}; !slices.Equal(lines, want) {
+		t.Fatalf("rejected lines %v, want %v", lines, want)
+	}
+
+	// JSONL rows are numbered by line too, blank lines included.
+	jsonl := fmt.Sprintf(`{"property_id":"hotel-1","room_type":"dbl","date":"%s"}`, d) + "\n\n" +
+		`{"property_id":"hotel-1","room_type":"dbl","colour":"red"}` + "\n"
+	log.Reset()
+	sum, err = importer.Import(context.Background(), c, strings.NewReader(jsonl), importer.Options{Format: importer.JSONL, Kind: importer.RoomPackages, Rejects: &log})
+	if err != nil || sum != (importer.Summary{Rows: 2, Imported: 1, Rejected: 1}) {
+		t.Fatalf("Import JSONL = %+v, %v", sum, err)
+	}
+	if got := rejects(t, &log); len(got) != 1 || got[0].Line != 3 {
+		t.Fatalf("JSONL rejects = %+v, want line 3", got)
+	}
+}
+
+// TestImportClientChecks checks that rows are verified by the client as it
+// is configured, here with a clock a year back, and not by the importer.
+func TestImportClientChecks(t *testing.T) {
+	lastYear := time.Now().AddDate(-1, 0, 0)
+	c, err := roomzintest.NewMemClient(roomzintest.Options{Clock: types.ClockFunc(func() time.Time { return lastYear })})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	in := fmt.Sprintf(`{"property_id":"hotel-1","room_type":"dbl","date":"%s"}`, types.Today(nil, nil).AddDays(-30)) + "\n"
+	sum, err := importer.Import(context.Background(), c, strings.NewReader(in), importer.Options{Format: importer.JSONL, Kind: importer.RoomPackages})
+	if err != nil || sum != (importer.Summary{Rows: 1, Imported: 1}) {
+		t.Fatalf("Import = %+v, %v; want the row the client accepts imported", sum, err)
+	}
+}
+
+// slowClient counts the writes in flight.
+type slowClient struct {
+	api.CacheClientAPI
+	inFlight, peak atomic.Int32
+}
+
+func (c *slowClient) SetPropCtx(ctx context.Context, p types.SetPropPayload) error {
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if n <= peak || c.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return c.CacheClientAPI.SetPropCtx(ctx, p)
+}
+
+func TestImportConcurrency(t *testing.T) {
+	c := &slowClient{CacheClientAPI: newClient(t)}
+	var in strings.Builder
+	const rows = 40
+	for i := range rows {
+		fmt.Fprintf(&in, `{"segment":"seg","area":"a","property_id":"hotel-%d","property_type":"hotel","category":"c","stars":3}`+"\n", i)
+	}
+	sum, err := importer.Import(context.Background(), c, strings.NewReader(in.String()), importer.Options{Format: importer.JSONL, Kind: importer.Properties, Concurrency: 4})
+	if err != nil || sum != (importer.Summary{Rows: rows, Imported: rows}) {
+		t.Fatalf("Import = %+v, %v", sum, err)
+	}
+	if peak := c.peak.Load(); peak > 4 || peak < 2 {
+		t.Fatalf("%d writes in flight at most, want 2 to 4", peak)
+	}
+	for i := range rows {
+		if ok, err := c.PropExist(fmt.Sprintf("hotel-%d", i)); err != nil || !ok {
+			t.Fatalf("hotel-%d not imported: %v", i, err)
+		}
+	}
+}
+
+func TestImportCancel(t *testing.T) {
+	c := &slowClient{CacheClientAPI: newClient(t)}
+	var in strings.Builder
+	for i := range 1000 {
+		fmt.Fprintf(&in, `{"segment":"seg","area":"a","property_id":"hotel-%d","property_type":"hotel","category":"c","stars":3}`+"\n", i)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	sum, err := importer.Import(ctx, c, strings.NewReader(in.String()), importer.Options{Format: importer.JSONL, Kind: importer.Properties, Concurrency: 2})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Import = %v, want the ctx error", err)
+	}
+	if sum.Rows == 0 || sum.Rows == 1000 || sum.Imported+sum.Rejected != sum.Rows {
+		t.Fatalf("Summary = %+v after cancelling", sum)
+	}
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// maxLineSize bounds one JSONL line.
+const maxLineSize = 1 << 20
+
+var (
+	propertyColumns = []string{"segment", "area", "property_id", "property_type", "category", "stars", "latitude", "longitude", "amenities"}
+	packageColumns  = []string{"property_id", "room_type", "date", "availability", "final_price", "rate_features"}
+
+	// required columns must be in the CSV header; the rest may be left out.
+	propertyRequired = []string{"segment", "area", "property_id", "property_type", "category", "stars"}
+	packageRequired  = []string{"property_id", "room_type", "date"}
+)
+
+type (
+	newJobFunc func(line int, row any) job
+	yieldFunc  func(job) bool
+)
+
+func readJSONL(r io.Reader, kind Kind, newJob newJobFunc, yield yieldFunc) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLineSize)
+	for line := 1; sc.Scan(); line++ {
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var row any
+		var err error
+		if kind == Properties {
+			var pr PropertyRow
+			err = decodeStrict(data, &pr)
+			row = pr
+		} else {
+			var pkg RoomPackageRow
+			err = decodeStrict(data, &pkg)
+			row = pkg
+		}
+		j := job{line: line, err: err}
+		if err == nil {
+			j = newJob(line, row)
+		}
+		if !yield(j) {
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return rowError("invalid JSON row: %v", err)
+	}
+	if dec.More() {
+		return rowError("invalid JSON row: more than one value on the line")
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, kind Kind, newJob newJobFunc, yield yieldFunc) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return types.RzError(fmt.Errorf("importer: reading CSV header: %w", err), types.KindClient)
+	}
+	columns, required := propertyColumns, propertyRequired
+	if kind == RoomPackages {
+		columns, required = packageColumns, packageRequired
+	}
+	cols, err := mapHeader(header, columns, required)
+	if err != nil {
+		return types.RzError(fmt.Errorf("importer: CSV header: %w", err), types.KindClient)
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line, _ := cr.FieldPos(0)
+		var j job
+		switch {
+		case errors.Is(err, csv.ErrFieldCount):
+			j = job{line: line, err: rowError("expected %d fields, got %d", len(header), len(rec))}
+		case err != nil:
+			return types.RzError(fmt.Errorf("importer: reading CSV: %w", err), types.KindClient)
+		default:
+			cell := func(name string) (string, bool) {
+				i, ok := cols[name]
+				if !ok {
+					return "", false
+				}
+				return strings.TrimSpace(rec[i]), true
+			}
+			var row any
+			if kind == Properties {
+				row, err = propertyFromCSV(cell)
+			} else {
+				row, err = packageFromCSV(cell)
+			}
+			j = job{line: line, err: err}
+			if err == nil {
+				j = newJob(line, row)
+			}
+		}
+		if !yield(j) {
+			return nil
+		}
+	}
+}
+
+// mapHeader indexes the header by column name.
+func mapHeader(header, columns, required []string) (map[string]int, error) {
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(columns, name) {
+			return nil, fmt.Errorf("unknown column %q, expected some of %s", name, strings.Join(columns, ","))
+		}
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		cols[name] = i
+	}
+	for _, name := range required {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	return cols, nil
+}
+
+type cellFunc func(name string) (value string, present bool)
+
+func propertyFromCSV(cell cellFunc) (PropertyRow, error) {
+	var (
+		r    PropertyRow
+		errs []string
+	)
+	r.Segment, _ = cell("segment")
+	r.Area, _ = cell("area")
+	r.PropertyID, _ = cell("property_id")
+	r.PropertyType, _ = cell("property_type")
+	r.Category, _ = cell("category")
+	if s, _ := cell("stars"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid stars %q", s))
+		}
+		r.Stars = uint8(n)
+	}
+	for _, c := range []struct {
+		name string
+		dst  *float64
+	}{{"latitude", &r.Latitude}, {"longitude", &r.Longitude}} {
+		if s, _ := cell(c.name); s != "" {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("invalid %s %q", c.name, s))
+			}
+			*c.dst = f
+		}
+	}
+	if s, _ := cell("amenities"); s != "" {
+		r.Amenities = splitList(s)
+	}
+	if len(errs) > 0 {
+		return r, rowError("%s", strings.Join(errs, "; "))
+	}
+	return r, nil
+}
+
+func packageFromCSV(cell cellFunc) (RoomPackageRow, error) {
+	var (
+		r    RoomPackageRow
+		errs []string
+	)
+	r.PropertyID, _ = cell("property_id")
+	r.RoomType, _ = cell("room_type")
+	if s, _ := cell("date"); s != "" {
+		d, err := types.ParseDate(s)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		r.Date = d
+	}
+	if s, _ := cell("availability"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 8)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid availability %q", s))
+		}
+		v := uint8(n)
+		r.Availability = &v
+	}
+	if s, _ := cell("final_price"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid final_price %q", s))
+		}
+		v := uint32(n)
+		r.FinalPrice = &v
+	}
+	if s, present := cell("rate_features"); present {
+		r.RateFeatures = splitList(s)
+		if r.RateFeatures == nil {
+			r.RateFeatures = []string{}
+		}
+	}
+	if len(errs) > 0 {
+		return r, rowError("%s", strings.Join(errs, "; "))
+	}
+	return r, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+// Package batch implements api.Batch on top of a mode specific pipelined
+// sender, so single and cluster clients share queueing and parsing.
+package batch
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/internal/command"
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Sender pipelines payloads and returns one result or error per payload.
+// nonIdempotent[i] marks payloads that must not be blindly resent.
+type Sender func(ctx context.Context, payloads [][]byte, nonIdempotent []bool) ([]protocol.RawResult, []error)
+
+type item struct {
+	cmd           string
+	payload       []byte
+	nonIdempotent bool
+	parse         func(status string, fields []protocol.Field) (uint8, error)
+	err           error // validation failure; the item is not sent
+}
+
+type Batch struct {
+	codecs func() (*types.Codecs, error)
+	checks func(propertyID string) []types.VerifyOption
+	send   Sender
+	items  []item
+}
+
+// New returns a batch that verifies payloads with codecs and with the
+// date checks given for each payload's property.
+func New(codecs func() (*types.Codecs, error), checks func(propertyID string) []types.VerifyOption, send Sender) *Batch {
+	return &Batch{codecs: codecs, checks: checks, send: send}
+}
+
+func (b *Batch) Len() int { return len(b.items) }
+
+func (b *Batch) add(cmd string, payload []byte, nonIdempotent bool, parse func(string, []protocol.Field) (uint8, error)) {
+	b.items = append(b.items, item{cmd: cmd, payload: payload, nonIdempotent: nonIdempotent, parse: parse})
+}
+
+func (b *Batch) reject(cmd string, err error) {
+	b.items = append(b.items, item{cmd: cmd, err: err})
+}
+
+func noValue(parse func(string, []protocol.Field) error) func(string, []protocol.Field) (uint8, error) {
+	return func(status string, fields []protocol.Field) (uint8, error) {
+		return 0, parse(status, fields)
+	}
+}
+
+func (b *Batch) SetProp(p types.SetPropPayload) {
+	codecs, err := b.codecs()
+	if err != nil {
+		b.reject("SETPROP", err)
+		return
+	}
+	if err := p.Verify(codecs); err != nil {
+		b.reject("SETPROP", err)
+		return
+	}
+	payload, _ := command.BuildSetPropPayload(p)
+	b.add("SETPROP", payload, false, noValue(command.ParseSetPropResp))
+}
+
+func (b *Batch) SetRoomPkg(p types.SetRoomPkgPayload) {
+	codecs, err := b.codecs()
+	if err != nil {
+		b.reject("SETROOMPKG", err)
+		return
+	}
+	if err := p.Verify(codecs, b.checks(p.PropertyID)...); err != nil {
+		b.reject("SETROOMPKG", err)
+		return
+	}
+	payload, err := command.BuildSetRoomPkgPayload(p)
+	if err != nil {
+		b.reject("SETROOMPKG", err)
+		return
+	}
+	b.add("SETROOMPKG", payload, false, noValue(command.ParseSetRoomPkgResp))
+}
+
+func (b *Batch) SetRoomAvl(p types.UpdRoomAvlPayload) {
+	if err := p.Verify(b.checks(p.PropertyID)...); err != nil {
+		b.reject("SETROOMAVL", err)
+		return
+	}
+	payload, _ := command.BuildSetRoomAvlPayload(p)
+	b.add("SETROOMAVL", payload, false, command.ParseSetRoomAvlResp)
+}
+
+func (b *Batch) IncRoomAvl(p types.UpdRoomAvlPayload) {
+	if err := p.Verify(b.checks(p.PropertyID)...); err != nil {
+		b.reject("INCROOMAVL", err)
+		return
+	}
+	payload, _ := command.BuildIncRoomAvlPayload(p)
+	b.add("INCROOMAVL", payload, true, command.ParseIncRoomAvlResp)
+}
+
+func (b *Batch) DecRoomAvl(p types.UpdRoomAvlPayload) {
+	if err := p.Verify(b.checks(p.PropertyID)...); err != nil {
+		b.reject("DECROOMAVL", err)
+		return
+	}
+	payload, _ := command.BuildDecRoomAvlPayload(p)
+	b.add("DECROOMAVL", payload, true, command.ParseDecRoomAvlResp)
+}
+
+func (b *Batch) DelRoomDay(p types.DelRoomDayRequest) {
+	if err := p.Verify(b.checks(p.PropertyID)...); err != nil {
+		b.reject("DELROOMDAY", err)
+		return
+	}
+	payload, _ := command.BuildDelRoomDayPayload(p)
+	b.add("DELROOMDAY", payload, false, noValue(command.ParseDelRoomDayResp))
+}
+
+func (b *Batch) Exec(ctx context.Context) ([]types.BatchResult, error) {
+	items := b.items
+	b.items = nil
+
+	out := make([]types.BatchResult, len(items))
+	var payloads [][]byte
+	var flags []bool
+	var idx []int
+	for i, it := range items {
+		out[i].Command = it.cmd
+		if it.err != nil {
+			out[i].Err = types.RzError(it.err)
+			continue
+		}
+		payloads = append(payloads, withKey(ctx, it.payload, i))
+		flags = append(flags, it.nonIdempotent)
+		idx = append(idx, i)
+	}
+
+	if len(payloads) > 0 {
+		res, errs := b.send(ctx, payloads, flags)
+		for j, i := range idx {
+			if errs[j] != nil {
+				out[i].Err = types.RzError(errs[j])
+				continue
+			}
+			v, err := parseSafe(items[i].parse, res[j])
+			out[i].Availability = v
+			if err != nil {
+				out[i].Err = types.RzError(err)
+			}
+		}
+	}
+
+	for i := range out {
+		if out[i].Err != nil {
+			return out, out[i].Err
+		}
+	}
+	return out, nil
+}
+
+// withKey derives the key of item i from the caller's key, if any, so a
+// whole batch resent with the same key is recognised item by item.
+func withKey(ctx context.Context, payload []byte, i int) []byte {
+	key, ok := api.IdempotencyKeyFrom(ctx)
+	if !ok {
+		return payload
+	}
+	return command.AppendIdempotencyKey(payload, key+"/"+strconv.Itoa(i))
+}
+
+// parseSafe guards against parsers indexing into an empty reply, which a
+// connection drop delivers to every pending slot.
+func parseSafe(parse func(string, []protocol.Field) (uint8, error), res protocol.RawResult) (uint8, error) {
+	if res.Status == "" {
+		return 0, protocol.ErrConnClosed
+	}
+	return parse(res.Status, res.Fields)
+}
+
+// ExecDates runs the batch, whose items were queued one per entry of
+// dates, and reports each outcome against its date.
+func (b *Batch) ExecDates(ctx context.Context, dates []types.Date) ([]types.DateResult, error) {
+	res, err := b.Exec(ctx)
+	out := make([]types.DateResult, len(res))
+	for i, r := range res {
+		out[i] = types.DateResult{Date: dates[i], Err: r.Err}
+	}
+	return out, err
+}
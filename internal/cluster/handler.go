@@ -2,15 +2,19 @@ package cluster
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/roomzin/roomzin-go/internal/command"
 	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/internal/transport"
+	"github.com/roomzin/roomzin-go/types"
 )
 
 type Config struct {
@@ -23,591 +27,322 @@ type Config struct {
 	KeepAlive         time.Duration
 	MaxActiveConns    int           // hard cap on open TCP connections
 	NodeProbeInterval time.Duration // how often to health-check
+	TLSConfig         *tls.Config   // nil means plaintext TCP and HTTP
+	DisableWriteRetry bool          // see ExecuteNonIdempotent
 }
 
+// Handler sends requests through a router that knows the cluster's
+// topology, and retries the replies that say another node should answer.
 type Handler struct {
-	cfg              *Config
-	leaderHandler    *leaderHandler
-	followersHandler *followersHandler
-	respChanPool     *sync.Pool
-	reqPool          *sync.Pool
-}
-
-type request struct {
-	payload  []byte
-	respChan chan protocol.RawResult
-	ctx      context.Context
-	clrID    uint32
-}
-
-type leaderHandler struct {
-	cfg         *Config
-	reqChan     chan *request
-	conn        *connection
-	connMu      sync.RWMutex
-	clrID       uint32
-	OnReconnect func()
-}
-
-type followersHandler struct {
-	cfg         *Config
-	reqChan     chan *request
-	connections []*connection
-	connMutex   sync.RWMutex
-	clrID       uint32
-	rrIndex     atomic.Uint32
-}
-
-type demuxMap struct {
-	mu      sync.RWMutex
-	entries map[uint32]demuxEntry
-}
-
-type demuxEntry struct {
-	ch        chan protocol.RawResult
-	send_time time.Time
+	cfg       *Config
+	router    *router
+	transport *transport.Transport
 }
 
 func NewHandler(cfg *Config) *Handler {
-	return &Handler{
+	r := &router{
 		cfg: cfg,
-		leaderHandler: &leaderHandler{
-			cfg:     cfg,
-			reqChan: make(chan *request, 1024),
-			conn:    nil,
-		},
-		followersHandler: &followersHandler{
-			cfg:         cfg,
-			reqChan:     make(chan *request, 1024),
-			connections: make([]*connection, 0),
-		},
-		respChanPool: &sync.Pool{
-			New: func() any { return make(chan protocol.RawResult, 1) },
-		},
-		reqPool: &sync.Pool{
-			New: func() any { return &request{} },
+		api: newAPIClient(cfg),
+		opts: transport.Options{
+			AuthToken: cfg.AuthToken,
+			Timeout:   cfg.Timeout,
+			KeepAlive: cfg.KeepAlive,
+			TLSConfig: cfg.TLSConfig,
+			Drop:      dropOnRoleChange,
 		},
 	}
+	return &Handler{cfg: cfg, router: r, transport: transport.New(r)}
 }
 
+// SetOnReconnectCallback sets a callback run each time the leader
+// connection is lost, before redialling.
 func (c *Handler) SetOnReconnectCallback(callback func()) {
-	c.leaderHandler.OnReconnect = callback
+	c.router.mu.Lock()
+	defer c.router.mu.Unlock()
+	c.router.onLeaderLost = callback
 }
 
+// Start connects to the leader and the followers, and keeps the follower
+// set in step with the cluster, until ctx ends.
 func (c *Handler) Start(ctx context.Context) {
-	go c.leaderHandler.LeaderSyncWorker(ctx)
-	go c.followersHandler.FollowerSyncWorker(ctx)
-
-	go c.leaderHandler.LeaderSendWorker(ctx)
-	go c.followersHandler.FollowerSendWroker(ctx)
-
-	go func() {
-		t := time.NewTicker(c.cfg.Timeout)
-		defer t.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-t.C:
-				c.leaderHandler.conn.demuxMap.Cleanup(c.cfg.Timeout * 2)
-			}
-		}
-	}()
+	c.router.leader = transport.NewNode(ctx, c.router.resolveLeader, nil, c.router.opts)
+	c.router.leader.SetOnLost(c.router.leaderLost)
+	go c.router.FollowerSyncWorker(ctx)
 }
 
-// ========================================================
-//   demuxMap – with TTL-based cleanup
-// ========================================================
-
-func (m *demuxMap) Store(clrID uint32, ch chan protocol.RawResult) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	if m.entries == nil {
-		m.entries = make(map[uint32]demuxEntry)
-	}
-	m.entries[clrID] = demuxEntry{ch: ch, send_time: time.Now()}
+// Close releases the connections of the cluster API probes. Connections to
+// the nodes close when the ctx given to Start ends.
+func (c *Handler) Close() error {
+	c.router.api.Close()
+	return nil
 }
 
-func (m *demuxMap) LoadRemove(clrID uint32) (chan protocol.RawResult, time.Time, bool) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	e, ok := m.entries[clrID]
-	if ok {
-		delete(m.entries, clrID)
-	}
-	return e.ch, e.send_time, ok
-}
-
-func (m *demuxMap) Cleanup(maxAge time.Duration) {
-	threshold := time.Now().Add(-maxAge)
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	for id, e := range m.entries {
-		if e.send_time.Before(threshold) {
-			close(e.ch)
-			delete(m.entries, id)
-		}
-	}
+// dropOnRoleChange closes a connection whose node replied that it no
+// longer serves the connection's role, so the router reconnects.
+func dropOnRoleChange(res protocol.RawResult) bool {
+	if res.Status != "ERROR" || len(res.Fields) == 0 {
+		return false
+	}
+	switch string(res.Fields[0].Data) {
+	case "308": // leader changed
+		return true
+	case "405": // method not allowed - a promoted follower rejects reads
+		return true
+	case "503": // unavailable
+		return true
+	}
+	return false
 }
 
 // ========================================================
-//   connection
+//
+//	router
+//
 // ========================================================
 
-type connection struct {
-	netConn   net.Conn
-	demuxMap  *demuxMap
-	sendQueue chan []byte
-	closer    sync.Once
-	cfg       *Config
-	addr      string
-	closed    atomic.Bool
+// router sends writes to the leader and spreads reads round-robin over
+// the followers.
+type router struct {
+	cfg    *Config
+	api    *apiClient
+	opts   transport.Options
+	leader *transport.Node // set by Start
+
+	mu           sync.RWMutex
+	followers    []follower
+	onLeaderLost func()
+	rrIndex      atomic.Uint32
 }
 
-func newConnection(addr string, cfg *Config, dm *demuxMap) (*connection, error) {
-	dialer := &net.Dialer{
-		Timeout:   cfg.Timeout,
-		KeepAlive: cfg.KeepAlive,
-	}
-	conn, err := dialer.Dial("tcp", net.JoinHostPort(addr, fmt.Sprintf("%d", cfg.TCPPort)))
-	if err != nil {
-		return nil, err
-	}
-
-	loginPayload, _ := protocol.BuildLoginPayload(cfg.AuthToken)
-	frame := protocol.PrependHeader(0, loginPayload)
-	if _, err := conn.Write(frame); err != nil {
-		conn.Close()
-		return nil, err
-	}
-
-	const LOGIN_RESP_OK string = "LOGIN OK"
-	buf := make([]byte, len(LOGIN_RESP_OK))
-	if _, err := conn.Read(buf); err != nil {
-		conn.Close()
-		return nil, err
-	}
-	if string(buf) != LOGIN_RESP_OK {
-		conn.Close()
-		return nil, errors.New("login failed")
-	}
-
-	c := &connection{
-		netConn:   conn,
-		demuxMap:  dm,
-		sendQueue: make(chan []byte, 8192),
-		cfg:       cfg,
-		addr:      addr,
-	}
-
-	return c, nil
-}
-
-func (c *connection) activate() {
-	go c.writeLoop()
-	go c.readLoop()
+type follower struct {
+	addr string // host:port
+	node *transport.Node
 }
 
-func (c *connection) writeLoop() {
-	// Now start reading from the queue
-	for data := range c.sendQueue {
-		if _, err := c.netConn.Write(data); err != nil {
-			c.Close()
-			return
-		}
+// Route implements transport.Router. Reads wait, up to ctx, for a
+// follower connection.
+func (r *router) Route(ctx context.Context, write bool) (*transport.Conn, error) {
+	if write {
+		return r.leader.Conn(ctx)
 	}
-}
-
-// scoring is used for followers
-func (c *connection) readLoop() {
 	for {
-		hdr, payload, err := protocol.DrainFrame(c.netConn)
-		if err != nil {
-			c.Close()
-			return
-		}
-
-		ch, _, ok := c.demuxMap.LoadRemove(hdr.ClrID)
-		if !ok {
-			c.Close()
-			return
-		}
-
-		fields, err := protocol.ParseFields(
-			payload[1+len(hdr.Status)+2:],
-			hdr.FieldCnt,
-		)
-		if err != nil {
-			c.Close()
-			return
-		}
-
-		// --- handle real-time error hints ---
-		if hdr.Status == "ERROR" && len(fields) > 0 {
-			switch string(fields[0].Data) {
-			case "308": // leader changed
-				c.Close() // force leaderHandler sync loop to reconnect
-
-			case "405": // method not allowed - leader rejects reads
-				c.Close() // force followerHandler to remove the connection from its list
-
-			case "503": // unavailable
-				c.Close() // leader unavailable, drop to trigger resync
-
-			case "429": // busy
-			}
-		}
-
-		ch <- protocol.RawResult{Status: hdr.Status, Fields: fields}
-	}
-}
-
-func (c *connection) Close() error {
-	var err error
-	c.closer.Do(func() {
-		if c.sendQueue != nil {
-			close(c.sendQueue)
+		if conn := r.nextFollowerConnection(); conn != nil {
+			return conn, nil
 		}
-		if c.netConn != nil {
-			err = c.netConn.Close()
+		select {
+		case <-ctx.Done():
+			return nil, transport.ContextErr(ctx)
+		case <-time.After(100 * time.Millisecond):
+			// Keep waiting for connection
 		}
-		c.closed.Store(true)
-	})
-	return err
-}
-
-func (c *connection) IsClosed() bool {
-	return c.closed.Load()
-}
-
-// ========================================================
-//
-//	leaderHandler
-//
-// ========================================================
-func (lh *leaderHandler) updateConnection(newConn *connection) {
-	lh.connMu.Lock()
-	defer lh.connMu.Unlock()
-
-	// Close old connection if it exists
-	if lh.conn != nil {
-		lh.conn.Close()
 	}
-
-	lh.conn = newConn
-}
-
-func (lh *leaderHandler) getConnection() *connection {
-	lh.connMu.RLock()
-	defer lh.connMu.RUnlock()
-	return lh.conn
 }
 
-func (lh *leaderHandler) reconnectLeader() {
-	curConn := lh.getConnection()
-
-	leaderAddr, _, err := getClusterInfo(lh.cfg)
+func (r *router) resolveLeader(ctx context.Context) (string, error) {
+	leader, _, err := r.api.getClusterInfo(ctx)
 	if err != nil {
-		return
-	}
-
-	dm := &demuxMap{}
-	if curConn != nil && curConn.demuxMap != nil {
-		dm = curConn.demuxMap
+		return "", err
 	}
-
-	conn, err := newConnection(leaderAddr, lh.cfg, dm)
-	if err != nil {
-		return
-	}
-
-	lh.updateConnection(conn)
-	conn.activate()
-
-	time.Sleep(10 * time.Millisecond) // Give goroutines time to start
+	return r.nodeAddr(leader), nil
 }
 
-func (lh *leaderHandler) LeaderSyncWorker(ctx context.Context) {
-	backoff := 100 * time.Millisecond
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
-		// first check leader is healthy
-		curConn := lh.getConnection()
-		if curConn == nil || curConn.IsClosed() {
-			// Invalidate codecs via callback
-			if lh.OnReconnect != nil {
-				lh.OnReconnect()
-			}
-			lh.reconnectLeader()
-		}
-
-		// backoff with cap + jitter
-		time.Sleep(backoff + time.Duration(rand.Intn(50))*time.Millisecond)
-		if backoff < time.Second {
-			backoff *= 2
-		}
-		if backoff > 2*time.Second {
-			backoff = 2 * time.Second
-		}
+func (r *router) leaderLost() {
+	r.mu.RLock()
+	callback := r.onLeaderLost
+	r.mu.RUnlock()
+	if callback != nil {
+		callback() // invalidates codecs
 	}
 }
 
-func (lh *leaderHandler) LeaderSendWorker(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case req := <-lh.reqChan:
-			var conn *connection
-			// Wait for leader connection to be ready
-			for {
-				conn = lh.getConnection()
-				if conn != nil && conn.netConn != nil && conn.sendQueue != nil {
-					break
-				}
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(100 * time.Millisecond):
-					// Keep waiting for connection
-				}
-			}
-
-			clrID := atomic.AddUint32(&lh.clrID, 1)
-			conn.demuxMap.Store(clrID, req.respChan)
-
-			frame := protocol.PrependHeader(clrID, req.payload)
-
-			// Try to send with detailed logging
-			conn.sendQueue <- frame
-		}
-	}
+func (r *router) nodeAddr(host string) string {
+	return net.JoinHostPort(host, strconv.Itoa(r.cfg.TCPPort))
 }
 
-func (fh *followersHandler) nextFollowerConnection() (*connection, error) {
-	fh.connMutex.RLock()
-	defer fh.connMutex.RUnlock()
-
-	total := len(fh.connections)
-	if total == 0 {
-		return nil, errors.New("no follower connections available")
-	}
+// nextFollowerConnection returns the next live follower connection in
+// round-robin order, or nil when there is none.
+func (r *router) nextFollowerConnection() *transport.Conn {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
+	total := len(r.followers)
 	// Try each connection once
 	for range total {
-		idx := fh.rrIndex.Add(1) % uint32(total)
-		conn := fh.connections[idx]
-
-		if conn != nil && !conn.IsClosed() && conn.netConn != nil {
-			return conn, nil
+		idx := r.rrIndex.Add(1) % uint32(total)
+		if conn := r.followers[idx].node.Ready(); conn != nil {
+			return conn
 		}
 		// Connection unhealthy, try next one
 	}
-
-	return nil, errors.New("no valid follower connection found")
-}
-
-func (fh *followersHandler) FollowerSendWroker(ctx context.Context) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case req := <-fh.reqChan:
-			var conn *connection
-			var err error
-			for {
-				conn, err = fh.nextFollowerConnection()
-				if err == nil && conn != nil && conn.netConn != nil && conn.sendQueue != nil {
-					break
-				}
-				select {
-				case <-ctx.Done():
-					return
-				case <-time.After(100 * time.Millisecond):
-					// Keep waiting for connection
-				}
-			}
-			clrID := atomic.AddUint32(&fh.clrID, 1)
-			conn.demuxMap.Store(clrID, req.respChan)
-			frame := protocol.PrependHeader(clrID, req.payload)
-			conn.sendQueue <- frame
-		}
-	}
+	return nil
 }
 
-func (fh *followersHandler) reconnectFollower(addr string) {
-	fh.connMutex.Lock()
-	defer fh.connMutex.Unlock()
-
-	// cold path is ok to have O(n) in favor of O(1) in load balancer
-	curIdx := -1
-	for idx, con := range fh.connections {
-		if con.addr == addr {
-			if con != nil && !con.IsClosed() {
-				return // already connected
-			}
-			fh.connections[idx].Close()
-			curIdx = idx
-			break
-		}
-	}
-
-	conn, err := newConnection(addr, fh.cfg, &demuxMap{})
-	if err != nil || conn.netConn == nil || conn.sendQueue == nil {
+// syncFollowers connects to followers new to the cluster and drops the
+// ones it no longer lists. A follower that is only down stays listed, and
+// its node keeps redialling.
+func (r *router) syncFollowers(ctx context.Context) {
+	_, hosts, err := r.api.getClusterInfo(ctx)
+	if err != nil {
 		return
 	}
-
-	if curIdx != -1 {
-		fh.connections[curIdx] = conn
-	} else {
-		fh.connections = append(fh.connections, conn)
+	want := make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		want[r.nodeAddr(h)] = true
 	}
 
-	conn.activate()
-}
-
-func (fh *followersHandler) syncFollowers() {
-	_, followers, err := getClusterInfo(fh.cfg)
-	if err != nil {
-		return
+	var dropped []*transport.Node
+	r.mu.Lock()
+	kept := make([]follower, 0, len(want))
+	for _, f := range r.followers {
+		if want[f.addr] {
+			kept = append(kept, f)
+			delete(want, f.addr)
+		} else {
+			dropped = append(dropped, f.node)
+		}
 	}
+	for addr := range want {
+		kept = append(kept, follower{addr: addr, node: transport.NewNode(ctx, transport.Static(addr), nil, r.opts)})
+	}
+	r.followers = kept
+	r.mu.Unlock()
 
-	// --- add new followers we do not have yet ---
-	for _, a := range followers {
-		fh.reconnectFollower(a)
+	for _, n := range dropped {
+		n.Close()
 	}
 }
 
-func (fh *followersHandler) FollowerSyncWorker(ctx context.Context) {
-	ticker := time.NewTicker(fh.cfg.NodeProbeInterval)
+func (r *router) FollowerSyncWorker(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.NodeProbeInterval)
 	fastTick := time.NewTicker(100 * time.Millisecond)
 	defer ticker.Stop()
 	defer fastTick.Stop()
 
+	r.syncFollowers(ctx)
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-fastTick.C:
-			active := len(fh.connections)
-			for _, con := range fh.connections {
-				if con == nil || con.IsClosed() {
-					active -= 1
-				}
-			}
-			if active == 0 {
-				fh.syncFollowers()
+			if r.nextFollowerConnection() == nil {
+				r.syncFollowers(ctx)
 			}
 		case <-ticker.C:
-			fh.syncFollowers()
+			r.syncFollowers(ctx)
 		}
 	}
 }
 
-// clrID is reset on every retry; uses config timeouts.
-func (c *Handler) Execute(ctx context.Context, isWrite bool, payload []byte) (protocol.RawResult, error) {
-	if len(payload) == 0 {
-		return protocol.RawResult{}, errors.New("payload should not be empty")
-	}
+// ========================================================
+//
+//	execution and retries
+//
+// ========================================================
 
-	if isWrite && c.leaderHandler.getConnection() == nil {
-		return protocol.RawResult{}, errors.New("cluster has no leader")
-	}
+// ExecuteNonIdempotent sends a write that must not be applied twice, such as
+// INCROOMAVL or DECROOMAVL. Replies proving the node never executed it (308,
+// 405) are always retried. 503, 429 and a dropped connection are retried
+// only when the payload carries an idempotency key, with which the server
+// answers a copy that already landed, and DisableWriteRetry is unset.
+// Otherwise a dropped connection fails with types.ErrOutcomeUnknown.
+func (c *Handler) ExecuteNonIdempotent(ctx context.Context, payload []byte) (protocol.RawResult, error) {
+	return c.execute(ctx, true, payload, c.writePolicy(payload))
+}
 
-	reqAny := c.reqPool.Get()
-	if reqAny == nil {
-		return protocol.RawResult{}, errors.New("failed to get request from pool")
-	}
-	req := reqAny.(*request)
-	defer c.reqPool.Put(req)
+// Execute sends a request to the leader when isWrite is set and to a
+// follower otherwise, retrying as retryAll allows. When ctx ends first the
+// pending entry is removed from the demux map.
+func (c *Handler) Execute(ctx context.Context, isWrite bool, payload []byte) (protocol.RawResult, error) {
+	return c.execute(ctx, isWrite, payload, retryAll)
+}
 
-	respAny := c.respChanPool.Get()
-	if respAny == nil {
-		return protocol.RawResult{}, errors.New("failed to get respChan from pool")
-	}
-	respChan := respAny.(chan protocol.RawResult)
-	defer c.respChanPool.Put(respChan)
-
-	req.payload = payload
-	req.ctx = ctx
-	req.respChan = respChan
-	req.clrID = 0 // will be set on send
-
-	// choose handler
-	handlerChan := c.followersHandler.reqChan
-	if isWrite {
-		handlerChan = c.leaderHandler.reqChan
+// writePolicy returns the retry policy for a non-idempotent write.
+func (c *Handler) writePolicy(payload []byte) retryPolicy {
+	if c.cfg.DisableWriteRetry || !command.HasIdempotencyKey(payload) {
+		return retryRejected
 	}
+	return retryAll
+}
 
-	// retry policy
-	maxRetries := 5
-	attempts := 0
+type retryPolicy uint8
 
-	send := func() error {
-		select {
-		case handlerChan <- req:
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
-		}
-	}
-
-	// send first attempt
-	if err := send(); err != nil {
-		return protocol.RawResult{}, err
-	}
+const (
+	retryAll      retryPolicy = iota // 308, 405, 503, 429, dropped connection
+	retryRejected                    // 308, 405 only; the outcome of a dropped write is unknown
+)
 
-	for {
-		select {
-		case <-ctx.Done():
-			return protocol.RawResult{}, ctx.Err()
+const maxRetries = 5
 
-		case res := <-respChan:
-			if res.Status == "SUCCESS" {
-				return res, nil
-			}
+func (c *Handler) execute(ctx context.Context, isWrite bool, payload []byte, policy retryPolicy) (protocol.RawResult, error) {
+	if len(payload) == 0 {
+		return protocol.RawResult{}, errors.New("payload should not be empty")
+	}
+	res, err := c.transport.RoundTrip(ctx, isWrite, payload)
+	return c.retry(ctx, isWrite, payload, policy, res, err)
+}
 
-			// res.Status == "ERROR"
-			var errMsg string
-			if len(res.Fields) > 0 {
-				errMsg = string(res.Fields[0].Data)
-			} else {
-				errMsg = res.Status
-			}
-			backoff := false
-			switch errMsg {
-			case "405", "308":
-				// 405: follower node is promoted to leader and rejects reads
-				// 308: leader changed
-			case "503", "429": // unavailable / busy
-				backoff = true
-			default:
-				return res, nil
+// retry resends payload while policy allows for its last outcome, res or
+// err, up to maxRetries times.
+func (c *Handler) retry(ctx context.Context, isWrite bool, payload []byte, policy retryPolicy, res protocol.RawResult, err error) (protocol.RawResult, error) {
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		again, backoff := retryable(res, err, policy)
+		if !again {
+			break
+		}
+		// reroutes retry immediately, busy nodes get a growing backoff
+		if backoff {
+			select {
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			case <-ctx.Done():
+				return protocol.RawResult{}, transport.ContextErr(ctx)
 			}
+		}
+		res, err = c.transport.RoundTrip(ctx, isWrite, payload)
+	}
+	if policy == retryRejected && errors.Is(err, protocol.ErrConnClosed) {
+		return res, fmt.Errorf("%w: %w", types.ErrOutcomeUnknown, err)
+	}
+	return res, err
+}
 
-			if attempts >= maxRetries {
-				return res, nil
-			}
-			attempts++
-
-			// first retry immediately, then backoff
-			if backoff {
-				select {
-				case <-time.After(time.Duration(attempts) * 100 * time.Millisecond):
-				case <-ctx.Done():
-					return protocol.RawResult{}, ctx.Err()
-				}
-			}
+// retryable reports whether policy allows resending a request that ended
+// with res or err, and whether to back off first.
+func retryable(res protocol.RawResult, err error, policy retryPolicy) (again, backoff bool) {
+	if err != nil {
+		// The node may have executed the request before the connection
+		// dropped; the router waits for a new one.
+		return policy == retryAll && errors.Is(err, protocol.ErrConnClosed), false
+	}
+	if res.Status == "SUCCESS" {
+		return false, false
+	}
+	errMsg := res.Status
+	if len(res.Fields) > 0 {
+		errMsg = string(res.Fields[0].Data)
+	}
+	switch errMsg {
+	case "405", "308":
+		// 405: follower node is promoted to leader and rejects reads
+		// 308: leader changed
+		return true, false
+	case "503", "429": // unavailable / busy
+		return policy == retryAll, true
+	}
+	return false, false
+}
 
-			req.clrID = 0
-			if err := send(); err != nil {
-				return res, nil
-			}
+// ExecuteBatch pipelines writes to the leader: frames are queued
+// back-to-back on one connection with distinct clrIDs, then each reply is
+// retried individually like Execute. nonIdempotent[i] selects the
+// ExecuteNonIdempotent retry policy for payloads[i].
+func (c *Handler) ExecuteBatch(ctx context.Context, payloads [][]byte, nonIdempotent []bool) ([]protocol.RawResult, []error) {
+	results, errs := c.transport.RoundTripBatch(ctx, true, payloads)
+	for i := range payloads {
+		policy := retryAll
+		if nonIdempotent[i] {
+			policy = c.writePolicy(payloads[i])
 		}
+		results[i], errs[i] = c.retry(ctx, true, payloads[i], policy, results[i], errs[i])
 	}
+	return results, errs
 }
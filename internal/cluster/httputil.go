@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
 var ErrNoLeaderAvailable = errors.New("no leader found in cluster")
@@ -35,17 +36,44 @@ func parseHosts(s string) []string {
 	return out
 }
 
-func httpGet(host string, port int, path string, authToken string, timeout time.Duration, dst any) error {
-	url := fmt.Sprintf("http://%s:%d%s", host, port, path)
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+// apiClient sends one Handler's requests to the nodes' HTTP API. It owns
+// its transport, so Close can drop the connections the periodic probes
+// keep alive.
+type apiClient struct {
+	cfg  *Config
+	http *http.Client
+}
+
+func newAPIClient(cfg *Config) *apiClient {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLSConfig != nil {
+		tr.TLSClientConfig = cfg.TLSConfig.Clone()
+	}
+	return &apiClient{cfg: cfg, http: &http.Client{Timeout: cfg.HttpTimeout, Transport: tr}}
+}
+
+// Close closes the idle connections of the transport.
+func (a *apiClient) Close() {
+	a.http.CloseIdleConnections()
+}
+
+func apiURL(cfg *Config, host string, path string) string {
+	scheme := "http"
+	if cfg.TLSConfig != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(cfg.APIPort)), path)
+}
+
+func (a *apiClient) httpGet(ctx context.Context, host string, path string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL(a.cfg, host, path), nil)
 	if err != nil {
 		return err
 	}
-	if authToken != "" {
-		req.Header.Set("Authorization", "Bearer "+authToken)
+	if a.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.AuthToken)
 	}
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Do(req)
+	resp, err := a.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -59,23 +87,21 @@ func httpGet(host string, port int, path string, authToken string, timeout time.
 	return json.NewDecoder(resp.Body).Decode(dst)
 }
 
-func getNodeInfo(host string, port int, authToken string, timeout time.Duration) (NodeInfo, error) {
+func (a *apiClient) getNodeInfo(ctx context.Context, host string) (NodeInfo, error) {
 	var out NodeInfo
-	err := httpGet(host, port, "/node-info", authToken, timeout, &out)
+	err := a.httpGet(ctx, host, "/node-info", &out)
 	return out, err
 }
 
-func healthCheck(host string, port int, authToken string, timeout time.Duration) (string, error) {
-	url := fmt.Sprintf("http://%s:%d/healthz", host, port)
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+func (a *apiClient) healthCheck(ctx context.Context, host string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL(a.cfg, host, "/healthz"), nil)
 	if err != nil {
 		return "", err
 	}
-	if authToken != "" {
-		req.Header.Set("Authorization", "Bearer "+authToken)
+	if a.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.AuthToken)
 	}
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Do(req)
+	resp, err := a.http.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -93,8 +119,10 @@ func healthCheck(host string, port int, authToken string, timeout time.Duration)
 	return strings.TrimSpace(string(body)), nil
 }
 
-func getClusterInfo(cfg *Config) (string, []string, error) {
-	hosts := parseHosts(cfg.SeedHosts)
+// getClusterInfo asks the seed hosts, and the peers they list, who leads
+// and who follows. Every probe gives up when ctx is done.
+func (a *apiClient) getClusterInfo(ctx context.Context) (string, []string, error) {
+	hosts := parseHosts(a.cfg.SeedHosts)
 
 	type nodeInfo struct {
 		host      string
@@ -118,12 +146,12 @@ func getClusterInfo(cfg *Config) (string, []string, error) {
 		go func(host string) {
 			defer wg.Done()
 
-			health, e := healthCheck(host, cfg.APIPort, cfg.AuthToken, cfg.HttpTimeout)
+			health, e := a.healthCheck(ctx, host)
 			if e != nil || health == "unavailable" {
 				return
 			}
 
-			info, e := getNodeInfo(host, cfg.APIPort, cfg.AuthToken, cfg.HttpTimeout)
+			info, e := a.getNodeInfo(ctx, host)
 			if e != nil {
 				return
 			}
@@ -137,7 +165,7 @@ func getClusterInfo(cfg *Config) (string, []string, error) {
 			mu.Unlock()
 
 			var peers []string
-			err := httpGet(host, cfg.APIPort, "/peers", cfg.AuthToken, cfg.HttpTimeout, &peers)
+			err := a.httpGet(ctx, host, "/peers", &peers)
 			if err != nil {
 				return
 			}
@@ -159,12 +187,12 @@ func getClusterInfo(cfg *Config) (string, []string, error) {
 		go func(host string) {
 			defer newWg.Done()
 
-			health, e := healthCheck(host, cfg.APIPort, cfg.AuthToken, cfg.HttpTimeout)
+			health, e := a.healthCheck(ctx, host)
 			if e != nil || health == "unavailable" {
 				return
 			}
 
-			info, e := getNodeInfo(host, cfg.APIPort, cfg.AuthToken, cfg.HttpTimeout)
+			info, e := a.getNodeInfo(ctx, host)
 			if e != nil {
 				return
 			}
@@ -180,6 +208,12 @@ func getClusterInfo(cfg *Config) (string, []string, error) {
 	}
 	newWg.Wait()
 
+	// Probes cut short by ctx make a partial picture; report why
+	// rather than a leader or followers drawn from it.
+	if err := ctx.Err(); err != nil {
+		return "", nil, err
+	}
+
 	// Third phase: determine leader and followers
 	votes := make(map[string]int, len(nodes))
 	// First pass: count all votes
@@ -0,0 +1,165 @@
+package codecs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Defaults for the staleness bounds of a Cache.
+const (
+	DefaultMaxAge   = 5 * time.Minute
+	DefaultMaxStale = 30 * time.Minute
+)
+
+// Cache holds a client's codecs. Reading fresh codecs is one atomic load;
+// refreshes are single-flight, so however many goroutines find the cache
+// stale, one GETCODECS goes out and all of them share its result.
+//
+// Codecs younger than maxAge are used as they are. Older ones, up to
+// maxStale, are still used while a background refresh runs. Beyond
+// maxStale, or after Invalidate, a read waits for a refresh, and falls
+// back to the old codecs only if the refresh fails and they are younger
+// than maxStale. Otherwise the read fails with a CODECS_UNAVAILABLE
+// error, matching types.ErrCodecsUnavailable, rather than handing out
+// nil codecs.
+type Cache struct {
+	Watchers
+
+	ctx      context.Context // client lifetime; bounds refreshes
+	fetch    func(ctx context.Context) (*types.Codecs, error)
+	maxAge   time.Duration
+	maxStale time.Duration
+
+	cur atomic.Pointer[entry]
+	gen atomic.Uint64 // bumped by Invalidate
+
+	mu     sync.Mutex
+	flight *flight
+	seq    uint64 // fetches started
+
+	notifyMu sync.Mutex
+	notified uint64 // seq of the last fetch shown to watchers
+}
+
+type entry struct {
+	codecs  *types.Codecs
+	fetched time.Time
+	gen     uint64 // Cache.gen when the fetch started
+	seq     uint64 // of the fetch
+}
+
+type flight struct {
+	done   chan struct{}
+	codecs *types.Codecs
+	err    error
+	gen    uint64 // Cache.gen when the fetch started
+}
+
+// NewCache returns an empty cache filled by fetch, which is called with
+// ctx. Zero bounds take the defaults; maxStale is at least maxAge.
+func NewCache(ctx context.Context, fetch func(ctx context.Context) (*types.Codecs, error), maxAge, maxStale time.Duration) *Cache {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	if maxStale <= 0 {
+		maxStale = DefaultMaxStale
+	}
+	if maxStale < maxAge {
+		maxStale = maxAge
+	}
+	return &Cache{ctx: ctx, fetch: fetch, maxAge: maxAge, maxStale: maxStale}
+}
+
+// Get returns codecs within the staleness bounds, refreshing as needed.
+func (c *Cache) Get(ctx context.Context) (*types.Codecs, error) {
+	e := c.cur.Load()
+	if e != nil && e.gen == c.gen.Load() {
+		switch age := time.Since(e.fetched); {
+		case age < c.maxAge:
+			return e.codecs, nil
+		case age < c.maxStale:
+			c.start()
+			return e.codecs, nil
+		}
+	}
+	codecs, err := c.Refresh(ctx)
+	if err == nil {
+		return codecs, nil
+	}
+	if e != nil && time.Since(e.fetched) < c.maxStale {
+		return e.codecs, nil
+	}
+	return nil, types.RzError("CODECS_UNAVAILABLE: " + err.Error())
+}
+
+// Refresh fetches the codecs now, joining a fetch already in flight, and
+// caches them. Only ctx ending stops the wait; the fetch itself runs
+// under the client's lifetime so other waiters still get its result.
+func (c *Cache) Refresh(ctx context.Context) (*types.Codecs, error) {
+	f := c.start()
+	select {
+	case <-f.done:
+		return f.codecs, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Invalidate makes the next Get wait for a refresh, as codecs may have
+// changed while the connection was down. Fetches started before the call
+// do not count as a refresh, and later reads do not join them.
+func (c *Cache) Invalidate() {
+	c.gen.Add(1)
+}
+
+// start returns the fetch in flight, starting one if there is none or the
+// one in flight started before the last Invalidate. A fetch never replaces
+// the codecs of a later one.
+func (c *Cache) start() *flight {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	gen := c.gen.Load()
+	if c.flight != nil && c.flight.gen == gen {
+		return c.flight
+	}
+	f := &flight{done: make(chan struct{}), gen: gen}
+	c.flight = f
+	c.seq++
+	seq := c.seq
+	go func() {
+		f.codecs, f.err = c.fetch(c.ctx)
+		if f.err == nil && f.codecs == nil {
+			f.err = types.ErrCodecsUnavailable
+		}
+		c.mu.Lock()
+		if cur := c.cur.Load(); f.err == nil && (cur == nil || cur.seq < seq) {
+			c.cur.Store(&entry{codecs: f.codecs, fetched: time.Now(), gen: gen, seq: seq})
+		}
+		if c.flight == f {
+			c.flight = nil
+		}
+		c.mu.Unlock()
+		close(f.done)
+		if f.err == nil {
+			c.notify(seq, f.codecs)
+		}
+	}()
+	return f
+}
+
+// notify shows watchers the codecs of fetch seq, unless a later fetch was
+// already shown, so they see versions in fetch order. Watchers run after
+// the flight is done, so one may call GetCodecs without deadlocking.
+func (c *Cache) notify(seq uint64, codecs *types.Codecs) {
+	c.notifyMu.Lock()
+	defer c.notifyMu.Unlock()
+	if seq <= c.notified {
+		return
+	}
+	c.notified = seq
+	c.Observe(codecs)
+}
@@ -0,0 +1,176 @@
+package codecs_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/internal/codecs"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// server hands out codecs v1, v2, ... one per fetch. A held fetch waits
+// for its release before answering.
+type server struct {
+	fetches atomic.Int32
+	fail    atomic.Bool
+	mu      sync.Mutex
+	gates   map[int32]chan struct{}
+}
+
+// hold makes fetch n wait until release is called.
+func (s *server) hold(n int32) (release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.gates == nil {
+		s.gates = make(map[int32]chan struct{})
+	}
+	g := make(chan struct{})
+	s.gates[n] = g
+	return func() { close(g) }
+}
+
+func (s *server) fetch(ctx context.Context) (*types.Codecs, error) {
+	n := s.fetches.Add(1)
+	s.mu.Lock()
+	g := s.gates[n]
+	s.mu.Unlock()
+	if g != nil {
+		select {
+		case <-g:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if s.fail.Load() {
+		return nil, errors.New("server down")
+	}
+	return &types.Codecs{Version: "v" + strconv.Itoa(int(n))}, nil
+}
+
+func version(t *testing.T, c *codecs.Cache) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	got, err := c.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got.Version
+}
+
+func TestCacheSingleFlight(t *testing.T) {
+	var s server
+	release := s.hold(1)
+	c := codecs.NewCache(context.Background(), s.fetch, time.Minute, time.Hour)
+
+	const readers = 16
+	got := make(chan string, readers)
+	for range readers {
+		go func() {
+			codecs, err := c.Get(context.Background())
+			if err != nil {
+				got <- err.Error()
+				return
+			}
+			got <- codecs.Version
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	release()
+	for range readers {
+		if v := <-got; v != "v1" {
+			t.Fatalf("Get = %s, want v1", v)
+		}
+	}
+	if n := s.fetches.Load(); n != 1 {
+		t.Fatalf("%d fetches, want 1", n)
+	}
+}
+
+func TestCacheStaleness(t *testing.T) {
+	t.Run("stale codecs are served while refreshing", func(t *testing.T) {
+		var s server
+		c := codecs.NewCache(context.Background(), s.fetch, 20*time.Millisecond, time.Hour)
+		if v := version(t, c); v != "v1" {
+			t.Fatalf("Get = %s, want v1", v)
+		}
+		release := s.hold(2)
+		time.Sleep(30 * time.Millisecond)
+		for range 3 {
+			if v := version(t, c); v != "v1" {
+				t.Fatalf("stale Get = %s, want v1 while the refresh runs", v)
+			}
+		}
+		release()
+		deadline := time.Now().Add(2 * time.Second)
+		for version(t, c) != "v2" {
+			if time.Now().After(deadline) {
+				t.Fatal("the background refresh never landed")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if n := s.fetches.Load(); n != 2 {
+			t.Fatalf("%d fetches, want 2", n)
+		}
+	})
+
+	t.Run("too stale codecs wait for a refresh", func(t *testing.T) {
+		var s server
+		c := codecs.NewCache(context.Background(), s.fetch, 20*time.Millisecond, 20*time.Millisecond)
+		if v := version(t, c); v != "v1" {
+			t.Fatalf("Get = %s, want v1", v)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if v := version(t, c); v != "v2" {
+			t.Fatalf("Get past maxStale = %s, want v2", v)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		s.fail.Store(true)
+		if _, err := c.Get(context.Background()); !errors.Is(err, types.ErrCodecsUnavailable) {
+			t.Fatalf("Get past maxStale with the server down = %v, want CODECS_UNAVAILABLE", err)
+		}
+	})
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	var s server
+	c := codecs.NewCache(context.Background(), s.fetch, time.Minute, time.Hour)
+	if v := version(t, c); v != "v1" {
+		t.Fatalf("Get = %s, want v1", v)
+	}
+
+	// A refresh starts and hangs, as if sent just before the connection
+	// dropped; the drop invalidates the cache.
+	release := s.hold(2)
+	refreshed := make(chan string, 1)
+	go func() {
+		codecs, err := c.Refresh(context.Background())
+		if err != nil {
+			refreshed <- err.Error()
+			return
+		}
+		refreshed <- codecs.Version
+	}()
+	for s.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Invalidate()
+
+	// Reads after Invalidate must not join the hung fetch.
+	if v := version(t, c); v != "v3" {
+		t.Fatalf("Get after Invalidate = %s, want v3", v)
+	}
+	release()
+	if v := <-refreshed; v != "v2" {
+		t.Fatalf("the hung Refresh = %s, want v2", v)
+	}
+	if v := version(t, c); v != "v3" {
+		t.Fatalf("Get = %s after the older fetch landed, want v3", v)
+	}
+}
@@ -0,0 +1,60 @@
+// Package codecs tracks the codecs a client has seen, so every client
+// mode tells WatchCodecs callers about version changes the same way.
+package codecs
+
+import (
+	"sync"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Watchers holds the WatchCodecs callbacks of one client and the last
+// codecs it fetched.
+type Watchers struct {
+	mu   sync.Mutex
+	last *types.Codecs
+	fns  map[int]func(old, new *types.Codecs)
+	next int
+}
+
+// Watch registers fn and returns a function that unregisters it.
+func (w *Watchers) Watch(fn func(old, new *types.Codecs)) (stop func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fns == nil {
+		w.fns = make(map[int]func(old, new *types.Codecs))
+	}
+	id := w.next
+	w.next++
+	w.fns[id] = fn
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.fns, id)
+	}
+}
+
+// Observe records freshly fetched codecs. When their version differs
+// from the last ones, every watcher is called with both, after the lock
+// is released so a watcher may use the client.
+func (w *Watchers) Observe(c *types.Codecs) {
+	if c == nil {
+		return
+	}
+	w.mu.Lock()
+	old := w.last
+	w.last = c
+	if old == nil || old.Version == c.Version {
+		w.mu.Unlock()
+		return
+	}
+	fns := make([]func(old, new *types.Codecs), 0, len(w.fns))
+	for _, fn := range w.fns {
+		fns = append(fns, fn)
+	}
+	w.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(old, c)
+	}
+}
@@ -0,0 +1,353 @@
+package command
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// decode splits a built payload back into its command name and fields.
+func decode(t *testing.T, payload []byte) (string, []protocol.Field) {
+	t.Helper()
+	n := int(payload[0])
+	cmd := string(payload[1 : 1+n])
+	cnt := binary.LittleEndian.Uint16(payload[1+n : 3+n])
+	fields, err := protocol.ParseFields(payload[3+n:], cnt)
+	if err != nil {
+		t.Fatalf("%s: %v", cmd, err)
+	}
+	return cmd, fields
+}
+
+func str(id uint16, s string) protocol.Field {
+	return protocol.Field{ID: id, FieldType: 0x01, Data: []byte(s)}
+}
+
+func u8(id uint16, v uint8) protocol.Field {
+	return protocol.Field{ID: id, FieldType: 0x02, Data: []byte{v}}
+}
+
+func u32(id uint16, v uint32) protocol.Field {
+	return protocol.Field{ID: id, FieldType: 0x03, Data: protocol.MakeU32(v)}
+}
+
+func TestBuildPayloads(t *testing.T) {
+	d := types.NewDate(2030, 5, 17)
+	avl, price := uint8(3), uint32(12500)
+
+	tests := []struct {
+		name   string
+		build  func() ([]byte, error)
+		cmd    string
+		fields []protocol.Field
+	}{
+		{
+			name: "set room avl",
+			build: func() ([]byte, error) {
+				return BuildSetRoomAvlPayload(types.UpdRoomAvlPayload{PropertyID: "p1", RoomType: "dbl", Date: d, Amount: 4})
+			},
+			cmd:    "SETROOMAVL",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17"), u8(4, 4)},
+		},
+		{
+			name: "inc room avl",
+			build: func() ([]byte, error) {
+				return BuildIncRoomAvlPayload(types.UpdRoomAvlPayload{PropertyID: "p1", RoomType: "dbl", Date: d, Amount: 1})
+			},
+			cmd:    "INCROOMAVL",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17"), u8(4, 1)},
+		},
+		{
+			name: "dec room avl",
+			build: func() ([]byte, error) {
+				return BuildDecRoomAvlPayload(types.UpdRoomAvlPayload{PropertyID: "p1", RoomType: "dbl", Date: d, Amount: 2})
+			},
+			cmd:    "DECROOMAVL",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17"), u8(4, 2)},
+		},
+		{
+			name: "set room avl if",
+			build: func() ([]byte, error) {
+				return BuildSetRoomAvlIfPayload(types.SetRoomAvlIfPayload{PropertyID: "p1", RoomType: "dbl", Date: d, Expected: 5, Amount: 4})
+			},
+			cmd:    "SETROOMAVLIF",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17"), u8(4, 5), u8(5, 4)},
+		},
+		{
+			name: "set room pkg, date only",
+			build: func() ([]byte, error) {
+				return BuildSetRoomPkgPayload(types.SetRoomPkgPayload{PropertyID: "p1", RoomType: "dbl", Date: d})
+			},
+			cmd:    "SETROOMPKG",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17")},
+		},
+		{
+			name: "set room pkg, every field",
+			build: func() ([]byte, error) {
+				return BuildSetRoomPkgPayload(types.SetRoomPkgPayload{PropertyID: "p1", RoomType: "dbl", Date: d, Availability: &avl, FinalPrice: &price, RateFeature: []string{"breakfast", "free_cancellation"}})
+			},
+			cmd:    "SETROOMPKG",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17"), u8(4, 3), u32(5, 12500), str(6, "breakfast,free_cancellation")},
+		},
+		{
+			name: "get prop room day",
+			build: func() ([]byte, error) {
+				return BuildGetPropRoomDayPayload(types.GetRoomDayRequest{PropertyID: "p1", RoomType: "dbl", Date: d})
+			},
+			cmd:    "GETPROPROOMDAY",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17")},
+		},
+		{
+			name: "del room day",
+			build: func() ([]byte, error) {
+				return BuildDelRoomDayPayload(types.DelRoomDayRequest{PropertyID: "p1", RoomType: "dbl", Date: d})
+			},
+			cmd:    "DELROOMDAY",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl"), str(3, "2030-05-17")},
+		},
+		{
+			name: "del prop day",
+			build: func() ([]byte, error) {
+				return BuildDelPropDayPayload(types.DelPropDayRequest{PropertyID: "p1", Date: d})
+			},
+			cmd:    "DELPROPDAY",
+			fields: []protocol.Field{str(1, "p1"), str(2, "2030-05-17")},
+		},
+		{
+			name: "prop room date list",
+			build: func() ([]byte, error) {
+				return BuildPropRoomDateListPayload(types.PropRoomDateListPayload{PropertyID: "p1", RoomType: "dbl"})
+			},
+			cmd:    "PROPROOMDATELIST",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl")},
+		},
+		{
+			name: "prop room exist",
+			build: func() ([]byte, error) {
+				return BuildPropRoomExistPayload(types.PropRoomExistPayload{PropertyID: "p1", RoomType: "dbl"})
+			},
+			cmd:    "PROPROOMEXIST",
+			fields: []protocol.Field{str(1, "p1"), str(2, "dbl")},
+		},
+		{
+			name:   "prop exist",
+			build:  func() ([]byte, error) { return BuildPropExistPayload("p1") },
+			cmd:    "PROPEXIST",
+			fields: []protocol.Field{str(1, "p1")},
+		},
+		{
+			name:   "get segments",
+			build:  BuildGetSegmentsPayload,
+			cmd:    "GETSEGMENTS",
+			fields: []protocol.Field{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			cmd, fields := decode(t, payload)
+			if cmd != tt.cmd {
+				t.Fatalf("command = %q, want %q", cmd, tt.cmd)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Fatalf("fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestBuildSetRoomPkgRequiresFields(t *testing.T) {
+	if _, err := BuildSetRoomPkgPayload(types.SetRoomPkgPayload{PropertyID: "p1", RoomType: "dbl"}); err == nil {
+		t.Fatal("built a SETROOMPKG without a date")
+	}
+}
+
+func TestAppendIdempotencyKey(t *testing.T) {
+	payload, _ := BuildPropExistPayload("p1")
+
+	if got := AppendIdempotencyKey(payload, ""); !reflect.DeepEqual(got, payload) {
+		t.Fatalf("an empty key changed the payload")
+	}
+
+	_, fields := decode(t, AppendIdempotencyKey(payload, "k1"))
+	want := []protocol.Field{str(1, "p1"), str(IdempotencyKeyField, "k1")}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("fields = %v, want %v", fields, want)
+	}
+
+	if HasIdempotencyKey(payload) || !HasIdempotencyKey(AppendIdempotencyKey(payload, "k1")) {
+		t.Fatal("HasIdempotencyKey does not see the key field")
+	}
+}
+
+func TestParseScalarResps(t *testing.T) {
+	parsers := map[string]func(string, []protocol.Field) (uint8, error){
+		"SETROOMAVL":   ParseSetRoomAvlResp,
+		"INCROOMAVL":   ParseIncRoomAvlResp,
+		"DECROOMAVL":   ParseDecRoomAvlResp,
+		"SETROOMAVLIF": ParseSetRoomAvlIfResp,
+	}
+	tests := []struct {
+		name    string
+		status  string
+		fields  []protocol.Field
+		want    uint8
+		wantErr string
+	}{
+		{name: "success", status: "SUCCESS", fields: []protocol.Field{u8(1, 7)}, want: 7},
+		{name: "bad scalar", status: "SUCCESS", fields: []protocol.Field{u32(1, 7)}, wantErr: "RESPONSE_ERROR: missing or invalid scalar value"},
+		{name: "server error", status: "ERROR", fields: []protocol.Field{str(1, "UNDERFLOW: not enough rooms")}, wantErr: "UNDERFLOW: not enough rooms"},
+	}
+	for cmd, parse := range parsers {
+		for _, tt := range tests {
+			t.Run(cmd+"/"+tt.name, func(t *testing.T) {
+				got, err := parse(tt.status, tt.fields)
+				if tt.wantErr != "" {
+					if err == nil || err.Error() != tt.wantErr {
+						t.Fatalf("err = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil || got != tt.want {
+					t.Fatalf("got %d, %v; want %d", got, err, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestParseSetRoomAvlIfConflict(t *testing.T) {
+	got, err := ParseSetRoomAvlIfResp("ERROR", []protocol.Field{str(1, "CONFLICT: availability is 2"), u8(2, 2)})
+	if err == nil || err.Error() != "CONFLICT: availability is 2" || got != 2 {
+		t.Fatalf("got %d, %v; want the current availability with the CONFLICT", got, err)
+	}
+	if !types.IsConflict(types.RzError(err)) {
+		t.Fatalf("%v does not classify as a conflict", err)
+	}
+}
+
+func TestParseStatusResps(t *testing.T) {
+	parsers := map[string]func(string, []protocol.Field) error{
+		"SETPROP":     ParseSetPropResp,
+		"SETROOMPKG":  ParseSetRoomPkgResp,
+		"DELROOMDAY":  ParseDelRoomDayResp,
+		"DELPROPDAY":  ParseDelPropDayResp,
+		"DELPROPROOM": ParseDelPropRoomResp,
+	}
+	for cmd, parse := range parsers {
+		t.Run(cmd, func(t *testing.T) {
+			if err := parse("SUCCESS", nil); err != nil {
+				t.Fatalf("SUCCESS: %v", err)
+			}
+			err := parse("ERROR", []protocol.Field{str(1, "NOT_FOUND: no such day")})
+			if err == nil || err.Error() != "NOT_FOUND: no such day" {
+				t.Fatalf("err = %v, want the server message", err)
+			}
+		})
+	}
+}
+
+func TestParsePropRoomDateListResp(t *testing.T) {
+	got, err := ParsePropRoomDateListResp("SUCCESS", []protocol.Field{str(1, "2030-05-18"), str(2, ""), str(3, "2030-05-17")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []types.Date{types.NewDate(2030, 5, 17), types.NewDate(2030, 5, 18)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("dates = %v, want %v", got, want)
+	}
+	if _, err := ParsePropRoomDateListResp("SUCCESS", []protocol.Field{str(1, "2030-02-30")}); err == nil {
+		t.Fatal("parsed an impossible date")
+	}
+}
+
+func TestParseGetSegmentsResp(t *testing.T) {
+	tests := []struct {
+		name    string
+		fields  []protocol.Field
+		want    []types.SegmentInfo
+		wantErr bool
+	}{
+		{name: "empty", fields: nil, want: []types.SegmentInfo{}},
+		{
+			name:   "pairs",
+			fields: []protocol.Field{str(1, "eu"), u32(2, 12), str(3, "us"), u32(4, 3)},
+			want:   []types.SegmentInfo{{Segment: "eu", PropCount: 12}, {Segment: "us", PropCount: 3}},
+		},
+		{name: "odd count", fields: []protocol.Field{str(1, "eu")}, wantErr: true},
+		{name: "count not u32", fields: []protocol.Field{str(1, "eu"), u8(2, 1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGetSegmentsResp("SUCCESS", tt.fields)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGetCodecsResp(t *testing.T) {
+	list := func(id uint16, s string) protocol.Field {
+		return protocol.Field{ID: id, FieldType: 0x09, Data: []byte(s)}
+	}
+	horizon := protocol.Field{ID: 2, FieldType: 0x02, Data: []byte{0x6d, 0x01}}
+	none := []string{}
+
+	tests := []struct {
+		name    string
+		fields  []protocol.Field
+		want    types.Codecs
+		wantErr bool
+	}{
+		{name: "baseline", fields: []protocol.Field{list(7, "breakfast,refundable")}, want: types.Codecs{RateFeatures: []string{"breakfast", "refundable"}, Amenities: none, PropertyTypes: none, Categories: none}},
+		{name: "baseline with horizon", fields: []protocol.Field{list(0, "breakfast"), horizon}, want: types.Codecs{RateFeatures: []string{"breakfast"}, BookingHorizon: 365, Amenities: none, PropertyTypes: none, Categories: none}},
+		{name: "empty rate features", fields: []protocol.Field{list(1, "")}, want: types.Codecs{RateFeatures: none, Amenities: none, PropertyTypes: none, Categories: none}},
+		{
+			name:   "some dictionaries, no version",
+			fields: []protocol.Field{list(1, "breakfast"), list(5, "hotel")},
+			want:   types.Codecs{RateFeatures: []string{"breakfast"}, Amenities: none, PropertyTypes: []string{"hotel"}, Categories: none},
+		},
+		{
+			name:   "all fields",
+			fields: []protocol.Field{horizon, list(1, "breakfast"), str(3, "v7"), list(4, "wifi,pool"), list(5, "hotel"), list(6, "city"), str(9, "unknown")},
+			want:   types.Codecs{RateFeatures: []string{"breakfast"}, BookingHorizon: 365, Version: "v7", Amenities: []string{"wifi", "pool"}, PropertyTypes: []string{"hotel"}, Categories: []string{"city"}},
+		},
+		{name: "no fields", wantErr: true},
+		{name: "rate features not a list", fields: []protocol.Field{str(1, "breakfast")}, wantErr: true},
+		{name: "horizon not u16", fields: []protocol.Field{list(1, ""), u8(2, 1)}, wantErr: true},
+		{name: "dictionary not a list", fields: []protocol.Field{list(1, ""), str(4, "wifi")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGetCodecsResp("SUCCESS", tt.fields)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want.Version == "" {
+				tt.want.Version = tt.want.Digest()
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Fatalf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -26,7 +26,7 @@ func BuildDecRoomAvlPayload(p types.UpdRoomAvlPayload) ([]byte, error) {
 	}{
 		{0x01, 0x01, []byte(p.PropertyID)},
 		{0x02, 0x01, []byte(p.RoomType)},
-		{0x03, 0x01, []byte(p.Date)},
+		{0x03, 0x01, []byte(p.Date.String())},
 		{0x04, 0x02, []byte{p.Amount}},
 	}
 	for _, f := range fields {
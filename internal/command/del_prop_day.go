@@ -26,7 +26,7 @@ func BuildDelPropDayPayload(p types.DelPropDayRequest) ([]byte, error) {
 		data []byte
 	}{
 		{0x01, 0x01, []byte(p.PropertyID)},
-		{0x02, 0x01, []byte(p.Date)},
+		{0x02, 0x01, []byte(p.Date.String())},
 	}
 	for _, f := range fields {
 		idBytes := make([]byte, 2)
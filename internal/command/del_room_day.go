@@ -27,7 +27,7 @@ func BuildDelRoomDayPayload(p types.DelRoomDayRequest) ([]byte, error) {
 	}{
 		{0x01, 0x01, []byte(p.PropertyID)},
 		{0x02, 0x01, []byte(p.RoomType)},
-		{0x03, 0x01, []byte(p.Date)},
+		{0x03, 0x01, []byte(p.Date.String())},
 	}
 	for _, f := range fields {
 		idBytes := make([]byte, 2)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/roomzin/roomzin-go/internal/protocol"
@@ -32,20 +33,74 @@ func ParseGetCodecsResp(status string, fields []protocol.Field) (*types.Codecs,
 		return nil, fmt.Errorf("unknown error")
 	}
 
-	// GETCODECS response should have exactly 1 field with type 0x09
-	if len(fields) != 1 {
-		return nil, fmt.Errorf("invalid field count: expected 1 field, got %d", len(fields))
+	// GETCODECS response fields, by ID:
+	//   1 rate features, comma separated (0x09)
+	//   2 booking horizon in days, u16 (0x02)
+	//   3 codecs version (0x01)
+	//   4 amenities, 5 property types, 6 categories, comma separated (0x09)
+	// Fields 2-6 are optional extensions; unknown IDs are skipped so newer
+	// servers can add dictionaries. Servers predating the extensions send
+	// the rate features alone, in one 0x09 field under any ID, so without
+	// a field 1 the first field holds them.
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("missing rate features field")
 	}
-
-	field := fields[0]
-	if field.FieldType != 0x09 {
-		return nil, fmt.Errorf("expected YAML field type 0x09, got type %d", field.FieldType)
+	rf := slices.IndexFunc(fields, func(f protocol.Field) bool { return f.ID == 1 })
+	if rf < 0 {
+		rf = 0
+	}
+	if fields[rf].FieldType != 0x09 {
+		return nil, fmt.Errorf("expected YAML field type 0x09, got type %d", fields[rf].FieldType)
+	}
+	codecs := &types.Codecs{RateFeatures: splitList(fields[rf].Data)}
+	for i, f := range fields {
+		if i == rf {
+			continue
+		}
+		switch f.ID {
+		case 2:
+			if f.FieldType != 0x02 || len(f.Data) != 2 {
+				return nil, fmt.Errorf("invalid booking horizon field")
+			}
+			codecs.BookingHorizon = int(binary.LittleEndian.Uint16(f.Data))
+		case 3:
+			if f.FieldType != 0x01 {
+				return nil, fmt.Errorf("invalid codecs version field")
+			}
+			codecs.Version = string(f.Data)
+		case 4, 5, 6:
+			if f.FieldType != 0x09 {
+				return nil, fmt.Errorf("expected YAML field type 0x09 for field %d, got type %d", f.ID, f.FieldType)
+			}
+			list := splitList(f.Data)
+			switch f.ID {
+			case 4:
+				codecs.Amenities = list
+			case 5:
+				codecs.PropertyTypes = list
+			case 6:
+				codecs.Categories = list
+			}
+		}
+	}
+	// Absent extensions default rather than fail: no dictionary is an
+	// empty one, and without a version the digest identifies the codecs.
+	for _, dict := range []*[]string{&codecs.Amenities, &codecs.PropertyTypes, &codecs.Categories} {
+		if *dict == nil {
+			*dict = []string{}
+		}
+	}
+	if codecs.Version == "" {
+		codecs.Version = codecs.Digest()
 	}
 
-	rateFeatures := strings.Split(string(field.Data), ",")
-
-	return &types.Codecs{
-		RateFeatures: rateFeatures,
-	}, nil
+	return codecs, nil
+}
 
+// splitList splits a comma separated list; empty data is an empty list.
+func splitList(data []byte) []string {
+	if len(data) == 0 {
+		return []string{}
+	}
+	return strings.Split(string(data), ",")
 }
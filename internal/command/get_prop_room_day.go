@@ -9,6 +9,7 @@ import (
 	"github.com/roomzin/roomzin-go/internal/protocol"
 
 	"errors"
+	"fmt"
 )
 
 func BuildGetPropRoomDayPayload(p types.GetRoomDayRequest) ([]byte, error) {
@@ -27,7 +28,7 @@ func BuildGetPropRoomDayPayload(p types.GetRoomDayRequest) ([]byte, error) {
 	}{
 		{0x01, 0x01, []byte(p.PropertyID)},
 		{0x02, 0x01, []byte(p.RoomType)},
-		{0x03, 0x01, []byte(p.Date)},
+		{0x03, 0x01, []byte(p.Date.String())},
 	}
 	for _, f := range fields {
 		idBytes := make([]byte, 2)
@@ -44,9 +45,13 @@ func ParseGetPropRoomDayResp(codecs *types.Codecs, status string, fields []proto
 	var res types.GetRoomDayResult
 	if status == "SUCCESS" {
 		chunk := fields[:5]
+		date, err := types.ParseDate(string(chunk[1].Data))
+		if err != nil {
+			return res, fmt.Errorf("RESPONSE_ERROR: %w", err)
+		}
 		return types.GetRoomDayResult{
 			PropertyID:   string(chunk[0].Data),
-			Date:         string(chunk[1].Data),
+			Date:         date,
 			Availability: chunk[2].Data[0],
 			FinalPrice:   binary.LittleEndian.Uint32(chunk[3].Data),
 			RateFeature:  protocol.BitmaskToRateFeatureStrings(codecs, binary.LittleEndian.Uint32(chunk[4].Data)),
@@ -0,0 +1,64 @@
+package command
+
+import (
+	"encoding/binary"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+)
+
+// IdempotencyKeyField is the field id carrying the idempotency key on
+// mutating commands. It sits above every command specific field id so it
+// can be appended to any payload.
+//
+// The field is an extension of the wire protocol that older servers do not
+// know, and some reject unknown field ids. It is therefore only sent when
+// the caller supplied a key with api.WithIdempotencyKey; plain writes go
+// out exactly as the baseline protocol defines them.
+const IdempotencyKeyField uint16 = 0xFE
+
+// AppendIdempotencyKey appends the idempotency key field to an already
+// built command payload and bumps its field count. The server uses the key
+// to recognise a resent write and answer it without applying it twice. An
+// empty key leaves the payload untouched.
+func AppendIdempotencyKey(payload []byte, key string) []byte {
+	if len(payload) == 0 || key == "" {
+		return payload
+	}
+	cntAt := 1 + int(payload[0])
+	if len(payload) < cntAt+2 {
+		return payload
+	}
+
+	out := make([]byte, 0, len(payload)+7+len(key))
+	out = append(out, payload...)
+	cnt := binary.LittleEndian.Uint16(out[cntAt : cntAt+2])
+	binary.LittleEndian.PutUint16(out[cntAt:cntAt+2], cnt+1)
+
+	out = binary.LittleEndian.AppendUint16(out, IdempotencyKeyField)
+	out = append(out, 0x01) // type string
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(key)))
+	out = append(out, key...)
+	return out
+}
+
+// HasIdempotencyKey reports whether payload carries an idempotency key
+// field, that is whether the server can tell a resent copy of it apart.
+func HasIdempotencyKey(payload []byte) bool {
+	if len(payload) == 0 {
+		return false
+	}
+	cntAt := 1 + int(payload[0])
+	if len(payload) < cntAt+2 {
+		return false
+	}
+	fields, err := protocol.ParseFields(payload[cntAt+2:], binary.LittleEndian.Uint16(payload[cntAt:cntAt+2]))
+	if err != nil {
+		return false
+	}
+	for _, f := range fields {
+		if f.ID == IdempotencyKeyField && len(f.Data) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -39,19 +39,25 @@ func BuildPropRoomDateListPayload(p types.PropRoomDateListPayload) ([]byte, erro
 	return buf.Bytes(), nil
 }
 
-func ParsePropRoomDateListResp(status string, fields []protocol.Field) ([]string, error) {
+func ParsePropRoomDateListResp(status string, fields []protocol.Field) ([]types.Date, error) {
 	if status != "SUCCESS" {
 		if len(fields) > 0 && fields[0].FieldType == 0x01 {
 			return nil, fmt.Errorf("%s", string(fields[0].Data))
 		}
 		return nil, fmt.Errorf("RESPONSE_ERROR")
 	}
-	out := make([]string, 0, len(fields))
+	out := make([]types.Date, 0, len(fields))
 	for i := range fields {
-		if s := string(fields[i].Data); s != "" {
-			out = append(out, s)
+		s := string(fields[i].Data)
+		if s == "" {
+			continue
 		}
+		d, err := types.ParseDate(s)
+		if err != nil {
+			return nil, fmt.Errorf("RESPONSE_ERROR: %w", err)
+		}
+		out = append(out, d)
 	}
-	slices.Sort(out)
+	slices.SortFunc(out, types.Date.Compare)
 	return out, nil
 }
@@ -59,7 +59,11 @@ func BuildSearchAvailPayload(p types.SearchAvailPayload) ([]byte, error) {
 		fields = append(fields, fld{0x0A, 0x03, protocol.MakeF64(*v)})
 	}
 	if len(p.Date) > 0 {
-		fields = append(fields, fld{0x0B, 0x01, []byte(strings.Join(p.Date, ","))})
+		dates := make([]string, len(p.Date))
+		for i, d := range p.Date {
+			dates[i] = d.String()
+		}
+		fields = append(fields, fld{0x0B, 0x01, []byte(strings.Join(dates, ","))})
 	}
 	if v := p.Availability; v != nil {
 		fields = append(fields, fld{0x0C, 0x02, []byte{*v}})
@@ -86,7 +90,10 @@ func BuildSearchAvailPayload(p types.SearchAvailPayload) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func ParseSearchAvailResp(codecs *types.Codecs, status string, fields []protocol.Field) ([]types.PropertyAvail, error) {
+// ParseSearchAvailResp decodes a SEARCHAVAIL reply. dates are the dates
+// searched; the packed dates in the reply are resolved against them, or
+// against today's year if none match.
+func ParseSearchAvailResp(codecs *types.Codecs, dates []types.Date, today types.Date, status string, fields []protocol.Field) ([]types.PropertyAvail, error) {
 	if status != "SUCCESS" {
 		if len(fields) > 0 && fields[0].FieldType == 0x01 {
 			return nil, fmt.Errorf("%s", string(fields[0].Data))
@@ -162,13 +169,13 @@ func ParseSearchAvailResp(codecs *types.Codecs, status string, fields []protocol
 			rateFeature := binary.LittleEndian.Uint32(data[dataCursor : dataCursor+4])
 			dataCursor += 4
 
-			dateStr, err := protocol.U16ToDate(datePacked)
+			date, err := types.UnpackDateNear(datePacked, dates, today)
 			if err != nil {
 				return nil, fmt.Errorf("RESPONSE_ERROR: invalid date for property=%q: %w", propID, err)
 			}
 
 			days = append(days, types.DayAvail{
-				Date:         dateStr,
+				Date:         date,
 				Availability: availability,
 				FinalPrice:   finalPrice,
 				RateFeature:  protocol.BitmaskToRateFeatureStrings(codecs, rateFeature),
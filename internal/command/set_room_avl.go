@@ -28,7 +28,7 @@ func BuildSetRoomAvlPayload(p types.UpdRoomAvlPayload) ([]byte, error) {
 	fields := []fld{
 		{0x01, 0x01, []byte(p.PropertyID)},
 		{0x02, 0x01, []byte(p.RoomType)},
-		{0x03, 0x01, []byte(p.Date)},
+		{0x03, 0x01, []byte(p.Date.String())},
 		{0x04, 0x02, []byte{p.Amount}},
 	}
 
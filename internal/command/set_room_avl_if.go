@@ -0,0 +1,66 @@
+package command
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/types"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+)
+
+func BuildSetRoomAvlIfPayload(p types.SetRoomAvlIfPayload) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// command name
+	cmdName := "SETROOMAVLIF"
+	buf.WriteByte(byte(len(cmdName)))
+	buf.WriteString(cmdName)
+
+	// fields
+	type fld struct {
+		id   uint16
+		typ  byte
+		data []byte
+	}
+	fields := []fld{
+		{0x01, 0x01, []byte(p.PropertyID)},
+		{0x02, 0x01, []byte(p.RoomType)},
+		{0x03, 0x01, []byte(p.Date.String())},
+		{0x04, 0x02, []byte{p.Expected}},
+		{0x05, 0x02, []byte{p.Amount}},
+	}
+
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(fields)))
+	for _, f := range fields {
+		idBytes := make([]byte, 2)
+		binary.LittleEndian.PutUint16(idBytes, f.id) // ← Write 2 bytes for ID
+		buf.Write(idBytes)
+		buf.WriteByte(f.typ)
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(len(f.data)))
+		buf.Write(f.data)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseSetRoomAvlIfResp returns the new availability on success. On a
+// CONFLICT the server appends the current availability as a u8 field,
+// which is returned alongside the error so the caller can retry.
+func ParseSetRoomAvlIfResp(status string, fields []protocol.Field) (uint8, error) {
+	if status == "SUCCESS" {
+		if len(fields) == 0 || len(fields[0].Data) != 1 {
+			return 0, errors.New("RESPONSE_ERROR: missing or invalid scalar value")
+		}
+		return fields[0].Data[0], nil
+	}
+	if len(fields) > 0 && fields[0].FieldType == 0x01 {
+		msg := string(fields[0].Data)
+		if strings.HasPrefix(msg, "CONFLICT") && len(fields) > 1 && fields[1].FieldType == 0x02 && len(fields[1].Data) == 1 {
+			return fields[1].Data[0], errors.New(msg)
+		}
+		return 0, errors.New(msg)
+	}
+	return 0, errors.New("RESPONSE_ERROR")
+}
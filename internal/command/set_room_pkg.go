@@ -12,7 +12,7 @@ import (
 )
 
 func BuildSetRoomPkgPayload(p types.SetRoomPkgPayload) ([]byte, error) {
-	if p.PropertyID == "" || p.RoomType == "" || p.Date == "" {
+	if p.PropertyID == "" || p.RoomType == "" || p.Date.IsZero() {
 		return nil, errors.New("missing required fields")
 	}
 
@@ -32,7 +32,7 @@ func BuildSetRoomPkgPayload(p types.SetRoomPkgPayload) ([]byte, error) {
 	fields := []fld{
 		{0x01, 0x01, []byte(p.PropertyID)},
 		{0x02, 0x01, []byte(p.RoomType)},
-		{0x03, 0x01, []byte(p.Date)},
+		{0x03, 0x01, []byte(p.Date.String())},
 	}
 	if p.Availability != nil {
 		fields = append(fields, fld{0x04, 0x02, []byte{byte(*p.Availability)}})
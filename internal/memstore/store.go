@@ -0,0 +1,536 @@
+// Package memstore keeps Roomzin data in memory with the server's
+// semantics as the SDK understands them. It backs the roomzintest fake
+// server and in-memory client; it is not a cache in its own right.
+//
+// Rules mirrored from the server:
+//   - room days can only be written on a property created by SETPROP;
+//   - INCROOMAVL, DECROOMAVL, SETROOMAVLIF and GETPROPROOMDAY need an
+//     existing day, and availability stays within 0..255 (OVERFLOW /
+//     UNDERFLOW otherwise);
+//   - deleting something that does not exist is NOT_FOUND;
+//   - rate features must be known to the codecs.
+//
+// Errors are plain "CODE: message" strings; callers wrap them with
+// types.RzError or send them on the wire as-is.
+package memstore
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+type day struct {
+	avail    uint8
+	price    uint32
+	features uint32 // codec bitmask
+}
+
+type property struct {
+	segment   string
+	area      string
+	propType  string
+	category  string
+	stars     uint8
+	lat, lon  float64
+	amenities []string
+	rooms     map[string]map[types.Date]*day // roomType -> date -> day
+}
+
+type Store struct {
+	mu     sync.RWMutex
+	codecs types.Codecs
+	props  map[string]*property
+}
+
+// New returns an empty store serving codecs.
+func New(codecs types.Codecs) *Store {
+	s := &Store{props: make(map[string]*property)}
+	s.SetCodecs(codecs)
+	return s
+}
+
+// SetCodecs replaces the codecs, versioned by their Digest unless they
+// carry a Version. Stored rate feature masks are kept, so their bits take
+// the meaning of the new codecs, as they would on a real server.
+func (s *Store) SetCodecs(codecs types.Codecs) {
+	codecs = cloneCodecs(&codecs)
+	if codecs.Version == "" {
+		codecs.Version = codecs.Digest()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codecs = codecs
+}
+
+func cloneCodecs(c *types.Codecs) types.Codecs {
+	out := *c
+	out.RateFeatures = slices.Clone(c.RateFeatures)
+	out.Amenities = slices.Clone(c.Amenities)
+	out.PropertyTypes = slices.Clone(c.PropertyTypes)
+	out.Categories = slices.Clone(c.Categories)
+	return out
+}
+
+func (s *Store) Codecs() *types.Codecs {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := cloneCodecs(&s.codecs)
+	return &c
+}
+
+func notFound(format string, args ...any) error {
+	return fmt.Errorf("NOT_FOUND: "+format, args...)
+}
+
+func (s *Store) mask(features []string) (uint32, error) {
+	var m uint32
+	for _, f := range features {
+		i := slices.Index(s.codecs.RateFeatures, f)
+		if i < 0 || i >= types.MaxRateFeatures {
+			return 0, fmt.Errorf("VALIDATION_ERROR: invalid rate feature: %s", f)
+		}
+		m |= 1 << uint(i)
+	}
+	return m, nil
+}
+
+func (s *Store) names(mask uint32) []string {
+	out := make([]string, 0)
+	for i := 0; i < types.MaxRateFeatures && i < len(s.codecs.RateFeatures); i++ {
+		if mask&(1<<uint(i)) != 0 {
+			out = append(out, s.codecs.RateFeatures[i])
+		}
+	}
+	return out
+}
+
+// day returns the stored day or a NOT_FOUND error naming what is missing.
+func (s *Store) day(propertyID, roomType string, date types.Date) (*day, error) {
+	p, ok := s.props[propertyID]
+	if !ok {
+		return nil, notFound("property %s", propertyID)
+	}
+	room, ok := p.rooms[roomType]
+	if !ok {
+		return nil, notFound("room %s of property %s", roomType, propertyID)
+	}
+	d, ok := room[date]
+	if !ok {
+		return nil, notFound("no data for room %s on %s", roomType, date)
+	}
+	return d, nil
+}
+
+// dayOrCreate is day for writers that may create the room and date.
+func (s *Store) dayOrCreate(propertyID, roomType string, date types.Date) (*day, error) {
+	if !date.IsValid() {
+		return nil, errors.New("VALIDATION_ERROR: invalid date")
+	}
+	p, ok := s.props[propertyID]
+	if !ok {
+		return nil, notFound("property %s", propertyID)
+	}
+	room, ok := p.rooms[roomType]
+	if !ok {
+		room = make(map[types.Date]*day)
+		p.rooms[roomType] = room
+	}
+	d, ok := room[date]
+	if !ok {
+		d = &day{}
+		room[date] = d
+	}
+	return d, nil
+}
+
+// SetProp creates or updates a property's attributes, keeping its rooms.
+// Property ids must fit the packed form search replies carry them in.
+func (s *Store) SetProp(p types.SetPropPayload) error {
+	if _, err := protocol.PropertyIDToBytes(p.PropertyID); err != nil {
+		return errors.New("VALIDATION_ERROR: " + err.Error())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prop, ok := s.props[p.PropertyID]
+	if !ok {
+		prop = &property{rooms: make(map[string]map[types.Date]*day)}
+		s.props[p.PropertyID] = prop
+	}
+	prop.segment = p.Segment
+	prop.area = p.Area
+	prop.propType = p.PropertyType
+	prop.category = p.Category
+	prop.stars = p.Stars
+	prop.lat, prop.lon = p.Latitude, p.Longitude
+	prop.amenities = slices.Clone(p.Amenities)
+	return nil
+}
+
+type filter struct {
+	segment   string
+	area      *string
+	propType  *string
+	stars     *uint8
+	category  *string
+	amenities []string
+}
+
+func (f filter) match(p *property) bool {
+	switch {
+	case p.segment != f.segment,
+		f.area != nil && p.area != *f.area,
+		f.propType != nil && p.propType != *f.propType,
+		f.stars != nil && p.stars != *f.stars,
+		f.category != nil && p.category != *f.category:
+		return false
+	}
+	for _, a := range f.amenities {
+		if !slices.Contains(p.amenities, a) {
+			return false
+		}
+	}
+	return true
+}
+
+// order sorts ids by distance from (lat, lon) when both are given and by
+// id otherwise, then applies limit.
+func (s *Store) order(ids []string, lat, lon *float64, limit *uint64) []string {
+	sort.Strings(ids)
+	if lat != nil && lon != nil {
+		dist := func(id string) float64 {
+			p := s.props[id]
+			return math.Hypot(p.lat-*lat, p.lon-*lon)
+		}
+		sort.SliceStable(ids, func(i, j int) bool { return dist(ids[i]) < dist(ids[j]) })
+	}
+	if limit != nil && uint64(len(ids)) > *limit {
+		ids = ids[:*limit]
+	}
+	return ids
+}
+
+func (s *Store) SearchProp(p types.SearchPropPayload) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f := filter{segment: p.Segment, area: p.Area, propType: p.Type, stars: p.Stars, category: p.Category}
+	if p.Amenities != nil {
+		f.amenities = *p.Amenities
+	}
+	ids := make([]string, 0)
+	for id, prop := range s.props {
+		if f.match(prop) {
+			ids = append(ids, id)
+		}
+	}
+	return s.order(ids, p.Latitude, p.Longitude, p.Limit), nil
+}
+
+// SearchAvail returns properties whose room has data on every requested
+// date with at least max(1, Availability) rooms, a price within FinalPrice
+// and all requested rate features. Days come back in request order.
+func (s *Store) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	want, err := s.mask(p.RateFeature)
+	if err != nil {
+		return nil, err
+	}
+	minAvail := uint8(1)
+	if p.Availability != nil && *p.Availability > minAvail {
+		minAvail = *p.Availability
+	}
+	f := filter{segment: p.Segment, area: p.Area, propType: p.Type, stars: p.Stars, category: p.Category, amenities: p.Amenities}
+
+	ids := make([]string, 0)
+	for id, prop := range s.props {
+		if p.PropertyID != nil && id != *p.PropertyID {
+			continue
+		}
+		if !f.match(prop) {
+			continue
+		}
+		room, ok := prop.rooms[p.RoomType]
+		if !ok {
+			continue
+		}
+		match := true
+		for _, date := range p.Date {
+			d, ok := room[date]
+			if !ok || d.avail < minAvail || d.features&want != want ||
+				(p.FinalPrice != nil && d.price > *p.FinalPrice) {
+				match = false
+				break
+			}
+		}
+		if match {
+			ids = append(ids, id)
+		}
+	}
+
+	ids = s.order(ids, p.Latitude, p.Longitude, p.Limit)
+	out := make([]types.PropertyAvail, 0, len(ids))
+	for _, id := range ids {
+		room := s.props[id].rooms[p.RoomType]
+		days := make([]types.DayAvail, 0, len(p.Date))
+		for _, date := range p.Date {
+			d := room[date]
+			days = append(days, types.DayAvail{
+				Date:         date,
+				Availability: d.avail,
+				FinalPrice:   d.price,
+				RateFeature:  s.names(d.features),
+			})
+		}
+		out = append(out, types.PropertyAvail{PropertyID: id, Days: days})
+	}
+	return out, nil
+}
+
+func (s *Store) SetRoomPkg(p types.SetRoomPkgPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	features, err := s.mask(p.RateFeature)
+	if err != nil {
+		return err
+	}
+	d, err := s.dayOrCreate(p.PropertyID, p.RoomType, p.Date)
+	if err != nil {
+		return err
+	}
+	if p.Availability != nil {
+		d.avail = *p.Availability
+	}
+	if p.FinalPrice != nil {
+		d.price = *p.FinalPrice
+	}
+	if p.RateFeature != nil {
+		d.features = features
+	}
+	return nil
+}
+
+func (s *Store) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.dayOrCreate(p.PropertyID, p.RoomType, p.Date)
+	if err != nil {
+		return 0, err
+	}
+	d.avail = p.Amount
+	return d.avail, nil
+}
+
+// ErrConflict prefixes a lost SetRoomAvlIf; the current availability is
+// returned alongside it.
+var ErrConflict = errors.New("CONFLICT: availability changed")
+
+func (s *Store) SetRoomAvlIf(p types.SetRoomAvlIfPayload) (uint8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.day(p.PropertyID, p.RoomType, p.Date)
+	if err != nil {
+		return 0, err
+	}
+	if d.avail != p.Expected {
+		return d.avail, ErrConflict
+	}
+	d.avail = p.Amount
+	return d.avail, nil
+}
+
+func (s *Store) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.day(p.PropertyID, p.RoomType, p.Date)
+	if err != nil {
+		return 0, err
+	}
+	if int(d.avail)+int(p.Amount) > math.MaxUint8 {
+		return 0, fmt.Errorf("OVERFLOW: availability %d + %d exceeds %d", d.avail, p.Amount, math.MaxUint8)
+	}
+	d.avail += p.Amount
+	return d.avail, nil
+}
+
+func (s *Store) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, err := s.day(p.PropertyID, p.RoomType, p.Date)
+	if err != nil {
+		return 0, err
+	}
+	if p.Amount > d.avail {
+		return 0, fmt.Errorf("UNDERFLOW: availability %d - %d is below 0", d.avail, p.Amount)
+	}
+	d.avail -= p.Amount
+	return d.avail, nil
+}
+
+func (s *Store) PropExist(propertyID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.props[propertyID]
+	return ok, nil
+}
+
+func (s *Store) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prop, ok := s.props[p.PropertyID]
+	if !ok {
+		return false, nil
+	}
+	_, ok = prop.rooms[p.RoomType]
+	return ok, nil
+}
+
+func (s *Store) PropRoomList(propertyID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prop, ok := s.props[propertyID]
+	if !ok {
+		return nil, notFound("property %s", propertyID)
+	}
+	out := make([]string, 0, len(prop.rooms))
+	for rt := range prop.rooms {
+		out = append(out, rt)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (s *Store) PropRoomDateList(p types.PropRoomDateListPayload) ([]types.Date, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	prop, ok := s.props[p.PropertyID]
+	if !ok {
+		return nil, notFound("property %s", p.PropertyID)
+	}
+	room, ok := prop.rooms[p.RoomType]
+	if !ok {
+		return nil, notFound("room %s of property %s", p.RoomType, p.PropertyID)
+	}
+	out := make([]types.Date, 0, len(room))
+	for date := range room {
+		out = append(out, date)
+	}
+	slices.SortFunc(out, types.Date.Compare)
+	return out, nil
+}
+
+func (s *Store) DelProp(propertyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.props[propertyID]; !ok {
+		return notFound("property %s", propertyID)
+	}
+	delete(s.props, propertyID)
+	return nil
+}
+
+func (s *Store) DelSegment(segment string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for id, p := range s.props {
+		if p.segment == segment {
+			delete(s.props, id)
+			found = true
+		}
+	}
+	if !found {
+		return notFound("segment %s", segment)
+	}
+	return nil
+}
+
+// DelPropDay removes the date from every room of the property.
+func (s *Store) DelPropDay(p types.DelPropDayRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prop, ok := s.props[p.PropertyID]
+	if !ok {
+		return notFound("property %s", p.PropertyID)
+	}
+	found := false
+	for _, room := range prop.rooms {
+		if _, ok := room[p.Date]; ok {
+			delete(room, p.Date)
+			found = true
+		}
+	}
+	if !found {
+		return notFound("no data for property %s on %s", p.PropertyID, p.Date)
+	}
+	return nil
+}
+
+func (s *Store) DelPropRoom(p types.DelPropRoomPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prop, ok := s.props[p.PropertyID]
+	if !ok {
+		return notFound("property %s", p.PropertyID)
+	}
+	if _, ok := prop.rooms[p.RoomType]; !ok {
+		return notFound("room %s of property %s", p.RoomType, p.PropertyID)
+	}
+	delete(prop.rooms, p.RoomType)
+	return nil
+}
+
+func (s *Store) DelRoomDay(p types.DelRoomDayRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.day(p.PropertyID, p.RoomType, p.Date); err != nil {
+		return err
+	}
+	delete(s.props[p.PropertyID].rooms[p.RoomType], p.Date)
+	return nil
+}
+
+func (s *Store) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	d, err := s.day(p.PropertyID, p.RoomType, p.Date)
+	if err != nil {
+		return types.GetRoomDayResult{}, err
+	}
+	return types.GetRoomDayResult{
+		PropertyID:   p.PropertyID,
+		Date:         p.Date,
+		Availability: d.avail,
+		FinalPrice:   d.price,
+		RateFeature:  s.names(d.features),
+	}, nil
+}
+
+// GetSegments lists segments with their property counts, by name.
+func (s *Store) GetSegments() ([]types.SegmentInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]uint32)
+	for _, p := range s.props {
+		counts[p.segment]++
+	}
+	out := make([]types.SegmentInfo, 0, len(counts))
+	for seg, n := range counts {
+		out = append(out, types.SegmentInfo{Segment: seg, PropCount: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Segment < out[j].Segment })
+	return out, nil
+}
+
+// Mask encodes rate features with the store's codecs.
+func (s *Store) Mask(features []string) (uint32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mask(features)
+}
@@ -109,3 +109,25 @@ func ParseFields(data []byte, fieldCount uint16) ([]Field, error) {
 
 	return fields, nil
 }
+
+// EncodePayload serialises a status (or command name) and its fields into
+// the payload layout read by DrainFrame and ParseFields:
+// | statusLen(1) | status | fieldCount(2) | fields... |
+// Each field is | id(2) | type(1) | len(4) | data |.
+func EncodePayload(status string, fields []Field) []byte {
+	size := 1 + len(status) + 2
+	for _, f := range fields {
+		size += 7 + len(f.Data)
+	}
+	out := make([]byte, 0, size)
+	out = append(out, byte(len(status)))
+	out = append(out, status...)
+	out = binary.LittleEndian.AppendUint16(out, uint16(len(fields)))
+	for _, f := range fields {
+		out = binary.LittleEndian.AppendUint16(out, f.ID)
+		out = append(out, f.FieldType)
+		out = binary.LittleEndian.AppendUint32(out, uint32(len(f.Data)))
+		out = append(out, f.Data...)
+	}
+	return out
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/binary"
 	"errors"
 	"math"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/roomzin/roomzin-go/types"
@@ -16,8 +15,8 @@ func BitmaskToRateFeatureStrings(codecs *types.Codecs, mask uint32) []string {
 		return []string{}
 	}
 
-	out := make([]string, 0, 24) // pre-allocate up to 24
-	for i := 0; i < 24 && i < len(codecs.RateFeatures); i++ {
+	out := make([]string, 0, types.MaxRateFeatures)
+	for i := 0; i < types.MaxRateFeatures && i < len(codecs.RateFeatures); i++ {
 		if mask&(1<<uint(i)) != 0 {
 			out = append(out, codecs.RateFeatures[i])
 		}
@@ -25,21 +24,6 @@ func BitmaskToRateFeatureStrings(codecs *types.Codecs, mask uint32) []string {
 	return out
 }
 
-// u16ToDate unpacks the 16-bit packed date (same bit layout as Rust)
-func U16ToDate(packed uint16) (string, error) {
-	yearOffset := int((packed >> 9) & 0b111)
-	month := int((packed>>5)&0b1111) + 1
-	day := int(packed&0b11111) + 1
-
-	// use current year as base (like Rust)
-	baseYear := time.Now().Year()
-	t := time.Date(baseYear+yearOffset, time.Month(month), day, 0, 0, 0, 0, time.UTC)
-	if t.Month() != time.Month(month) || t.Day() != day {
-		return "", errors.New("invalid packed date")
-	}
-	return t.Format("2006-01-02"), nil
-}
-
 func MakeF64(v float64) []byte {
 	b := make([]byte, 8)
 	binary.LittleEndian.PutUint64(b, math.Float64bits(v))
@@ -57,6 +41,27 @@ func MakeU32(v uint32) []byte {
 	return b
 }
 
+// PropertyIDToBytes is the inverse of BytesToPropertyID: UUIDs travel as
+// their 16 raw bytes, anything else as a short string split around the
+// 0xF0 marker at index 6. Short strings hold at most 15 bytes.
+func PropertyIDToBytes(id string) ([]byte, error) {
+	if u, err := uuid.Parse(id); err == nil {
+		switch u.Version() {
+		case 1, 2, 3, 4, 5, 7:
+			return u[:], nil
+		}
+	}
+	if len(id) > 15 {
+		return nil, errors.New("property id must be a UUID or at most 15 bytes")
+	}
+	out := make([]byte, 16)
+	left := min(len(id), 6)
+	copy(out[:left], id[:left])
+	out[6] = 0xF0
+	copy(out[7:], id[left:])
+	return out, nil
+}
+
 func BytesToPropertyID(data []byte) string {
 	// 1. Too short → return empty
 	if len(data) < 7 {
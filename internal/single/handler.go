@@ -2,15 +2,13 @@ package single
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"crypto/tls"
 	"net"
 	"strconv"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/internal/transport"
 )
 
 type Config struct {
@@ -19,202 +17,54 @@ type Config struct {
 	AuthToken string
 	Timeout   time.Duration
 	KeepAlive time.Duration
+	TLSConfig *tls.Config // nil means plaintext
 }
 
+// Handler talks to a single node: a one-node topology whose Node
+// reconnects with backoff when the connection drops.
 type Handler struct {
-	config *Config
-	conn   net.Conn
-	next   uint32
-
-	mu          sync.Mutex
-	closed      bool
-	demux       map[uint32]chan protocol.RawResult
-	ctx         context.Context
-	OnReconnect func()
+	node      *transport.Node
+	transport *transport.Transport
 }
 
+// NewHandler connects to the node, failing if the first dial or login
+// does.
 func NewHandler(cfg *Config, ctx context.Context) (*Handler, error) {
-	c := &Handler{
-		config: cfg,
-		demux:  make(map[uint32]chan protocol.RawResult),
-		ctx:    ctx,
-	}
-	if err := c.reconnect(); err != nil { // first dial
-		return nil, err
-	}
-	return c, nil
-}
-
-func (c *Handler) reconnect() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.conn != nil {
-		_ = c.conn.Close()
-	}
-	host := ParseHost(c.config.Addr)
-	addr := net.JoinHostPort(host, strconv.Itoa(int(c.config.TCPPort)))
-	conn, err := dial(addr, c.config.AuthToken, c.config.Timeout, c.config.KeepAlive)
-	if err != nil {
-		return err
-	}
-	c.conn = conn
-	// ----  start reader exactly here ----
-	go c.readLoop()
-	return nil
-}
-
-func dial(addr string, token string, timeout, keepAlive time.Duration) (*net.TCPConn, error) {
-	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	addr := net.JoinHostPort(ParseHost(cfg.Addr), strconv.Itoa(int(cfg.TCPPort)))
+	opts := transport.Options{
+		AuthToken: cfg.AuthToken,
+		Timeout:   cfg.Timeout,
+		KeepAlive: cfg.KeepAlive,
+		TLSConfig: cfg.TLSConfig,
+	}
+	conn, err := transport.Dial(ctx, addr, opts)
 	if err != nil {
 		return nil, err
 	}
-	dialer := &net.Dialer{Timeout: timeout, KeepAlive: keepAlive}
-	conn, err := dialer.Dial("tcp", tcpAddr.String())
-	if err != nil {
-		return nil, err
-	}
-	tcpConn, ok := conn.(*net.TCPConn)
-	if !ok {
-		conn.Close()
-		return nil, fmt.Errorf("unexpected connection type for %s", addr)
-	}
-
-	// check authentication
-	if err := handshake(tcpConn, token, timeout); err != nil {
-		return nil, fmt.Errorf("%v, failed to handshake to %s", err, addr)
-	}
-
-	tcpConn.SetKeepAlive(true)
-	tcpConn.SetKeepAlivePeriod(keepAlive)
-	return tcpConn, nil
+	node := transport.NewNode(ctx, transport.Static(addr), conn, opts)
+	return &Handler{node: node, transport: transport.New(node)}, nil
 }
 
-func handshake(conn *net.TCPConn, token string, timeout time.Duration) error {
-	_ = conn.SetDeadline(time.Now().Add(timeout))
-	defer conn.SetDeadline(time.Time{})
-
-	// 1. send framed login
-	payload, _ := protocol.BuildLoginPayload(token)
-	frame := protocol.PrependHeader(0, payload)
-	if _, err := conn.Write(frame); err != nil {
-		return err
-	}
-
-	// 2. read plain-text reply
-	buf := make([]byte, 32) // 12/13 bytes is enough
-	n, err := conn.Read(buf)
-	if err != nil {
-		return err
-	}
-	switch string(buf[:n]) {
-	case "LOGIN OK":
-		return nil
-	case "LOGIN FAILED":
-		return errors.New("AUTH_ERROR: invalid token")
-	default:
-		return fmt.Errorf("RESPONSE_ERROR: unexpected login reply %q", buf[:n])
-	}
+// SetOnReconnectCallback sets a callback run each time the connection is
+// lost, before redialling.
+func (c *Handler) SetOnReconnectCallback(callback func()) {
+	c.node.SetOnLost(callback)
 }
 
+// Close fails pending calls, closes the connection and stops reconnecting.
 func (c *Handler) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.closed {
-		return nil
-	}
-	c.closed = true
-	_ = c.conn.Close()
-	for _, ch := range c.demux {
-		close(ch)
-	}
-
-	return nil
-}
-
-func (c *Handler) NextID() uint32 { return atomic.AddUint32(&c.next, 1) }
-
-func (c *Handler) RoundTrip(clrid uint32, payload []byte) (protocol.RawResult, error) {
-	c.mu.Lock()
-	if c.closed {
-		c.mu.Unlock()
-		return protocol.RawResult{}, protocol.ErrConnClosed
-	}
-	// ---  added self-heal  ---
-	if c.conn == nil {
-		c.mu.Unlock()
-		if err := c.reconnect(); err != nil {
-			return protocol.RawResult{}, err
-		}
-		c.mu.Lock()
-	}
-	// ---  end self-heal  ---
-
-	ch := make(chan protocol.RawResult, 1)
-	c.demux[clrid] = ch
-	c.mu.Unlock()
-
-	if _, err := c.conn.Write(protocol.PrependHeader(clrid, payload)); err != nil {
-		c.cleanup(clrid)
-		_ = c.reconnect() // mark bad, retry next call
-		return protocol.RawResult{}, err
-	}
-
-	select {
-	case res := <-ch:
-		return res, nil
-	case <-time.After(c.config.Timeout):
-		c.cleanup(clrid)
-		_ = c.reconnect()
-		return protocol.RawResult{}, protocol.ErrTimeout
-	}
+	return c.node.Close()
 }
 
-func (c *Handler) cleanup(clrid uint32) {
-	c.mu.Lock()
-	delete(c.demux, clrid)
-	c.mu.Unlock()
+// RoundTrip sends one frame and waits for its reply until ctx is done.
+func (c *Handler) RoundTrip(ctx context.Context, payload []byte) (protocol.RawResult, error) {
+	return c.transport.RoundTrip(ctx, false, payload)
 }
 
-func (c *Handler) readLoop() {
-	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		default:
-		}
-		hdr, payload, err := protocol.DrainFrame(c.conn)
-		if err != nil {
-			c.failAll(err)
-			// Connection lost - invalidate codecs
-			if c.OnReconnect != nil {
-				c.OnReconnect() // This sets codecs = nil
-			}
-			return
-		}
-		fields, _ := protocol.ParseFields(payload[1+len(hdr.Status)+2:], hdr.FieldCnt)
-
-		c.mu.Lock()
-		ch, ok := c.demux[hdr.ClrID]
-		delete(c.demux, hdr.ClrID)
-		c.mu.Unlock()
-
-		if ok {
-			ch <- protocol.RawResult{Status: hdr.Status, Fields: fields}
-			close(ch)
-		}
-	}
-}
-
-func (c *Handler) failAll(err error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	for _, ch := range c.demux {
-		ch <- protocol.RawResult{}
-		close(ch)
-	}
-	for k := range c.demux {
-		delete(c.demux, k)
-	}
+// RoundTripBatch pipelines payloads on the connection and returns one
+// result or error per payload.
+func (c *Handler) RoundTripBatch(ctx context.Context, payloads [][]byte) ([]protocol.RawResult, []error) {
+	return c.transport.RoundTripBatch(ctx, true, payloads)
 }
 
 func ParseHost(addr string) string {
@@ -0,0 +1,389 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+)
+
+// Options configure the connections to every node.
+type Options struct {
+	AuthToken string
+	Timeout   time.Duration // bounds dialling, login and each write
+	KeepAlive time.Duration
+	TLSConfig *tls.Config // nil means plaintext
+
+	// Drop, when set, sees every reply. Returning true closes the
+	// connection once the reply is delivered, for replies saying the node
+	// no longer serves what this connection was opened for.
+	Drop func(res protocol.RawResult) bool
+}
+
+// maxWriteBytes caps how many queued bytes the write loop coalesces into
+// one write.
+const maxWriteBytes = 256 << 10
+
+// batchWriteFrames caps how many frames RoundTripBatch queues at once.
+const batchWriteFrames = 512
+
+// Conn is one authenticated connection. Its write loop is the only writer
+// and its read loop the only reader of the socket; pending maps the clrIDs
+// in flight on this connection, and no other, to their callers. When the
+// connection dies every pending call fails with protocol.ErrConnClosed; a
+// call that times out only gives up its own reply.
+type Conn struct {
+	nc     net.Conn
+	addr   string
+	opts   Options
+	writes chan []byte
+	next   atomic.Uint32
+
+	mu      sync.Mutex
+	pending map[uint32]chan result
+	err     error         // why the connection died, set before done closes
+	done    chan struct{} // closed by fail
+}
+
+type result struct {
+	res protocol.RawResult
+	err error
+}
+
+// Dial connects to addr, logs in and starts the connection's loops.
+func Dial(ctx context.Context, addr string, opts Options) (*Conn, error) {
+	dialer := &net.Dialer{Timeout: opts.Timeout, KeepAlive: opts.KeepAlive}
+	var (
+		nc  net.Conn
+		err error
+	)
+	if opts.TLSConfig != nil {
+		// tls.Dialer completes the TLS handshake (and any client
+		// certificate exchange) before we send LOGIN.
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: opts.TLSConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := login(nc, opts.AuthToken, opts.Timeout); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("%v, failed to handshake to %s", err, addr)
+	}
+
+	c := &Conn{
+		nc:      nc,
+		addr:    addr,
+		opts:    opts,
+		writes:  make(chan []byte, 1024),
+		pending: make(map[uint32]chan result),
+		done:    make(chan struct{}),
+	}
+	go c.writeLoop()
+	go c.readLoop()
+	return c, nil
+}
+
+// Login replies are plain text, not frames.
+const (
+	loginOK     = "LOGIN OK"
+	loginFailed = "LOGIN FAILED"
+)
+
+// login sends a framed LOGIN and reads the reply. It reads no further
+// than the longest reply still possible, however the bytes are split
+// across reads, so nothing after the reply is consumed.
+func login(conn net.Conn, token string, timeout time.Duration) error {
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	payload, _ := protocol.BuildLoginPayload(token)
+	if _, err := conn.Write(protocol.PrependHeader(0, payload)); err != nil {
+		return err
+	}
+
+	var got []byte
+	for {
+		switch string(got) {
+		case loginOK:
+			return nil
+		case loginFailed:
+			return errors.New("AUTH_ERROR: invalid token")
+		}
+		want := 0
+		for _, reply := range []string{loginOK, loginFailed} {
+			if strings.HasPrefix(reply, string(got)) {
+				want = max(want, len(reply)-len(got))
+			}
+		}
+		if want == 0 {
+			return fmt.Errorf("RESPONSE_ERROR: unexpected login reply %q", got)
+		}
+		buf := make([]byte, want)
+		n, err := conn.Read(buf)
+		got = append(got, buf[:n]...)
+		if err == io.EOF && n > 0 {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Addr returns the host:port the connection was dialled to.
+func (c *Conn) Addr() string { return c.addr }
+
+// Done is closed once the connection is dead.
+func (c *Conn) Done() <-chan struct{} { return c.done }
+
+// Alive reports whether the connection is still usable.
+func (c *Conn) Alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close fails pending calls with protocol.ErrConnClosed and closes the
+// socket.
+func (c *Conn) Close() error {
+	c.fail(protocol.ErrConnClosed)
+	return nil
+}
+
+// RoundTrip sends one frame and waits for its reply until ctx is done.
+// On cancellation the pending demux entry is dropped so a late reply is
+// discarded by the read loop instead of lingering in the map.
+func (c *Conn) RoundTrip(ctx context.Context, payload []byte) (protocol.RawResult, error) {
+	clrid := c.next.Add(1)
+	chans, err := c.register([]uint32{clrid})
+	if err != nil {
+		return protocol.RawResult{}, err
+	}
+	if err := c.send(ctx, protocol.PrependHeader(clrid, payload)); err != nil {
+		c.unregister(clrid)
+		return protocol.RawResult{}, err
+	}
+
+	select {
+	case r := <-chans[0]:
+		return r.res, r.err
+	case <-ctx.Done():
+		c.unregister(clrid)
+		return protocol.RawResult{}, ContextErr(ctx)
+	}
+}
+
+// RoundTripBatch pipelines payloads: each frame gets its own clrID and
+// demux slot, frames are queued back-to-back, and the replies are
+// collected in order. It returns one result or error per payload.
+func (c *Conn) RoundTripBatch(ctx context.Context, payloads [][]byte) ([]protocol.RawResult, []error) {
+	results := make([]protocol.RawResult, len(payloads))
+	errs := make([]error, len(payloads))
+	failFrom := func(i int, err error) {
+		for ; i < len(payloads); i++ {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+	}
+
+	ids := make([]uint32, len(payloads))
+	for i := range payloads {
+		ids[i] = c.next.Add(1)
+	}
+	chans, err := c.register(ids)
+	if err != nil {
+		failFrom(0, err)
+		return results, errs
+	}
+
+	for start := 0; start < len(payloads); start += batchWriteFrames {
+		end := min(start+batchWriteFrames, len(payloads))
+		var buf []byte
+		for i := start; i < end; i++ {
+			buf = append(buf, protocol.PrependHeader(ids[i], payloads[i])...)
+		}
+		if err := c.send(ctx, buf); err != nil {
+			// Frames already queued may still be answered; only the
+			// unsent ones are given up here.
+			c.unregister(ids[start:]...)
+			failFrom(start, err)
+			chans = chans[:start]
+			break
+		}
+	}
+
+	for i, ch := range chans {
+		select {
+		case r := <-ch:
+			results[i], errs[i] = r.res, r.err
+		case <-ctx.Done():
+			c.unregister(ids[i:len(chans)]...)
+			failFrom(i, ContextErr(ctx))
+			return results, errs
+		}
+	}
+	return results, errs
+}
+
+// register parks a reply slot for each clrID. It fails, registering
+// nothing, once the connection is dead.
+func (c *Conn) register(ids []uint32) ([]chan result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.closedErr()
+	}
+	chans := make([]chan result, len(ids))
+	for i, id := range ids {
+		chans[i] = make(chan result, 1)
+		c.pending[id] = chans[i]
+	}
+	return chans, nil
+}
+
+func (c *Conn) unregister(ids ...uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, id := range ids {
+		delete(c.pending, id)
+	}
+}
+
+// send queues frames for the write loop. A failed write is reported to
+// the registered callers by fail, not here.
+func (c *Conn) send(ctx context.Context, frames []byte) error {
+	select {
+	case c.writes <- frames:
+		return nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.closedErr()
+	case <-ctx.Done():
+		return ContextErr(ctx)
+	}
+}
+
+func (c *Conn) writeLoop() {
+	var buf []byte
+	for {
+		select {
+		case <-c.done:
+			return
+		case frames := <-c.writes:
+			buf = append(buf[:0], frames...)
+		drain:
+			for len(buf) < maxWriteBytes {
+				select {
+				case more := <-c.writes:
+					buf = append(buf, more...)
+				default:
+					break drain
+				}
+			}
+			if c.opts.Timeout > 0 {
+				_ = c.nc.SetWriteDeadline(time.Now().Add(c.opts.Timeout))
+			}
+			if _, err := c.nc.Write(buf); err != nil {
+				c.fail(err)
+				return
+			}
+			if cap(buf) > maxWriteBytes {
+				buf = nil
+			}
+		}
+	}
+}
+
+func (c *Conn) readLoop() {
+	for {
+		hdr, payload, err := protocol.DrainFrame(c.nc)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		fields, err := protocol.ParseFields(payload[1+len(hdr.Status)+2:], hdr.FieldCnt)
+
+		c.mu.Lock()
+		ch, ok := c.pending[hdr.ClrID]
+		delete(c.pending, hdr.ClrID)
+		c.mu.Unlock()
+
+		if err != nil {
+			if ok {
+				ch <- result{err: fmt.Errorf("RESPONSE_ERROR: %v", err)}
+			}
+			continue
+		}
+		res := protocol.RawResult{Status: hdr.Status, Fields: fields}
+		if ok {
+			ch <- result{res: res}
+		}
+		if c.opts.Drop != nil && c.opts.Drop(res) {
+			c.fail(fmt.Errorf("node replied %s %s", res.Status, errorCode(res)))
+			return
+		}
+	}
+}
+
+// errorCode returns the code of an ERROR reply.
+func errorCode(res protocol.RawResult) string {
+	if len(res.Fields) == 0 {
+		return ""
+	}
+	return string(res.Fields[0].Data)
+}
+
+// fail marks the connection dead for cause, fails every pending call and
+// closes the socket, which stops both loops. Only the first call counts.
+func (c *Conn) fail(cause error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.err = cause
+	err := c.closedErr()
+	for id, ch := range c.pending {
+		ch <- result{err: err}
+		delete(c.pending, id)
+	}
+	close(c.done)
+	c.mu.Unlock()
+	_ = c.nc.Close()
+}
+
+// closedErr wraps the cause of death in protocol.ErrConnClosed. c.mu must
+// be held.
+func (c *Conn) closedErr() error {
+	if c.err == protocol.ErrConnClosed {
+		return c.err
+	}
+	return fmt.Errorf("%w: %v", protocol.ErrConnClosed, c.err)
+}
+
+// ContextErr returns why ctx is done, reporting a ctx that ran out of
+// time as protocol.ErrTimeout. The result matches ctx.Err() with
+// errors.Is either way.
+func ContextErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", protocol.ErrTimeout, ctx.Err())
+	}
+	return ctx.Err()
+}
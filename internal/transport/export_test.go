@@ -0,0 +1,8 @@
+package transport
+
+// Pending returns how many calls wait for a reply on c.
+func (c *Conn) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
@@ -0,0 +1,209 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+)
+
+// Reconnect backoff: the first redial waits minBackoff, each failure
+// doubles the wait up to maxBackoff, and every wait gets up to a quarter
+// of jitter so clients of a restarted server do not redial in lockstep.
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+type nodeState uint8
+
+const (
+	stateConnected    nodeState = iota // conn is live
+	stateReconnecting                  // no conn; redialling with backoff
+	stateClosed                        // Close was called or ctx ended
+)
+
+// Node keeps one connection to a node open. When the connection drops, one
+// supervisor goroutine redials with backoff, asking resolve for the
+// address each time so a role such as leader can move between hosts;
+// calls made meanwhile wait for it, up to their ctx. A Node routes every
+// request to its connection, which makes it the Router of a one-node
+// topology.
+type Node struct {
+	opts    Options
+	resolve func(ctx context.Context) (string, error)
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{} // closed when the supervisor exits
+
+	mu      sync.Mutex
+	state   nodeState
+	conn    *Conn         // nil unless state is stateConnected
+	ready   chan struct{} // closed when state leaves stateReconnecting
+	lastErr error         // why the last redial failed
+	onLost  func()
+}
+
+// NewNode starts supervising first, or dialling right away when first is
+// nil, until ctx ends or Close is called.
+func NewNode(ctx context.Context, resolve func(ctx context.Context) (string, error), first *Conn, opts Options) *Node {
+	ctx, cancel := context.WithCancel(ctx)
+	n := &Node{
+		opts:    opts,
+		resolve: resolve,
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+		state:   stateReconnecting,
+		ready:   make(chan struct{}),
+	}
+	if first != nil {
+		n.state, n.conn = stateConnected, first
+	}
+	go n.supervise(first)
+	return n
+}
+
+// Static returns a resolve func that always names addr.
+func Static(addr string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) { return addr, nil }
+}
+
+// SetOnLost sets a callback run each time the connection is lost, before
+// redialling.
+func (n *Node) SetOnLost(fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.onLost = fn
+}
+
+// supervise watches the live connection and replaces it when it dies,
+// until the node closes. It is the only goroutine that dials.
+func (n *Node) supervise(cur *Conn) {
+	defer close(n.stopped)
+	for {
+		if cur != nil {
+			select {
+			case <-n.ctx.Done():
+				cur.Close()
+				n.setClosed()
+				return
+			case <-cur.Done():
+			}
+			n.mu.Lock()
+			n.lostLocked(cur)
+			onLost := n.onLost
+			n.mu.Unlock()
+			if onLost != nil {
+				onLost()
+			}
+		}
+		if cur = n.redial(); cur == nil {
+			n.setClosed()
+			return
+		}
+	}
+}
+
+// redial dials until it succeeds, backing off between failures, and makes
+// the new connection current. It returns nil once the node closes.
+func (n *Node) redial() *Conn {
+	backoff := minBackoff
+	for {
+		addr, err := n.resolve(n.ctx)
+		if err == nil {
+			var c *Conn
+			if c, err = Dial(n.ctx, addr, n.opts); err == nil {
+				n.mu.Lock()
+				n.state, n.conn, n.lastErr = stateConnected, c, nil
+				close(n.ready)
+				n.mu.Unlock()
+				return c
+			}
+		}
+		n.mu.Lock()
+		n.lastErr = err
+		n.mu.Unlock()
+
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff/4)+1))
+		select {
+		case <-n.ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// lostLocked moves the node to stateReconnecting if c is still current.
+// n.mu must be held.
+func (n *Node) lostLocked(c *Conn) {
+	if n.conn == c {
+		n.state, n.conn, n.ready = stateReconnecting, nil, make(chan struct{})
+	}
+}
+
+func (n *Node) setClosed() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.state == stateReconnecting {
+		close(n.ready)
+	}
+	n.state, n.conn = stateClosed, nil
+}
+
+// Conn returns the live connection, waiting while the node reconnects
+// until ctx is done.
+func (n *Node) Conn(ctx context.Context) (*Conn, error) {
+	for {
+		n.mu.Lock()
+		if n.state == stateConnected && !n.conn.Alive() {
+			// dead, but the supervisor has not noticed yet; wait for
+			// the redial rather than fail on it again
+			n.lostLocked(n.conn)
+		}
+		state, c, ready, lastErr := n.state, n.conn, n.ready, n.lastErr
+		n.mu.Unlock()
+
+		switch state {
+		case stateConnected:
+			return c, nil
+		case stateClosed:
+			return nil, protocol.ErrConnClosed
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w while reconnecting: %v", ContextErr(ctx), lastErr)
+			}
+			return nil, fmt.Errorf("%w while reconnecting", ContextErr(ctx))
+		}
+	}
+}
+
+// Ready returns the live connection, or nil without waiting.
+func (n *Node) Ready() *Conn {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.state == stateConnected && n.conn.Alive() {
+		return n.conn
+	}
+	return nil
+}
+
+// Route implements Router: every request goes to the node.
+func (n *Node) Route(ctx context.Context, _ bool) (*Conn, error) {
+	return n.Conn(ctx)
+}
+
+// Close fails pending calls with protocol.ErrConnClosed, closes the
+// connection and stops reconnecting. Later calls fail the same way.
+func (n *Node) Close() error {
+	n.cancel()
+	<-n.stopped
+	return nil
+}
@@ -0,0 +1,53 @@
+// Package transport carries frames between a client and its nodes: the
+// connection with its login handshake, write loop and demux, reconnecting
+// with backoff, and a Router choosing the node of each request. Single
+// mode is a one-node topology routed by a Node; cluster mode routes writes
+// to the leader and reads to followers.
+package transport
+
+import (
+	"context"
+
+	"github.com/roomzin/roomzin-go/internal/protocol"
+)
+
+// Router decides which node a request goes to.
+type Router interface {
+	// Route returns the connection to send a request on, waiting up to
+	// ctx while none is ready. write reports whether the request changes
+	// data.
+	Route(ctx context.Context, write bool) (*Conn, error)
+}
+
+// Transport sends requests over the connections a Router picks.
+type Transport struct {
+	router Router
+}
+
+func New(router Router) *Transport {
+	return &Transport{router: router}
+}
+
+// RoundTrip sends payload to the node chosen for it and waits for the
+// reply until ctx is done.
+func (t *Transport) RoundTrip(ctx context.Context, write bool, payload []byte) (protocol.RawResult, error) {
+	conn, err := t.router.Route(ctx, write)
+	if err != nil {
+		return protocol.RawResult{}, err
+	}
+	return conn.RoundTrip(ctx, payload)
+}
+
+// RoundTripBatch pipelines payloads on one connection chosen for them; see
+// Conn.RoundTripBatch.
+func (t *Transport) RoundTripBatch(ctx context.Context, write bool, payloads [][]byte) ([]protocol.RawResult, []error) {
+	conn, err := t.router.Route(ctx, write)
+	if err != nil {
+		errs := make([]error, len(payloads))
+		for i := range errs {
+			errs[i] = err
+		}
+		return make([]protocol.RawResult, len(payloads)), errs
+	}
+	return conn.RoundTripBatch(ctx, payloads)
+}
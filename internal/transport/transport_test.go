@@ -0,0 +1,256 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/internal/command"
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/internal/transport"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/single"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+const props = 8
+
+// seed gives property p<i> the single room type r<i>, so a reply to
+// PROPROOMLIST shows which request it answers.
+func seed(t *testing.T, srv *roomzintest.Server) {
+	t.Helper()
+	cfg := srv.SingleConfig()
+	c, err := single.New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	avl := uint8(1)
+	for i := range props {
+		id := fmt.Sprintf("p%d", i)
+		if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: id, PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+			t.Fatal(err)
+		}
+		p := types.SetRoomPkgPayload{PropertyID: id, RoomType: fmt.Sprintf("r%d", i), Date: types.Today(nil, nil).AddDays(1), Availability: &avl}
+		if err := c.SetRoomPkg(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// check reports whether the call for p<i> was served. A call failed by
+// the connection dropping under it is not served but is no error; any
+// other error, or a reply meant for another call, is.
+func check(i int, res protocol.RawResult, err error) (ok bool, _ error) {
+	if err != nil {
+		if errors.Is(err, protocol.ErrConnClosed) {
+			return false, nil
+		}
+		return false, err
+	}
+	rooms, err := command.ParsePropRoomListResp(res.Status, res.Fields)
+	if err != nil {
+		return false, err
+	}
+	if want := []string{fmt.Sprintf("r%d", i)}; !slices.Equal(rooms, want) {
+		return false, fmt.Errorf("p%d got the reply %v, want %v", i, rooms, want)
+	}
+	return true, nil
+}
+
+// TestNodeReconnectUnderLoad drops every connection over and over while
+// many goroutines send single and pipelined requests. Run it with -race:
+// each call must get its own reply or fail with ErrConnClosed, and the
+// node must end up connected.
+func TestNodeReconnectUnderLoad(t *testing.T) {
+	srv, err := roomzintest.NewServer(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	seed(t, srv)
+
+	payloads := make([][]byte, props)
+	for i := range payloads {
+		payloads[i], _ = command.BuildPropRoomListPayload(fmt.Sprintf("p%d", i))
+	}
+
+	opts := transport.Options{AuthToken: srv.Token(), Timeout: 2 * time.Second}
+	node := transport.NewNode(context.Background(), transport.Static(srv.Addr()), nil, opts)
+	var lost atomic.Int32
+	node.SetOnLost(func() { lost.Add(1) })
+	tr := transport.New(node)
+
+	const workers, calls = 32, 100
+	var (
+		wg     sync.WaitGroup
+		served atomic.Int32
+		errs   = make(chan error, workers)
+	)
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range calls {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				i := (w + n) % props
+				if n%2 == 0 {
+					res, err := tr.RoundTrip(ctx, false, payloads[i])
+					ok, err := check(i, res, err)
+					if err != nil {
+						cancel()
+						errs <- err
+						return
+					}
+					if ok {
+						served.Add(1)
+					}
+				} else {
+					batch := []int{i, (i + 1) % props, (i + 2) % props}
+					res, callErrs := tr.RoundTripBatch(ctx, false, [][]byte{payloads[batch[0]], payloads[batch[1]], payloads[batch[2]]})
+					for k, j := range batch {
+						ok, err := check(j, res[k], callErrs[k])
+						if err != nil {
+							cancel()
+							errs <- err
+							return
+						}
+						if ok {
+							served.Add(1)
+						}
+					}
+				}
+				cancel()
+			}
+		}()
+	}
+
+	stop := make(chan struct{})
+	dropped := make(chan struct{})
+	go func() {
+		defer close(dropped)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(2 * time.Millisecond):
+				srv.CloseConns()
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-dropped
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if served.Load() == 0 {
+		t.Fatal("no call was served")
+	}
+	if lost.Load() == 0 {
+		t.Fatal("the connection was never lost")
+	}
+
+	// The last drop may not have been noticed yet, failing one more call;
+	// after that the node must serve again.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for {
+		res, err := tr.RoundTrip(ctx, false, payloads[0])
+		ok, err := check(0, res, err)
+		if err != nil {
+			t.Fatalf("after the drops: %v", err)
+		}
+		if ok {
+			break
+		}
+	}
+
+	node.Close()
+	if _, err := tr.RoundTrip(ctx, false, payloads[0]); !errors.Is(err, protocol.ErrConnClosed) {
+		t.Fatalf("RoundTrip after Close = %v, want ErrConnClosed", err)
+	}
+}
+
+// TestRoundTripCancel gives up on calls the server holds and checks that
+// each returns an error matching ctx.Err() and leaves no demux entry.
+func TestRoundTripCancel(t *testing.T) {
+	srv, err := roomzintest.NewServer(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	seed(t, srv)
+
+	payload, _ := command.BuildPropRoomListPayload("p0")
+	node := transport.NewNode(context.Background(), transport.Static(srv.Addr()), nil, transport.Options{AuthToken: srv.Token(), Timeout: 2 * time.Second})
+	defer node.Close()
+	conn, err := node.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := transport.New(node)
+
+	tests := []struct {
+		name  string
+		ctx   func() (context.Context, context.CancelFunc)
+		batch bool
+		want  error
+	}{
+		{name: "cancelled", ctx: cancelSoon, want: context.Canceled},
+		{name: "deadline", ctx: deadlineSoon, want: context.DeadlineExceeded},
+		{name: "batch cancelled", ctx: cancelSoon, batch: true, want: context.Canceled},
+		{name: "batch deadline", ctx: deadlineSoon, batch: true, want: context.DeadlineExceeded},
+	}
+	release := srv.Hold()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			var errs []error
+			if tt.batch {
+				_, errs = tr.RoundTripBatch(ctx, false, [][]byte{payload, payload})
+			} else {
+				_, err := tr.RoundTrip(ctx, false, payload)
+				errs = []error{err}
+			}
+			for _, err := range errs {
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("err = %v, want %v", err, tt.want)
+				}
+			}
+			if n := conn.Pending(); n != 0 {
+				t.Fatalf("%d demux entries left", n)
+			}
+		})
+	}
+	release()
+
+	// The held calls are answered now; the replies nobody waits for are
+	// dropped and the connection serves on.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res, err := tr.RoundTrip(ctx, false, payload)
+	if ok, err := check(0, res, err); !ok || err != nil {
+		t.Fatalf("RoundTrip after release = %v", err)
+	}
+	if n := conn.Pending(); n != 0 {
+		t.Fatalf("%d demux entries left", n)
+	}
+}
+
+func cancelSoon() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	return ctx, cancel
+}
+
+func deadlineSoon() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 50*time.Millisecond)
+}
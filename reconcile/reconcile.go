@@ -0,0 +1,461 @@
+// Package reconcile brings Roomzin in line with a source of truth, such as
+// a PMS, by writing only what differs.
+//
+// The desired inventory is streamed as Records. For each room type it
+// names, the reconciler lists the stored dates with PropRoomDateList, reads
+// the days it also wants with GetPropRoomDay and compares availability,
+// price and rate features. Missing days are created and drifted ones
+// updated with SetRoomPkg, sending only the fields that changed; stored
+// days the source does not list are removed with DelRoomDay, once the whole
+// source has been read, so a source that fails midway never deletes.
+// Stored days are read and removed whatever their date, so days that have
+// fallen into the past are compared and pruned too.
+// Writes are pipelined in batches. With Options.DryRun nothing is written
+// and the changes are only reported.
+//
+// Room types and properties the source never names are left alone.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Defaults for Options fields left zero.
+const (
+	DefaultConcurrency = 8   // GetPropRoomDay calls in flight
+	DefaultBatchSize   = 256 // writes per pipelined batch
+)
+
+// Record is the desired state of one room type on one date.
+type Record struct {
+	PropertyID   string     `json:"property_id"`
+	RoomType     string     `json:"room_type"`
+	Date         types.Date `json:"date"`
+	Availability uint8      `json:"availability"`
+	FinalPrice   uint32     `json:"final_price"`
+	RateFeatures []string   `json:"rate_features"`
+}
+
+// Source yields the desired inventory. Next returns io.EOF once done.
+//
+// Records of one property and room type must be contiguous; their order
+// otherwise does not matter. A date repeated within a room keeps the last
+// record.
+type Source interface {
+	Next() (Record, error)
+}
+
+// Options configures Reconcile.
+type Options struct {
+	DryRun      bool // compute and report changes without writing
+	KeepExtra   bool // leave stored days the source does not list
+	Concurrency int  // reads and deletes in flight; default DefaultConcurrency
+	BatchSize   int  // writes per batch; default DefaultBatchSize
+
+	// OnChange, if set, receives every change after it was applied (or
+	// would have been, in a dry run): creates and updates in source order,
+	// then deletes.
+	OnChange func(Change)
+
+	// Clock and Location set "today" for the date check of planned
+	// writes, as on the client configs; nil means the system clock and
+	// UTC. A location set on ctx with api.WithLocation takes precedence.
+	Clock    types.Clock
+	Location *time.Location
+}
+
+// Action is what a change does to a stored day.
+type Action string
+
+const (
+	Create Action = "create" // SetRoomPkg with every field
+	Update Action = "update" // SetRoomPkg with the changed fields
+	Delete Action = "delete" // DelRoomDay
+)
+
+// Day is the state of one room day.
+type Day struct {
+	Availability uint8    `json:"availability"`
+	FinalPrice   uint32   `json:"final_price"`
+	RateFeatures []string `json:"rate_features"`
+}
+
+// Change is one difference between the source and the cache.
+type Change struct {
+	Action     Action
+	PropertyID string
+	RoomType   string
+	Date       types.Date
+	Fields     []string // for Update: "availability", "final_price", "rate_features"
+	Before     *Day     // stored state; nil for Create
+	After      *Day     // desired state; nil for Delete
+	Err        error    // why the change failed or would fail; nil on success
+}
+
+// Report counts the outcome of a reconcile. In a dry run Created, Updated
+// and Deleted count the planned changes.
+type Report struct {
+	Rooms     int `json:"rooms"`     // room types compared
+	Unchanged int `json:"unchanged"` // days already matching
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Deleted   int `json:"deleted"`
+	Failed    int `json:"failed"` // invalid records and failed writes
+}
+
+// Reconcile applies the difference between src and c. Invalid records and
+// failed writes are counted in Report.Failed and passed to OnChange with
+// their error; the returned error is reserved for src failing, records
+// not grouped by room, read errors and ctx ending.
+func Reconcile(ctx context.Context, c api.CacheClientAPI, src Source, opts Options) (Report, error) {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	codecs, err := c.GetCodecsCtx(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	loc := opts.Location
+	if l, ok := api.LocationFrom(ctx); ok {
+		loc = l
+	}
+	r := &reconciler{c: c, codecs: codecs, asOf: types.AsOf(types.Today(opts.Clock, loc)), opts: opts}
+	if !opts.DryRun {
+		r.batch = c.NewBatch()
+	}
+
+	var (
+		cur  roomKey
+		want map[types.Date]Record
+		seen = make(map[roomKey]bool)
+	)
+	for {
+		rec, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return r.report, err
+		}
+		k := roomKey{rec.PropertyID, rec.RoomType}
+		if k != cur || want == nil {
+			if want != nil {
+				if err := r.room(ctx, cur, want); err != nil {
+					return r.report, err
+				}
+			}
+			if seen[k] {
+				return r.report, fmt.Errorf("reconcile: records of %s/%s are not contiguous", k.property, k.room)
+			}
+			seen[k] = true
+			cur, want = k, make(map[types.Date]Record)
+		}
+		want[rec.Date] = rec
+	}
+	if want != nil {
+		if err := r.room(ctx, cur, want); err != nil {
+			return r.report, err
+		}
+	}
+	if err := r.flush(ctx); err != nil {
+		return r.report, err
+	}
+	return r.report, r.deleteExtra(ctx)
+}
+
+type roomKey struct{ property, room string }
+
+// pending is a change awaiting the next flush. Changes that failed before
+// sending wait too, so OnChange sees them in order.
+type pending struct {
+	ch   Change
+	sent bool // queued in the batch
+}
+
+type reconciler struct {
+	c      api.CacheClientAPI
+	codecs *types.Codecs
+	asOf   types.VerifyOption
+	opts   Options
+	report Report
+
+	batch   api.Batch
+	pending []pending
+	deletes []Change // sent after the source is read
+
+	// The last property checked with propExists.
+	lastProp   string
+	lastExists bool
+}
+
+// room diffs and queues one room type.
+func (r *reconciler) room(ctx context.Context, k roomKey, want map[types.Date]Record) error {
+	r.report.Rooms++
+	dates, err := r.c.PropRoomDateListCtx(ctx, types.PropRoomDateListPayload{PropertyID: k.property, RoomType: k.room})
+	missing := false // the property does not exist, so creates fail
+	if isNotFound(err) {
+		var exists bool
+		exists, err = r.propExists(ctx, k.property)
+		dates, missing = nil, !exists
+	}
+	if err != nil {
+		return err
+	}
+	var both []types.Date
+	for _, d := range dates {
+		if _, ok := want[d]; ok {
+			both = append(both, d)
+		}
+	}
+	have, err := r.read(ctx, k, both)
+	if err != nil {
+		return err
+	}
+
+	wanted := make([]types.Date, 0, len(want))
+	for d := range want {
+		wanted = append(wanted, d)
+	}
+	slices.SortFunc(wanted, types.Date.Compare)
+	for _, d := range wanted {
+		rec := want[d]
+		after := &Day{Availability: rec.Availability, FinalPrice: rec.FinalPrice, RateFeatures: nonNil(rec.RateFeatures)}
+		ch := Change{PropertyID: k.property, RoomType: k.room, Date: d, After: after}
+		p := types.SetRoomPkgPayload{
+			PropertyID:   k.property,
+			RoomType:     k.room,
+			Date:         d,
+			Availability: &after.Availability,
+			FinalPrice:   &after.FinalPrice,
+			RateFeature:  after.RateFeatures,
+		}
+		before, stored := have[d]
+		switch {
+		case !stored:
+			ch.Action = Create
+			if err := p.Verify(r.codecs, r.asOf); err != nil {
+				ch.Err = types.RzError(err)
+			} else if missing {
+				ch.Err = types.RzError(fmt.Sprintf("NOT_FOUND: property %s does not exist", k.property))
+			}
+		default:
+			ch.Action, ch.Before = Update, &before
+			if before.Availability != after.Availability {
+				ch.Fields = append(ch.Fields, "availability")
+			} else {
+				p.Availability = nil
+			}
+			if before.FinalPrice != after.FinalPrice {
+				ch.Fields = append(ch.Fields, "final_price")
+			} else {
+				p.FinalPrice = nil
+			}
+			if !sameFeatures(before.RateFeatures, after.RateFeatures) {
+				ch.Fields = append(ch.Fields, "rate_features")
+			} else {
+				p.RateFeature = nil
+			}
+			if len(ch.Fields) == 0 {
+				r.report.Unchanged++
+				continue
+			}
+			// Checked only now, so a stored past day the source still
+			// lists as is counts as unchanged rather than failing.
+			if err := p.Verify(r.codecs, r.asOf); err != nil {
+				ch.Err = types.RzError(err)
+			}
+		}
+		if err := r.queue(ctx, ch, func(b api.Batch) { b.SetRoomPkg(p) }); err != nil {
+			return err
+		}
+	}
+
+	if r.opts.KeepExtra {
+		return nil
+	}
+	for _, d := range dates {
+		if _, ok := want[d]; ok {
+			continue
+		}
+		r.deletes = append(r.deletes, Change{Action: Delete, PropertyID: k.property, RoomType: k.room, Date: d})
+	}
+	return nil
+}
+
+// propExists reports whether a property without stored days for a room
+// exists, so creates can be reported as failing in a dry run too.
+func (r *reconciler) propExists(ctx context.Context, id string) (bool, error) {
+	if id != r.lastProp {
+		ok, err := r.c.PropExistCtx(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		r.lastProp, r.lastExists = id, ok
+	}
+	return r.lastExists, nil
+}
+
+// read fetches the stored days concurrently. Days deleted since the date
+// list was taken are left out, so they are created. Stored days are read
+// whatever their date, as some may have fallen into the past.
+func (r *reconciler) read(ctx context.Context, k roomKey, dates []types.Date) (map[types.Date]Day, error) {
+	ctx = api.WithAnyDate(ctx)
+	var (
+		mu   sync.Mutex
+		out  = make(map[types.Date]Day, len(dates))
+		errs = make([]error, len(dates))
+		sem  = make(chan struct{}, r.opts.Concurrency)
+		wg   sync.WaitGroup
+	)
+	for i, date := range dates {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res, err := r.c.GetPropRoomDayCtx(ctx, types.GetRoomDayRequest{PropertyID: k.property, RoomType: k.room, Date: date})
+			if isNotFound(err) {
+				return
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			mu.Lock()
+			out[date] = Day{Availability: res.Availability, FinalPrice: res.FinalPrice, RateFeatures: nonNil(res.RateFeature)}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// deleteExtra removes the stored days the source does not list. They are
+// sent concurrently rather than batched, with no date window, since a
+// stored day in the past is exactly the kind of day to prune; a batch
+// would check it against today. Deletes are reported in order.
+func (r *reconciler) deleteExtra(ctx context.Context) error {
+	if r.opts.DryRun {
+		for _, d := range r.deletes {
+			r.done(d)
+		}
+		return nil
+	}
+	var (
+		delCtx = api.WithAnyDate(ctx)
+		sem    = make(chan struct{}, r.opts.Concurrency)
+		wg     sync.WaitGroup
+	)
+	for i := range r.deletes {
+		d := &r.deletes[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			d.Err = r.c.DelRoomDayCtx(delCtx, types.DelRoomDayRequest{PropertyID: d.PropertyID, RoomType: d.RoomType, Date: d.Date})
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, d := range r.deletes {
+		r.done(d)
+	}
+	return nil
+}
+
+// queue adds a change to the batch; one that already failed, or any in a
+// dry run, is only reported.
+func (r *reconciler) queue(ctx context.Context, ch Change, add func(api.Batch)) error {
+	if r.opts.DryRun {
+		r.done(ch)
+		return nil
+	}
+	send := ch.Err == nil
+	if send {
+		add(r.batch)
+	}
+	r.pending = append(r.pending, pending{ch, send})
+	if len(r.pending) >= r.opts.BatchSize {
+		return r.flush(ctx)
+	}
+	return nil
+}
+
+// flush sends the queued writes and reports the pending changes.
+func (r *reconciler) flush(ctx context.Context) error {
+	var results []types.BatchResult
+	if r.batch != nil && r.batch.Len() > 0 {
+		results, _ = r.batch.Exec(ctx)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	for _, p := range r.pending {
+		if p.sent {
+			p.ch.Err, results = results[0].Err, results[1:]
+		}
+		r.done(p.ch)
+	}
+	r.pending = r.pending[:0]
+	return nil
+}
+
+func (r *reconciler) done(ch Change) {
+	switch {
+	case ch.Err != nil:
+		r.report.Failed++
+	case ch.Action == Create:
+		r.report.Created++
+	case ch.Action == Update:
+		r.report.Updated++
+	case ch.Action == Delete:
+		r.report.Deleted++
+	}
+	if r.opts.OnChange != nil {
+		r.opts.OnChange(ch)
+	}
+}
+
+// sameFeatures compares rate features as sets: the server returns them in
+// codec order, the source in any order.
+func sameFeatures(a, b []string) bool {
+	for _, f := range a {
+		if !slices.Contains(b, f) {
+			return false
+		}
+	}
+	for _, f := range b {
+		if !slices.Contains(a, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func nonNil(fs []string) []string {
+	if fs == nil {
+		return []string{}
+	}
+	return fs
+}
+
+func isNotFound(err error) bool {
+	var rz *types.RoomzinError
+	return errors.As(err, &rz) && rz.Code == "NOT_FOUND"
+}
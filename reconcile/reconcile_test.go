@@ -0,0 +1,72 @@
+package reconcile_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/reconcile"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// clock is a settable types.Clock.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestReconcilePastDays(t *testing.T) {
+	clk := &clock{now: time.Date(2030, 5, 17, 12, 0, 0, 0, time.UTC)}
+	c, err := roomzintest.NewMemClient(roomzintest.Options{Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := types.DateOf(clk.now)
+	avl, price := uint8(3), uint32(12000)
+	for i := range 4 {
+		p := types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: today.AddDays(i), Availability: &avl, FinalPrice: &price, RateFeature: []string{}}
+		if err := c.SetRoomPkg(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Days 0 and 1 are now in the past.
+	clk.now = clk.now.AddDate(0, 0, 2)
+	rec := func(day int, avl uint8) reconcile.Record {
+		return reconcile.Record{PropertyID: "hotel-1", RoomType: "dbl", Date: today.AddDays(day), Availability: avl, FinalPrice: price}
+	}
+	src := []reconcile.Record{
+		rec(0, 3), // past, unchanged
+		rec(2, 3), // unchanged
+		rec(3, 1), // drifted
+		// day 1 is past and no longer listed, so it is pruned
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		var changes []reconcile.Change
+		got, err := reconcile.Reconcile(context.Background(), c, reconcile.Records(src), reconcile.Options{
+			DryRun:   dryRun,
+			Clock:    clk,
+			OnChange: func(ch reconcile.Change) { changes = append(changes, ch) },
+		})
+		if err != nil {
+			t.Fatalf("dry run %v: %v", dryRun, err)
+		}
+		want := reconcile.Report{Rooms: 1, Unchanged: 2, Updated: 1, Deleted: 1}
+		if got != want {
+			t.Fatalf("dry run %v: report = %+v, want %+v; changes %+v", dryRun, got, want, changes)
+		}
+	}
+
+	dates, err := c.PropRoomDateList(types.PropRoomDateListPayload{PropertyID: "hotel-1", RoomType: "dbl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 3 || dates[0] != today || dates[1] != today.AddDays(2) {
+		t.Fatalf("stored dates = %v", dates)
+	}
+}
@@ -0,0 +1,58 @@
+package reconcile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Records returns a Source over recs.
+func Records(recs []Record) Source { return &sliceSource{recs: recs} }
+
+type sliceSource struct{ recs []Record }
+
+func (s *sliceSource) Next() (Record, error) {
+	if len(s.recs) == 0 {
+		return Record{}, io.EOF
+	}
+	rec := s.recs[0]
+	s.recs = s.recs[1:]
+	return rec, nil
+}
+
+// JSONL returns a Source reading one Record per line from r. Blank lines
+// are skipped; unknown keys are an error, so a misspelt field is not
+// silently reconciled to zero.
+func JSONL(r io.Reader) Source {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	return &jsonlSource{sc: sc}
+}
+
+type jsonlSource struct {
+	sc   *bufio.Scanner
+	line int
+}
+
+func (s *jsonlSource) Next() (Record, error) {
+	for s.sc.Scan() {
+		s.line++
+		data := bytes.TrimSpace(s.sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var rec Record
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			return Record{}, fmt.Errorf("reconcile: line %d: %w", s.line, err)
+		}
+		return rec, nil
+	}
+	if err := s.sc.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
@@ -0,0 +1,174 @@
+package roomzintest_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/single"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// slowLink forwards connections to addr and holds back every chunk it
+// passes on by d, like a long network path. Bytes arriving meanwhile go
+// out with the next chunk, so a client that waits for each reply before
+// sending the next request pays 2d per request, and one that pipelines
+// pays it about once.
+func slowLink(t *testing.T, addr string, d time.Duration) (host string, port int) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			client, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			server, err := net.Dial("tcp", addr)
+			if err != nil {
+				client.Close()
+				continue
+			}
+			go forward(server, client, d)
+			go forward(client, server, d)
+		}
+	}()
+	h, p, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ = strconv.Atoi(p)
+	return h, port
+}
+
+func forward(dst, src net.Conn, d time.Duration) {
+	defer dst.Close()
+	defer src.Close()
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			time.Sleep(d)
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestBatchPipelined(t *testing.T) {
+	srv, err := roomzintest.NewServer(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	const delay, items = 20 * time.Millisecond, 50
+	cfg := srv.SingleConfig()
+	cfg.Host, cfg.TCPPort = slowLink(t, srv.Addr(), delay)
+	c, err := single.New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	b := c.NewBatch()
+	for i := range items {
+		b.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: fmt.Sprintf("hotel-%d", i), PropertyType: "hotel", Category: "c", Stars: 3})
+	}
+	start := time.Now()
+	res, err := b.Exec(context.Background())
+	took := time.Since(start)
+	if err != nil || len(res) != items {
+		t.Fatalf("Exec = %d results, %v", len(res), err)
+	}
+	// One by one the batch would take items*2*delay, 2s.
+	if took > items*2*delay/4 {
+		t.Fatalf("Exec took %v, the commands were not pipelined", took)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("Len after Exec = %d, want 0", b.Len())
+	}
+}
+
+func TestBatchResults(t *testing.T) {
+	_, c := newSingle(t, roomzintest.Options{})
+	d := types.Today(nil, nil).AddDays(1)
+	avl := uint8(1)
+	day := types.UpdRoomAvlPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Amount: 1}
+
+	b := c.NewBatch()
+	b.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3})
+	b.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Availability: &avl})
+	b.DecRoomAvl(day)
+	// UNDERFLOW, mid-batch
+	b.DecRoomAvl(day)
+	// fails Verify, never sent
+	b.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: types.Today(nil, nil).AddDays(-1), Availability: &avl})
+	b.IncRoomAvl(day)
+	b.IncRoomAvl(day)
+	// NOT_FOUND
+	b.DelRoomDay(types.DelRoomDayRequest{PropertyID: "hotel-2", RoomType: "dbl", Date: d})
+
+	res, err := b.Exec(context.Background())
+	want := []struct {
+		cmd     string
+		avl     uint8
+		wantErr func(error) bool
+	}{
+		{cmd: "SETPROP"},
+		{cmd: "SETROOMPKG"},
+		{cmd: "DECROOMAVL", avl: 0},
+		{cmd: "DECROOMAVL", wantErr: types.IsRequest},
+		{cmd: "SETROOMPKG", wantErr: types.IsRequest},
+		{cmd: "INCROOMAVL", avl: 1},
+		{cmd: "INCROOMAVL", avl: 2},
+		{cmd: "DELROOMDAY", wantErr: types.IsRequest},
+	}
+	if len(res) != len(want) {
+		t.Fatalf("Exec = %d results, want %d", len(res), len(want))
+	}
+	for i, w := range want {
+		r := res[i]
+		if r.Command != w.cmd {
+			t.Fatalf("result %d is for %s, want %s", i, r.Command, w.cmd)
+		}
+		if w.wantErr != nil {
+			if !w.wantErr(r.Err) {
+				t.Fatalf("result %d (%s): err = %v", i, r.Command, r.Err)
+			}
+			continue
+		}
+		if r.Err != nil || r.Availability != w.avl {
+			t.Fatalf("result %d (%s) = %d, %v; want %d", i, r.Command, r.Availability, r.Err, w.avl)
+		}
+	}
+	// Exec reports the first failure; the commands after it still ran.
+	if err != res[3].Err {
+		t.Fatalf("Exec error = %v, want the first failure %v", err, res[3].Err)
+	}
+	got, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: d})
+	if err != nil || got.Availability != 2 {
+		t.Fatalf("availability = %d, %v; want 2", got.Availability, err)
+	}
+
+	// A key resends the batch without applying anything twice.
+	ctx := api.WithIdempotencyKey(context.Background(), "batch-1")
+	for range 2 {
+		b.IncRoomAvl(day)
+		if _, err := b.Exec(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err = c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: d})
+	if err != nil || got.Availability != 3 {
+		t.Fatalf("availability after a resent batch = %d, %v; want 3", got.Availability, err)
+	}
+}
@@ -0,0 +1,421 @@
+package roomzintest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roomzin/roomzin-go/cluster"
+)
+
+// ClusterOptions configures a fake cluster. The zero value starts three
+// nodes on 127.0.0.1, 127.0.0.2 and 127.0.0.3.
+type ClusterOptions struct {
+	Options
+
+	// Nodes is the node count; default 3, or len(Hosts) when set.
+	Nodes int
+	// Hosts are the node addresses. The cluster client shares one TCP and
+	// one API port across hosts, so every node needs its own IP. Linux
+	// routes all of 127.0.0.0/8 to loopback; elsewhere add aliases and
+	// list them here.
+	Hosts []string
+}
+
+// Cluster is a set of fake nodes serving the HTTP discovery API
+// (/healthz, /node-info, /peers) and the framed protocol on shared ports.
+// Nodes share one store, so replication is instant. Node 0 starts as the
+// leader.
+//
+// Nodes answer like real ones given their role: followers reject writes
+// with 308, the leader rejects reads with 405 and an unavailable node
+// replies 503 to everything. GETCODECS is served by any live node.
+type Cluster struct {
+	*backend
+	useTLS  bool
+	opts    ClusterOptions
+	tcpPort int
+	apiPort int
+
+	mu     sync.Mutex
+	nodes  []*Node
+	leader *Node
+}
+
+// Node is one member of a fake Cluster.
+type Node struct {
+	c    *Cluster
+	id   string
+	host string
+
+	// guarded by c.mu
+	tcp         *Server
+	api         *http.Server
+	alive       bool
+	unavailable bool
+	failCode    string
+	failN       int
+	dropN       int
+	served      int
+}
+
+// NewCluster starts the nodes and returns once all of them listen.
+func NewCluster(opts ClusterOptions) (*Cluster, error) {
+	if err := opts.applyDefaults(); err != nil {
+		return nil, err
+	}
+	if len(opts.Hosts) == 0 {
+		if opts.Nodes == 0 {
+			opts.Nodes = 3
+		}
+		for i := 1; i <= opts.Nodes; i++ {
+			opts.Hosts = append(opts.Hosts, "127.0.0."+strconv.Itoa(i))
+		}
+	}
+
+	c := &Cluster{backend: newBackend(opts.Options), opts: opts, useTLS: opts.TLSConfig != nil}
+	tcpLns, tcpPort, err := listenAll(opts.Hosts, opts.Options)
+	if err != nil {
+		return nil, err
+	}
+	apiLns, apiPort, err := listenAll(opts.Hosts, opts.Options)
+	if err != nil {
+		closeAll(tcpLns)
+		return nil, err
+	}
+	c.tcpPort, c.apiPort = tcpPort, apiPort
+
+	for i, host := range opts.Hosts {
+		n := &Node{c: c, id: fmt.Sprintf("node-%d", i+1), host: host}
+		n.start(tcpLns[i], apiLns[i])
+		c.nodes = append(c.nodes, n)
+	}
+	c.leader = c.nodes[0]
+	return c, nil
+}
+
+// listenAll binds one listener per host on a common free port.
+func listenAll(hosts []string, opts Options) ([]net.Listener, int, error) {
+	var lastErr error
+	for range 20 {
+		first, err := listen(net.JoinHostPort(hosts[0], "0"), opts.TLSConfig)
+		if err != nil {
+			return nil, 0, err
+		}
+		_, portStr, _ := net.SplitHostPort(first.Addr().String())
+		lns := []net.Listener{first}
+		for _, host := range hosts[1:] {
+			ln, err := listen(net.JoinHostPort(host, portStr), opts.TLSConfig)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			lns = append(lns, ln)
+		}
+		if len(lns) == len(hosts) {
+			port, _ := strconv.Atoi(portStr)
+			return lns, port, nil
+		}
+		closeAll(lns) // port taken on some host; pick another
+	}
+	return nil, 0, fmt.Errorf("roomzintest: no common free port: %w", lastErr)
+}
+
+func closeAll(lns []net.Listener) {
+	for _, ln := range lns {
+		_ = ln.Close()
+	}
+}
+
+func (n *Node) start(tcpLn, apiLn net.Listener) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", n.authorized(n.handleHealth))
+	mux.HandleFunc("/node-info", n.authorized(n.handleNodeInfo))
+	mux.HandleFunc("/peers", n.authorized(n.handlePeers))
+
+	n.tcp = serve(tcpLn, n.c.backend, n.gate)
+	n.api = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	n.alive = true
+	go func() { _ = n.api.Serve(apiLn) }()
+}
+
+// SeedHosts returns every node host, comma separated, for ClusterConfig.
+func (c *Cluster) SeedHosts() string { return strings.Join(c.opts.Hosts, ",") }
+
+// TCPPort returns the framed protocol port shared by all nodes.
+func (c *Cluster) TCPPort() int { return c.tcpPort }
+
+// APIPort returns the HTTP discovery port shared by all nodes.
+func (c *Cluster) APIPort() int { return c.apiPort }
+
+// Token returns the accepted auth token.
+func (c *Cluster) Token() string { return c.token }
+
+// ClusterConfig returns a cluster.ClusterConfig pointing at the nodes.
+// TLS is left unset; add a TLSConfig when the cluster was started with one.
+func (c *Cluster) ClusterConfig() cluster.ClusterConfig {
+	return cluster.ClusterConfig{
+		SeedHosts:   c.SeedHosts(),
+		APIPort:     c.apiPort,
+		TCPPort:     c.tcpPort,
+		AuthToken:   c.token,
+		Timeout:     2 * time.Second,
+		HttpTimeout: time.Second,
+		KeepAlive:   30 * time.Second,
+		Clock:       c.clock,
+		Location:    c.loc,
+		Currencies:  c.currencies,
+	}
+}
+
+// Nodes returns the nodes in host order.
+func (c *Cluster) Nodes() []*Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Node(nil), c.nodes...)
+}
+
+// Node returns the i-th node.
+func (c *Cluster) Node(i int) *Node { return c.nodes[i] }
+
+// Leader returns the node every member currently names as leader. It may
+// be dead after Kill until Elect or Failover picks a new one.
+func (c *Cluster) Leader() *Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.leader
+}
+
+// Elect makes node i the leader and every other node its follower. Open
+// connections stay up, so clients see 308 on writes to the old leader and
+// 405 on reads to the new one, as during a real election.
+func (c *Cluster) Elect(i int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := c.nodes[i]
+	if !n.alive {
+		return fmt.Errorf("roomzintest: cannot elect dead %s", n.id)
+	}
+	c.leader = n
+	return nil
+}
+
+// Failover kills the current leader and promotes the first live follower,
+// returning the new leader.
+func (c *Cluster) Failover() (*Node, error) {
+	c.Leader().Kill()
+	for i, n := range c.Nodes() {
+		if n.Alive() {
+			return n, c.Elect(i)
+		}
+	}
+	return nil, errors.New("roomzintest: no live follower to promote")
+}
+
+// FailNext makes every live node answer its next count requests with
+// code, e.g. "429" for a throttling storm or "503".
+func (c *Cluster) FailNext(code string, count int) {
+	for _, n := range c.Nodes() {
+		n.FailNext(code, count)
+	}
+}
+
+// DropNext makes every live node run its next count commands but close the
+// connection instead of replying, as a node that fails after applying a
+// write and before answering it.
+func (c *Cluster) DropNext(count int) {
+	for _, n := range c.Nodes() {
+		n.DropNext(count)
+	}
+}
+
+// Close stops every node.
+func (c *Cluster) Close() error {
+	for _, n := range c.Nodes() {
+		n.Kill()
+	}
+	return nil
+}
+
+// ID returns the node id reported by /node-info, e.g. "node-1".
+func (n *Node) ID() string { return n.id }
+
+// Host returns the node address.
+func (n *Node) Host() string { return n.host }
+
+// IsLeader reports whether the cluster currently names n as leader.
+func (n *Node) IsLeader() bool { return n.c.Leader() == n }
+
+// Alive reports whether n is listening.
+func (n *Node) Alive() bool {
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	return n.alive
+}
+
+// Served returns how many commands n has executed, not counting those it
+// turned away with an error code.
+func (n *Node) Served() int {
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	return n.served
+}
+
+// Kill closes the node's listeners and connections, as if the process
+// died. A dead leader stays named as leader until Elect or Failover.
+func (n *Node) Kill() {
+	n.c.mu.Lock()
+	if !n.alive {
+		n.c.mu.Unlock()
+		return
+	}
+	n.alive = false
+	tcp, api := n.tcp, n.api
+	n.c.mu.Unlock()
+
+	_ = api.Close()
+	_ = tcp.Close()
+}
+
+// Revive restarts a killed node on its old address as a follower, or as
+// leader if it is still the one named.
+func (n *Node) Revive() error {
+	if n.Alive() {
+		return nil
+	}
+	tcpLn, err := listen(net.JoinHostPort(n.host, strconv.Itoa(n.c.tcpPort)), n.c.opts.TLSConfig)
+	if err != nil {
+		return err
+	}
+	apiLn, err := listen(net.JoinHostPort(n.host, strconv.Itoa(n.c.apiPort)), n.c.opts.TLSConfig)
+	if err != nil {
+		_ = tcpLn.Close()
+		return err
+	}
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	n.unavailable, n.failN, n.dropN = false, 0, 0
+	n.start(tcpLn, apiLn)
+	return nil
+}
+
+// SetUnavailable makes /healthz report "unavailable" and every command
+// fail with 503 until cleared.
+func (n *Node) SetUnavailable(v bool) {
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	n.unavailable = v
+}
+
+// FailNext answers the node's next count requests with code.
+func (n *Node) FailNext(code string, count int) {
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	n.failCode, n.failN = code, count
+}
+
+// DropNext runs the node's next count commands but closes the connection
+// instead of replying; commands turned away with an error code do not
+// count.
+func (n *Node) DropNext(count int) {
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	n.dropN = count
+}
+
+// gate applies role and fault injection before a command executes.
+func (n *Node) gate(cmd string) (code string, drop bool) {
+	n.c.mu.Lock()
+	defer n.c.mu.Unlock()
+	switch {
+	case n.unavailable:
+		return "503", false
+	case n.failN > 0:
+		n.failN--
+		return n.failCode, false
+	case cmd == "GETCODECS":
+	case writes[cmd] && n.c.leader != n:
+		return "308", false
+	case !writes[cmd] && n.c.leader == n:
+		return "405", false
+	}
+	n.served++
+	if n.dropN > 0 {
+		n.dropN--
+		return "", true
+	}
+	return "", false
+}
+
+func (n *Node) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+n.c.token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (n *Node) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	n.c.mu.Lock()
+	status := "active_follower"
+	switch {
+	case n.unavailable:
+		status = "unavailable"
+	case n.c.leader == n:
+		status = "active_leader"
+	}
+	n.c.mu.Unlock()
+	_, _ = w.Write([]byte(status))
+}
+
+// nodeInfo mirrors the JSON served by a real node's /node-info.
+type nodeInfo struct {
+	NodeID    string `json:"node_id"`
+	ZoneID    string `json:"zone_id"`
+	ShardID   string `json:"shard_id"`
+	LeaderID  string `json:"leader_id"`
+	LeaderURL string `json:"leader_url"`
+}
+
+func (n *Node) handleNodeInfo(w http.ResponseWriter, _ *http.Request) {
+	n.c.mu.Lock()
+	leader := n.c.leader
+	n.c.mu.Unlock()
+
+	scheme := "http"
+	if n.c.useTLS {
+		scheme = "https"
+	}
+	writeJSON(w, nodeInfo{
+		NodeID:    n.id,
+		ZoneID:    "zone-1",
+		ShardID:   "shard-1",
+		LeaderID:  leader.id,
+		LeaderURL: scheme + "://" + net.JoinHostPort(leader.host, strconv.Itoa(n.c.apiPort)),
+	})
+}
+
+// handlePeers lists every other member, dead or alive, like a static
+// membership list.
+func (n *Node) handlePeers(w http.ResponseWriter, _ *http.Request) {
+	peers := make([]string, 0, len(n.c.opts.Hosts)-1)
+	for _, h := range n.c.opts.Hosts {
+		if h != n.host {
+			peers = append(peers, h)
+		}
+	}
+	writeJSON(w, peers)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,91 @@
+package roomzintest_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+type codecsChange struct{ old, new *types.Codecs }
+
+func watch(c api.CacheClientAPI) (<-chan codecsChange, func()) {
+	ch := make(chan codecsChange, 8)
+	stop := c.WatchCodecs(func(old, new *types.Codecs) { ch <- codecsChange{old, new} })
+	return ch, stop
+}
+
+// upgrade serves codecs with version and the extra rate feature from now
+// on, drops the client's connection and keeps writing until watched sees
+// the change the reconnect brings.
+func upgrade(t *testing.T, srv *roomzintest.Server, c api.CacheClientAPI, watched <-chan codecsChange, version, feature string) codecsChange {
+	t.Helper()
+	features := append(slices.Clone(roomzintest.DefaultRateFeatures), feature)
+	if err := srv.SetCodecs(types.Codecs{Version: version, RateFeatures: features}); err != nil {
+		t.Fatal(err)
+	}
+	srv.CloseConns()
+	deadline := time.After(5 * time.Second)
+	for {
+		// Writes check against the codecs, so one after the reconnect
+		// fetches them.
+		_ = c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3})
+		select {
+		case ch := <-watched:
+			return ch
+		case <-deadline:
+			t.Fatalf("no watcher call for %s", version)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestWatchCodecs(t *testing.T) {
+	srv, c := newSingle(t, roomzintest.Options{})
+	first, err := c.GetCodecs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopped, stop := watch(c)
+	watched, _ := watch(c)
+
+	got := upgrade(t, srv, c, watched, "v2", "spa")
+	if got.old.Version != first.Version || got.new.Version != "v2" || !slices.Contains(got.new.RateFeatures, "spa") {
+		t.Fatalf("watcher got %s -> %s %v, want %s -> v2 with spa", got.old.Version, got.new.Version, got.new.RateFeatures, first.Version)
+	}
+	if got := <-stopped; got.old.Version != first.Version || got.new.Version != "v2" {
+		t.Fatalf("second watcher got %s -> %s, want %s -> v2", got.old.Version, got.new.Version, first.Version)
+	}
+
+	// A stopped watcher hears nothing more.
+	stop()
+	got = upgrade(t, srv, c, watched, "v3", "sauna")
+	if got.old.Version != "v2" || got.new.Version != "v3" || !slices.Contains(got.new.RateFeatures, "sauna") {
+		t.Fatalf("watcher got %s -> %s %v, want v2 -> v3 with sauna", got.old.Version, got.new.Version, got.new.RateFeatures)
+	}
+
+	// Refetching the same version calls nobody.
+	srv.CloseConns()
+	for attempt := 0; ; attempt++ {
+		// The first call may go out on the dropped connection.
+		_, err := c.GetCodecs()
+		if err == nil {
+			break
+		}
+		if attempt == 10 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case got := <-watched:
+		t.Fatalf("watcher called for an unchanged version: %s -> %s", got.old.Version, got.new.Version)
+	case got := <-stopped:
+		t.Fatalf("stopped watcher called: %s -> %s", got.old.Version, got.new.Version)
+	default:
+	}
+}
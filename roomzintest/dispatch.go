@@ -0,0 +1,458 @@
+package roomzintest
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roomzin/roomzin-go/internal/command"
+	"github.com/roomzin/roomzin-go/internal/memstore"
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+const idempotencyKeyField = command.IdempotencyKeyField
+
+// writes lists the commands a cluster routes to its leader.
+var writes = map[string]bool{
+	"SETPROP":      true,
+	"SETROOMPKG":   true,
+	"SETROOMAVL":   true,
+	"SETROOMAVLIF": true,
+	"INCROOMAVL":   true,
+	"DECROOMAVL":   true,
+	"DELPROP":      true,
+	"DELSEGMENT":   true,
+	"DELPROPDAY":   true,
+	"DELPROPROOM":  true,
+	"DELROOMDAY":   true,
+}
+
+// args indexes request fields by id.
+type args map[uint16]protocol.Field
+
+func newArgs(fields []protocol.Field) args {
+	a := make(args, len(fields))
+	for _, f := range fields {
+		a[f.ID] = f
+	}
+	return a
+}
+
+func (a args) str(id uint16) string { return string(a[id].Data) }
+
+func (a args) strp(id uint16) *string {
+	f, ok := a[id]
+	if !ok {
+		return nil
+	}
+	v := string(f.Data)
+	return &v
+}
+
+// list splits a comma separated field; absent or empty yields nil.
+func (a args) list(id uint16) []string {
+	s := a.str(id)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// listp is list for optional fields: absent yields nil, present but empty
+// yields an empty list, which clears what is stored.
+func (a args) listp(id uint16) []string {
+	if _, ok := a[id]; !ok {
+		return nil
+	}
+	if l := a.list(id); l != nil {
+		return l
+	}
+	return []string{}
+}
+
+// date parses a YYYY-MM-DD field; a malformed one yields the zero Date,
+// which the store rejects.
+func (a args) date(id uint16) types.Date {
+	d, _ := types.ParseDate(a.str(id))
+	return d
+}
+
+func (a args) dates(id uint16) []types.Date {
+	list := a.list(id)
+	out := make([]types.Date, len(list))
+	for i, s := range list {
+		out[i], _ = types.ParseDate(s)
+	}
+	return out
+}
+
+func (a args) u8(id uint16) uint8 {
+	if d := a[id].Data; len(d) == 1 {
+		return d[0]
+	}
+	return 0
+}
+
+func (a args) u8p(id uint16) *uint8 {
+	if _, ok := a[id]; !ok {
+		return nil
+	}
+	v := a.u8(id)
+	return &v
+}
+
+func (a args) u32p(id uint16) *uint32 {
+	d := a[id].Data
+	if len(d) != 4 {
+		return nil
+	}
+	v := binary.LittleEndian.Uint32(d)
+	return &v
+}
+
+func (a args) u64p(id uint16) *uint64 {
+	d := a[id].Data
+	if len(d) != 8 {
+		return nil
+	}
+	v := binary.LittleEndian.Uint64(d)
+	return &v
+}
+
+func (a args) f64p(id uint16) *float64 {
+	d := a[id].Data
+	if len(d) != 8 {
+		return nil
+	}
+	v := math.Float64frombits(binary.LittleEndian.Uint64(d))
+	return &v
+}
+
+func (a args) f64(id uint16) float64 {
+	if v := a.f64p(id); v != nil {
+		return *v
+	}
+	return 0
+}
+
+func str(id uint16, s string) protocol.Field {
+	return protocol.Field{ID: id, FieldType: 0x01, Data: []byte(s)}
+}
+
+func u8(id uint16, v uint8) protocol.Field {
+	return protocol.Field{ID: id, FieldType: 0x02, Data: []byte{v}}
+}
+
+func u32(id uint16, v uint32) protocol.Field {
+	return protocol.Field{ID: id, FieldType: 0x03, Data: protocol.MakeU32(v)}
+}
+
+func boolean(v bool) protocol.Field {
+	if v {
+		return u8(1, 1)
+	}
+	return u8(1, 0)
+}
+
+func failure(err error) (string, []protocol.Field) {
+	return "ERROR", []protocol.Field{str(1, err.Error())}
+}
+
+func success(fields ...protocol.Field) (string, []protocol.Field) {
+	return "SUCCESS", fields
+}
+
+func strs(list []string) []protocol.Field {
+	out := make([]protocol.Field, len(list))
+	for i, s := range list {
+		out[i] = str(uint16(i+1), s)
+	}
+	return out
+}
+
+func scalar(v uint8, err error) (string, []protocol.Field) {
+	if err != nil {
+		return failure(err)
+	}
+	return success(u8(1, v))
+}
+
+func done(err error) (string, []protocol.Field) {
+	if err != nil {
+		return failure(err)
+	}
+	return success()
+}
+
+// dispatch decodes a request with the field ids used by internal/command,
+// applies it to store and returns the reply status and fields.
+func dispatch(store *memstore.Store, cmd string, a args) (string, []protocol.Field) {
+	switch cmd {
+	case "GETCODECS":
+		codecs := store.Codecs()
+		out := []protocol.Field{{ID: 1, FieldType: 0x09, Data: []byte(strings.Join(codecs.RateFeatures, ","))}}
+		if codecs.BookingHorizon > 0 {
+			out = append(out, protocol.Field{ID: 2, FieldType: 0x02, Data: binary.LittleEndian.AppendUint16(nil, uint16(codecs.BookingHorizon))})
+		}
+		out = append(out, protocol.Field{ID: 3, FieldType: 0x01, Data: []byte(codecs.Version)})
+		for id, dict := range [][]string{4: codecs.Amenities, 5: codecs.PropertyTypes, 6: codecs.Categories} {
+			if len(dict) > 0 {
+				out = append(out, protocol.Field{ID: uint16(id), FieldType: 0x09, Data: []byte(strings.Join(dict, ","))})
+			}
+		}
+		return success(out...)
+
+	case "SETPROP":
+		p := types.SetPropPayload{
+			Segment:      a.str(0x01),
+			Area:         a.str(0x02),
+			PropertyID:   a.str(0x03),
+			PropertyType: a.str(0x04),
+			Category:     a.str(0x05),
+			Stars:        a.u8(0x06),
+			Latitude:     a.f64(0x07),
+			Longitude:    a.f64(0x08),
+			Amenities:    a.list(0x09),
+		}
+		return done(store.SetProp(p))
+
+	case "SEARCHPROP":
+		p := types.SearchPropPayload{
+			Segment:   a.str(0x01),
+			Area:      a.strp(0x02),
+			Type:      a.strp(0x03),
+			Stars:     a.u8p(0x04),
+			Category:  a.strp(0x05),
+			Longitude: a.f64p(0x07),
+			Latitude:  a.f64p(0x08),
+			Limit:     a.u64p(0x09),
+		}
+		if am := a.list(0x06); am != nil {
+			p.Amenities = &am
+		}
+		ids, err := store.SearchProp(p)
+		if err != nil {
+			return failure(err)
+		}
+		out := make([]protocol.Field, len(ids))
+		for i, id := range ids {
+			b, _ := protocol.PropertyIDToBytes(id)
+			out[i] = protocol.Field{ID: uint16(i + 1), FieldType: 0x01, Data: b}
+		}
+		return success(out...)
+
+	case "SEARCHAVAIL":
+		p := types.SearchAvailPayload{
+			Segment:      a.str(0x01),
+			RoomType:     a.str(0x02),
+			Area:         a.strp(0x03),
+			PropertyID:   a.strp(0x04),
+			Type:         a.strp(0x05),
+			Stars:        a.u8p(0x06),
+			Category:     a.strp(0x07),
+			Amenities:    a.list(0x08),
+			Longitude:    a.f64p(0x09),
+			Latitude:     a.f64p(0x0A),
+			Date:         a.dates(0x0B),
+			Availability: a.u8p(0x0C),
+			FinalPrice:   a.u32p(0x0D),
+			RateFeature:  a.list(0x0E),
+			Limit:        a.u64p(0x0F),
+		}
+		res, err := store.SearchAvail(p)
+		if err != nil {
+			return failure(err)
+		}
+		return searchAvailReply(store, len(p.Date), res)
+
+	case "SETROOMPKG":
+		p := types.SetRoomPkgPayload{
+			PropertyID:   a.str(0x01),
+			RoomType:     a.str(0x02),
+			Date:         a.date(0x03),
+			Availability: a.u8p(0x04),
+			FinalPrice:   a.u32p(0x05),
+			RateFeature:  a.listp(0x06),
+		}
+		return done(store.SetRoomPkg(p))
+
+	case "SETROOMAVL", "INCROOMAVL", "DECROOMAVL":
+		p := types.UpdRoomAvlPayload{
+			PropertyID: a.str(0x01),
+			RoomType:   a.str(0x02),
+			Date:       a.date(0x03),
+			Amount:     a.u8(0x04),
+		}
+		switch cmd {
+		case "SETROOMAVL":
+			return scalar(store.SetRoomAvl(p))
+		case "INCROOMAVL":
+			return scalar(store.IncRoomAvl(p))
+		default:
+			return scalar(store.DecRoomAvl(p))
+		}
+
+	case "SETROOMAVLIF":
+		p := types.SetRoomAvlIfPayload{
+			PropertyID: a.str(0x01),
+			RoomType:   a.str(0x02),
+			Date:       a.date(0x03),
+			Expected:   a.u8(0x04),
+			Amount:     a.u8(0x05),
+		}
+		v, err := store.SetRoomAvlIf(p)
+		if errors.Is(err, memstore.ErrConflict) {
+			// the current value rides along so the caller can retry
+			return "ERROR", []protocol.Field{str(1, err.Error()), u8(2, v)}
+		}
+		return scalar(v, err)
+
+	case "PROPEXIST":
+		v, err := store.PropExist(a.str(0x01))
+		if err != nil {
+			return failure(err)
+		}
+		return success(boolean(v))
+
+	case "PROPROOMEXIST":
+		v, err := store.PropRoomExist(types.PropRoomExistPayload{PropertyID: a.str(0x01), RoomType: a.str(0x02)})
+		if err != nil {
+			return failure(err)
+		}
+		return success(boolean(v))
+
+	case "PROPROOMLIST":
+		list, err := store.PropRoomList(a.str(0x01))
+		if err != nil {
+			return failure(err)
+		}
+		return success(strs(list)...)
+
+	case "PROPROOMDATELIST":
+		list, err := store.PropRoomDateList(types.PropRoomDateListPayload{PropertyID: a.str(0x01), RoomType: a.str(0x02)})
+		if err != nil {
+			return failure(err)
+		}
+		dates := make([]string, len(list))
+		for i, d := range list {
+			dates[i] = d.String()
+		}
+		return success(strs(dates)...)
+
+	case "DELPROP":
+		return done(store.DelProp(a.str(0x01)))
+
+	case "DELSEGMENT":
+		return done(store.DelSegment(a.str(0x01)))
+
+	case "DELPROPDAY":
+		return done(store.DelPropDay(types.DelPropDayRequest{PropertyID: a.str(0x01), Date: a.date(0x02)}))
+
+	case "DELPROPROOM":
+		return done(store.DelPropRoom(types.DelPropRoomPayload{PropertyID: a.str(0x01), RoomType: a.str(0x02)}))
+
+	case "DELROOMDAY":
+		return done(store.DelRoomDay(types.DelRoomDayRequest{PropertyID: a.str(0x01), RoomType: a.str(0x02), Date: a.date(0x03)}))
+
+	case "GETPROPROOMDAY":
+		r, err := store.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: a.str(0x01), RoomType: a.str(0x02), Date: a.date(0x03)})
+		if err != nil {
+			return failure(err)
+		}
+		mask, _ := store.Mask(r.RateFeature)
+		return success(str(1, r.PropertyID), str(2, r.Date.String()), u8(3, r.Availability), u32(4, r.FinalPrice), u32(5, mask))
+
+	case "GETSEGMENTS":
+		segs, err := store.GetSegments()
+		if err != nil {
+			return failure(err)
+		}
+		out := make([]protocol.Field, 0, 2*len(segs))
+		for i, seg := range segs {
+			out = append(out, str(uint16(2*i+1), seg.Segment), u32(uint16(2*i+2), seg.PropCount))
+		}
+		return success(out...)
+	}
+	return failure(errors.New("VALIDATION_ERROR: unknown command " + cmd))
+}
+
+// decodeRequest splits a request payload built by internal/command into
+// its command name and fields.
+func decodeRequest(payload []byte) (string, []protocol.Field, error) {
+	if len(payload) < 1 || len(payload) < 1+int(payload[0])+2 {
+		return "", nil, errors.New("short request payload")
+	}
+	n := int(payload[0])
+	cmd := string(payload[1 : 1+n])
+	count := binary.LittleEndian.Uint16(payload[1+n:])
+	fields, err := protocol.ParseFields(payload[1+n+2:], count)
+	return cmd, fields, err
+}
+
+// searchAvailReply encodes | num_days u16 | then per property a packed id
+// and a vector of | count u16 | 11 bytes per day |.
+func searchAvailReply(store *memstore.Store, numDays int, res []types.PropertyAvail) (string, []protocol.Field) {
+	out := []protocol.Field{{ID: 1, FieldType: 0x02, Data: binary.LittleEndian.AppendUint16(nil, uint16(numDays))}}
+	for _, prop := range res {
+		id, _ := protocol.PropertyIDToBytes(prop.PropertyID)
+		vec := binary.LittleEndian.AppendUint16(make([]byte, 0, 2+11*len(prop.Days)), uint16(len(prop.Days)))
+		for _, d := range prop.Days {
+			packed, err := d.Date.Pack(time.Now().Year())
+			if err != nil {
+				return failure(errors.New("VALIDATION_ERROR: " + err.Error()))
+			}
+			mask, _ := store.Mask(d.RateFeature)
+			vec = binary.LittleEndian.AppendUint16(vec, packed)
+			vec = append(vec, d.Availability)
+			vec = binary.LittleEndian.AppendUint32(vec, d.FinalPrice)
+			vec = binary.LittleEndian.AppendUint32(vec, mask)
+		}
+		n := uint16(len(out))
+		out = append(out,
+			protocol.Field{ID: n + 1, FieldType: 0x01, Data: id},
+			protocol.Field{ID: n + 2, FieldType: 0x08, Data: vec},
+		)
+	}
+	return success(out...)
+}
+
+// replayCacheSize bounds how many idempotency keys are remembered.
+const replayCacheSize = 4096
+
+// replayCache remembers the reply for recent idempotency keys, evicting
+// the oldest key once full.
+type replayCache struct {
+	mu    sync.Mutex
+	max   int
+	order []string
+	resp  map[string][]byte
+}
+
+func newReplayCache(max int) *replayCache {
+	return &replayCache{max: max, resp: make(map[string][]byte)}
+}
+
+func (c *replayCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.resp[key]
+	return r, ok
+}
+
+func (c *replayCache) put(key string, resp []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.resp[key]; ok {
+		return
+	}
+	if len(c.order) >= c.max {
+		delete(c.resp, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.order = append(c.order, key)
+	c.resp[key] = resp
+}
@@ -0,0 +1,465 @@
+package roomzintest
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/internal/batch"
+	"github.com/roomzin/roomzin-go/internal/codecs"
+	"github.com/roomzin/roomzin-go/internal/memstore"
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+type memClient struct {
+	store      *memstore.Store
+	clock      types.Clock
+	loc        *time.Location
+	currencies types.Currencies
+	watchers   codecs.Watchers
+	closed     atomic.Bool
+}
+
+// NewMemClient returns an api.CacheClientAPI that keeps its data in memory
+// and never opens a socket. Payloads are verified exactly as by the single
+// and cluster clients, and the store applies the same server semantics as
+// NewServer, so application tests can run against it directly. Only
+// the codecs, Clock, Location and Currencies of Options are used.
+func NewMemClient(opts Options) (api.CacheClientAPI, error) {
+	if err := opts.applyDefaults(); err != nil {
+		return nil, types.RzError(err, types.KindClient)
+	}
+	return &memClient{store: memstore.New(opts.codecs()), clock: opts.Clock, loc: opts.Location, currencies: opts.Currencies}, nil
+}
+
+// begin reports why a call cannot start: the client was closed or ctx
+// is already done.
+func (c *memClient) begin(ctx context.Context) error {
+	if c.closed.Load() {
+		return types.RzError(protocol.ErrConnClosed)
+	}
+	if err := ctx.Err(); err != nil {
+		return types.RzError(err)
+	}
+	return nil
+}
+
+// dateChecks checks dates against today in the ctx location, else the
+// configured one, and against the store's booking horizon. A ctx from
+// api.WithAnyDate skips both.
+func (c *memClient) dateChecks(ctx context.Context, _ string) []types.VerifyOption {
+	if api.AnyDateFrom(ctx) {
+		return []types.VerifyOption{types.AnyDate()}
+	}
+	loc, ok := api.LocationFrom(ctx)
+	if !ok {
+		loc = c.loc
+	}
+	return []types.VerifyOption{types.AsOf(types.Today(c.clock, loc)), types.Horizon(c.getCodecs().BookingHorizon)}
+}
+
+// getCodecs reads the store's codecs, so watchers hear of a change made
+// with SetCodecs on the next call that uses them.
+func (c *memClient) getCodecs() *types.Codecs {
+	codecs := c.store.Codecs()
+	c.watchers.Observe(codecs)
+	return codecs
+}
+
+// rz wraps a store error, keeping nil an untyped nil.
+func rz(err error) error {
+	if err == nil {
+		return nil
+	}
+	return types.RzError(err)
+}
+
+func (c *memClient) WatchCodecs(fn func(old, new *types.Codecs)) (stop func()) {
+	return c.watchers.Watch(fn)
+}
+
+func (c *memClient) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+// --------------------------------------------------
+//
+//	public API
+//
+// --------------------------------------------------
+
+func (c *memClient) GetCodecs() (*types.Codecs, error) {
+	return c.GetCodecsCtx(context.Background())
+}
+
+func (c *memClient) SetProp(p types.SetPropPayload) error {
+	return c.SetPropCtx(context.Background(), p)
+}
+
+func (c *memClient) SearchProp(p types.SearchPropPayload) ([]string, error) {
+	return c.SearchPropCtx(context.Background(), p)
+}
+
+func (c *memClient) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	return c.SearchAvailCtx(context.Background(), p)
+}
+
+func (c *memClient) SetRoomPkg(p types.SetRoomPkgPayload) error {
+	return c.SetRoomPkgCtx(context.Background(), p)
+}
+
+func (c *memClient) SetRoomPkgRange(p types.SetRoomPkgRangePayload) ([]types.DateResult, error) {
+	return c.SetRoomPkgRangeCtx(context.Background(), p)
+}
+
+func (c *memClient) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.SetRoomAvlCtx(context.Background(), p)
+}
+
+func (c *memClient) SetRoomAvlIf(p types.SetRoomAvlIfPayload) (uint8, error) {
+	return c.SetRoomAvlIfCtx(context.Background(), p)
+}
+
+func (c *memClient) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.IncRoomAvlCtx(context.Background(), p)
+}
+
+func (c *memClient) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.DecRoomAvlCtx(context.Background(), p)
+}
+
+func (c *memClient) PropExist(propertyID string) (bool, error) {
+	return c.PropExistCtx(context.Background(), propertyID)
+}
+
+func (c *memClient) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
+	return c.PropRoomExistCtx(context.Background(), p)
+}
+
+func (c *memClient) PropRoomList(propertyID string) ([]string, error) {
+	return c.PropRoomListCtx(context.Background(), propertyID)
+}
+
+func (c *memClient) PropRoomDateList(p types.PropRoomDateListPayload) ([]types.Date, error) {
+	return c.PropRoomDateListCtx(context.Background(), p)
+}
+
+func (c *memClient) DelProp(propertyID string) error {
+	return c.DelPropCtx(context.Background(), propertyID)
+}
+
+func (c *memClient) DelSegment(segment string) error {
+	return c.DelSegmentCtx(context.Background(), segment)
+}
+
+func (c *memClient) DelPropDay(p types.DelPropDayRequest) error {
+	return c.DelPropDayCtx(context.Background(), p)
+}
+
+func (c *memClient) DelPropRoom(p types.DelPropRoomPayload) error {
+	return c.DelPropRoomCtx(context.Background(), p)
+}
+
+func (c *memClient) DelRoomDay(p types.DelRoomDayRequest) error {
+	return c.DelRoomDayCtx(context.Background(), p)
+}
+
+func (c *memClient) DelRoomRange(p types.DelRoomRangePayload) ([]types.DateResult, error) {
+	return c.DelRoomRangeCtx(context.Background(), p)
+}
+
+func (c *memClient) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	return c.GetPropRoomDayCtx(context.Background(), p)
+}
+
+func (c *memClient) GetSegments() ([]types.SegmentInfo, error) {
+	return c.GetSegmentsCtx(context.Background())
+}
+
+// NewBatch queues writes like the network clients; Exec runs each encoded
+// command against the store through the fake server's dispatcher.
+func (c *memClient) NewBatch() api.Batch {
+	return c.newBatch(context.Background())
+}
+
+// newBatch returns a batch whose dates are checked as of today for ctx.
+func (c *memClient) newBatch(ctx context.Context) *batch.Batch {
+	getCodecs := func() (*types.Codecs, error) { return c.getCodecs(), nil }
+	checks := func(propertyID string) []types.VerifyOption {
+		return append(c.dateChecks(ctx, propertyID), types.PricesIn(c.currencies.ForProperty(propertyID)))
+	}
+	return batch.New(getCodecs, checks, func(ctx context.Context, payloads [][]byte, _ []bool) ([]protocol.RawResult, []error) {
+		results := make([]protocol.RawResult, len(payloads))
+		errs := make([]error, len(payloads))
+		for i, payload := range payloads {
+			if errs[i] = c.begin(ctx); errs[i] != nil {
+				continue
+			}
+			cmd, fields, err := decodeRequest(payload)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			status, out := dispatch(c.store, cmd, newArgs(fields))
+			results[i] = protocol.RawResult{Status: status, Fields: out}
+		}
+		return results, errs
+	})
+}
+
+// --------------------------------------------------
+//
+//	context-aware API
+//
+// --------------------------------------------------
+
+func (c *memClient) GetCodecsCtx(ctx context.Context) (*types.Codecs, error) {
+	if err := c.begin(ctx); err != nil {
+		return nil, err
+	}
+	return c.getCodecs(), nil
+}
+
+func (c *memClient) SetPropCtx(ctx context.Context, p types.SetPropPayload) error {
+	if err := p.Verify(c.getCodecs()); err != nil {
+		return types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.SetProp(p))
+}
+
+func (c *memClient) SearchPropCtx(ctx context.Context, p types.SearchPropPayload) ([]string, error) {
+	if err := p.Verify(c.getCodecs()); err != nil {
+		return nil, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return nil, err
+	}
+	ids, err := c.store.SearchProp(p)
+	return ids, rz(err)
+}
+
+func (c *memClient) SearchAvailCtx(ctx context.Context, p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	if err := p.Verify(c.getCodecs(), append(c.dateChecks(ctx, ""), types.PricesIn(c.currencies.For(p.Segment)))...); err != nil {
+		return nil, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return nil, err
+	}
+	res, err := c.store.SearchAvail(p)
+	if err != nil {
+		return res, rz(err)
+	}
+	cur := c.currencies.For(p.Segment)
+	for i := range res {
+		for j := range res[i].Days {
+			res[i].Days[j].Currency = cur
+		}
+	}
+	return res, nil
+}
+
+func (c *memClient) SetRoomPkgCtx(ctx context.Context, p types.SetRoomPkgPayload) error {
+	if err := p.Verify(c.getCodecs(), append(c.dateChecks(ctx, p.PropertyID), types.PricesIn(c.currencies.ForProperty(p.PropertyID)))...); err != nil {
+		return types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.SetRoomPkg(p))
+}
+
+func (c *memClient) SetRoomPkgRangeCtx(ctx context.Context, p types.SetRoomPkgRangePayload) ([]types.DateResult, error) {
+	days, err := p.Days()
+	if err != nil {
+		return nil, types.RzError(err)
+	}
+	b := c.newBatch(ctx)
+	dates := make([]types.Date, len(days))
+	for i, d := range days {
+		b.SetRoomPkg(d)
+		dates[i] = d.Date
+	}
+	return b.ExecDates(ctx, dates)
+}
+
+func (c *memClient) SetRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return 0, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return 0, err
+	}
+	v, err := c.store.SetRoomAvl(p)
+	return v, rz(err)
+}
+
+func (c *memClient) SetRoomAvlIfCtx(ctx context.Context, p types.SetRoomAvlIfPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return 0, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return 0, err
+	}
+	v, err := c.store.SetRoomAvlIf(p)
+	return v, rz(err)
+}
+
+func (c *memClient) IncRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return 0, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return 0, err
+	}
+	v, err := c.store.IncRoomAvl(p)
+	return v, rz(err)
+}
+
+func (c *memClient) DecRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return 0, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return 0, err
+	}
+	v, err := c.store.DecRoomAvl(p)
+	return v, rz(err)
+}
+
+func (c *memClient) PropExistCtx(ctx context.Context, propertyID string) (bool, error) {
+	if strings.TrimSpace(propertyID) == "" {
+		return false, types.RzError("VALIDATION_ERROR: propertyID is required")
+	}
+	if err := c.begin(ctx); err != nil {
+		return false, err
+	}
+	v, err := c.store.PropExist(propertyID)
+	return v, rz(err)
+}
+
+func (c *memClient) PropRoomExistCtx(ctx context.Context, p types.PropRoomExistPayload) (bool, error) {
+	if err := p.Verify(); err != nil {
+		return false, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return false, err
+	}
+	v, err := c.store.PropRoomExist(p)
+	return v, rz(err)
+}
+
+func (c *memClient) PropRoomListCtx(ctx context.Context, propertyID string) ([]string, error) {
+	if strings.TrimSpace(propertyID) == "" {
+		return nil, types.RzError("VALIDATION_ERROR: propertyID is required")
+	}
+	if err := c.begin(ctx); err != nil {
+		return nil, err
+	}
+	list, err := c.store.PropRoomList(propertyID)
+	return list, rz(err)
+}
+
+func (c *memClient) PropRoomDateListCtx(ctx context.Context, p types.PropRoomDateListPayload) ([]types.Date, error) {
+	if err := p.Verify(); err != nil {
+		return nil, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return nil, err
+	}
+	list, err := c.store.PropRoomDateList(p)
+	return list, rz(err)
+}
+
+func (c *memClient) DelPropCtx(ctx context.Context, propertyID string) error {
+	if strings.TrimSpace(propertyID) == "" {
+		return types.RzError("VALIDATION_ERROR: propertyID is required")
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.DelProp(propertyID))
+}
+
+func (c *memClient) DelSegmentCtx(ctx context.Context, segment string) error {
+	if strings.TrimSpace(segment) == "" {
+		return types.RzError("VALIDATION_ERROR: segment is required")
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.DelSegment(segment))
+}
+
+func (c *memClient) DelPropDayCtx(ctx context.Context, p types.DelPropDayRequest) error {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.DelPropDay(p))
+}
+
+func (c *memClient) DelPropRoomCtx(ctx context.Context, p types.DelPropRoomPayload) error {
+	if err := p.Verify(); err != nil {
+		return types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.DelPropRoom(p))
+}
+
+func (c *memClient) DelRoomDayCtx(ctx context.Context, p types.DelRoomDayRequest) error {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return err
+	}
+	return rz(c.store.DelRoomDay(p))
+}
+
+func (c *memClient) DelRoomRangeCtx(ctx context.Context, p types.DelRoomRangePayload) ([]types.DateResult, error) {
+	days, err := p.Days()
+	if err != nil {
+		return nil, types.RzError(err)
+	}
+	b := c.newBatch(ctx)
+	dates := make([]types.Date, len(days))
+	for i, d := range days {
+		b.DelRoomDay(d)
+		dates[i] = d.Date
+	}
+	return b.ExecDates(ctx, dates)
+}
+
+func (c *memClient) GetPropRoomDayCtx(ctx context.Context, p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return types.GetRoomDayResult{}, types.RzError(err)
+	}
+	if err := c.begin(ctx); err != nil {
+		return types.GetRoomDayResult{}, err
+	}
+	res, err := c.store.GetPropRoomDay(p)
+	if err != nil {
+		return res, rz(err)
+	}
+	res.Currency = c.currencies.ForProperty(p.PropertyID)
+	return res, nil
+}
+
+func (c *memClient) GetSegmentsCtx(ctx context.Context) ([]types.SegmentInfo, error) {
+	if err := c.begin(ctx); err != nil {
+		return nil, err
+	}
+	segs, err := c.store.GetSegments()
+	return segs, rz(err)
+}
@@ -0,0 +1,109 @@
+package roomzintest_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+func TestRangeWrites(t *testing.T) {
+	_, c := newSingle(t, roomzintest.Options{})
+	if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+		t.Fatal(err)
+	}
+	from := types.Today(nil, nil).AddDays(1)
+	to := from.AddDays(13)
+	avl, price := uint8(4), uint32(9900)
+
+	res, err := c.SetRoomPkgRange(types.SetRoomPkgRangePayload{PropertyID: "hotel-1", RoomType: "dbl", From: from, To: to, Availability: &avl, FinalPrice: &price, RateFeature: []string{"breakfast"}})
+	if err != nil || len(res) != 14 {
+		t.Fatalf("SetRoomPkgRange = %d results, %v; want 14", len(res), err)
+	}
+	for i, r := range res {
+		if r.Err != nil || r.Date != from.AddDays(i) {
+			t.Fatalf("result %d = %s, %v; want %s", i, r.Date, r.Err, from.AddDays(i))
+		}
+		day, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: r.Date})
+		if err != nil || day.Availability != avl || day.FinalPrice != price || !slices.Equal(day.RateFeature, []string{"breakfast"}) {
+			t.Fatalf("%s = %+v, %v", r.Date, day, err)
+		}
+	}
+
+	// Only the weekend nights are deleted.
+	weekend := types.WeekdayMask(1<<time.Saturday | 1<<time.Sunday)
+	res, err = c.DelRoomRange(types.DelRoomRangePayload{PropertyID: "hotel-1", RoomType: "dbl", From: from, To: to, Weekdays: weekend})
+	if err != nil || len(res) != 4 {
+		t.Fatalf("DelRoomRange = %d results, %v; want 4", len(res), err)
+	}
+	for d := range types.DateRange(from, to) {
+		_, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: d})
+		if weekend.Has(d.Weekday()) != types.IsRequest(err) {
+			t.Fatalf("%s (%s) after deleting the weekends: %v", d, d.Weekday(), err)
+		}
+	}
+}
+
+func TestRangePartialFailure(t *testing.T) {
+	t.Run("past the horizon", func(t *testing.T) {
+		_, c := newSingle(t, roomzintest.Options{BookingHorizon: 5})
+		if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+			t.Fatal(err)
+		}
+		today := types.Today(nil, nil)
+		avl := uint8(1)
+		res, err := c.SetRoomPkgRangeCtx(context.Background(), types.SetRoomPkgRangePayload{PropertyID: "hotel-1", RoomType: "dbl", From: today.AddDays(3), To: today.AddDays(8), Availability: &avl})
+		if len(res) != 6 {
+			t.Fatalf("SetRoomPkgRange = %d results, want 6", len(res))
+		}
+		// Days 3..5 are within the horizon and land; 6..8 are rejected.
+		for i, r := range res {
+			beyond := i >= 3
+			if beyond != types.IsRequest(r.Err) {
+				t.Fatalf("%s: err = %v", r.Date, r.Err)
+			}
+			_, getErr := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: r.Date})
+			if beyond != (getErr != nil) {
+				t.Fatalf("%s stored = %v, want %v", r.Date, getErr == nil, !beyond)
+			}
+		}
+		if err != res[3].Err {
+			t.Fatalf("SetRoomPkgRange error = %v, want the first failure %v", err, res[3].Err)
+		}
+	})
+
+	t.Run("missing days", func(t *testing.T) {
+		_, c := newSingle(t, roomzintest.Options{})
+		if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+			t.Fatal(err)
+		}
+		from := types.Today(nil, nil).AddDays(1)
+		avl := uint8(1)
+		for _, i := range []int{0, 1, 3} {
+			if err := c.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: from.AddDays(i), Availability: &avl}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		res, err := c.DelRoomRange(types.DelRoomRangePayload{PropertyID: "hotel-1", RoomType: "dbl", From: from, To: from.AddDays(4)})
+		if len(res) != 5 {
+			t.Fatalf("DelRoomRange = %d results, want 5", len(res))
+		}
+		for i, r := range res {
+			missing := i == 2 || i == 4
+			if missing != types.IsRequest(r.Err) || (!missing && r.Err != nil) {
+				t.Fatalf("%s: err = %v", r.Date, r.Err)
+			}
+		}
+		if err != res[2].Err {
+			t.Fatalf("DelRoomRange error = %v, want the first failure %v", err, res[2].Err)
+		}
+		for i := range 5 {
+			if _, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: from.AddDays(i)}); !types.IsRequest(err) {
+				t.Fatalf("%s still stored: %v", from.AddDays(i), err)
+			}
+		}
+	})
+}
@@ -0,0 +1,394 @@
+// Package roomzintest runs an in-process fake Roomzin server for tests.
+//
+// The server listens on a local TCP port, speaks the same framed protocol
+// as a real node (LOGIN, GETCODECS and every data command) and keeps its
+// data in memory, so single.New can point at it and integration tests run
+// without a live cluster:
+//
+//	srv, err := roomzintest.NewServer(roomzintest.Options{})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//
+//	cfg := srv.SingleConfig()
+//	client, err := single.New(&cfg)
+//
+// Data semantics follow the server as the SDK understands them: room days
+// can only be written on a property created by SETPROP, INCROOMAVL,
+// DECROOMAVL, SETROOMAVLIF and GETPROPROOMDAY need an existing day,
+// availability stays within 0..255 (OVERFLOW / UNDERFLOW otherwise),
+// deleting something missing is NOT_FOUND and writes carrying the same
+// idempotency key are applied once.
+package roomzintest
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/roomzin/roomzin-go/internal/memstore"
+	"github.com/roomzin/roomzin-go/internal/protocol"
+	"github.com/roomzin/roomzin-go/single"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// DefaultToken is the auth token accepted when Options.Token is empty.
+const DefaultToken = "roomzintest"
+
+// DefaultRateFeatures are the codecs served when Options.RateFeatures is empty.
+var DefaultRateFeatures = []string{
+	"breakfast",
+	"half_board",
+	"full_board",
+	"free_cancellation",
+	"non_refundable",
+	"pay_at_property",
+}
+
+// Options configures a fake server. The zero value is ready to use.
+type Options struct {
+	Addr         string      // listen address; default "127.0.0.1:0"
+	Token        string      // accepted LOGIN token; default DefaultToken
+	RateFeatures []string    // GETCODECS rate features; default DefaultRateFeatures
+	TLSConfig    *tls.Config // serve TLS instead of plaintext TCP when set
+
+	// BookingHorizon, when set, is advertised by GETCODECS as the number
+	// of days past today the server accepts dates.
+	BookingHorizon int
+
+	// Amenities, PropertyTypes and Categories, when set, are advertised
+	// by GETCODECS as the dictionaries clients check properties against.
+	Amenities     []string
+	PropertyTypes []string
+	Categories    []string
+
+	// Clock and Location set "today" for client date checks. They are
+	// copied into SingleConfig and ClusterConfig and used by
+	// NewMemClient; nil means the system clock and UTC.
+	Clock    types.Clock
+	Location *time.Location
+
+	// Currencies is the currency of FinalPrice values, copied into
+	// SingleConfig and ClusterConfig and used by NewMemClient.
+	Currencies types.Currencies
+}
+
+func (opts *Options) applyDefaults() error {
+	if opts.Token == "" {
+		opts.Token = DefaultToken
+	}
+	if len(opts.RateFeatures) == 0 {
+		opts.RateFeatures = DefaultRateFeatures
+	}
+	return checkCodecs(opts.codecs())
+}
+
+// codecs returns the codecs opts describe, versioned by their digest.
+func (opts *Options) codecs() types.Codecs {
+	return types.Codecs{
+		RateFeatures:   opts.RateFeatures,
+		Amenities:      opts.Amenities,
+		PropertyTypes:  opts.PropertyTypes,
+		Categories:     opts.Categories,
+		BookingHorizon: opts.BookingHorizon,
+	}
+}
+
+func checkCodecs(c types.Codecs) error {
+	if len(c.RateFeatures) > types.MaxRateFeatures {
+		return errors.New("roomzintest: at most 24 rate features fit the codec bitmask")
+	}
+	if c.BookingHorizon < 0 || c.BookingHorizon > types.MaxBookingHorizon {
+		return errors.New("roomzintest: booking horizon out of range")
+	}
+	return nil
+}
+
+// backend is the state shared by every node of a fake deployment, so a
+// write accepted by one node is visible on all of them.
+type backend struct {
+	token      string
+	store      *memstore.Store
+	idem       *replayCache
+	clock      types.Clock
+	loc        *time.Location
+	currencies types.Currencies
+}
+
+// SetCodecs changes what GETCODECS returns from now on, as a server
+// upgrade would. Stored rate feature masks keep their bits. Clients see
+// the change on their next fetch; CloseConns forces one. An empty
+// Version is replaced by the codecs' Digest.
+func (b *backend) SetCodecs(codecs types.Codecs) error {
+	if err := checkCodecs(codecs); err != nil {
+		return err
+	}
+	b.store.SetCodecs(codecs)
+	return nil
+}
+
+func newBackend(opts Options) *backend {
+	return &backend{
+		token:      opts.Token,
+		store:      memstore.New(opts.codecs()),
+		idem:       newReplayCache(replayCacheSize),
+		clock:      opts.Clock,
+		loc:        opts.Location,
+		currencies: opts.Currencies,
+	}
+}
+
+// Server is a fake single Roomzin node.
+type Server struct {
+	ln net.Listener
+	*backend
+	// gate, when set, may turn a command away with an error code such
+	// as "308" before it reaches the store, or have the connection
+	// dropped instead of answering once the command ran.
+	gate func(cmd string) (code string, drop bool)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	held   chan struct{} // closed by the release func of Hold
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer starts a fake server and returns once it accepts connections.
+func NewServer(opts Options) (*Server, error) {
+	if opts.Addr == "" {
+		opts.Addr = "127.0.0.1:0"
+	}
+	if err := opts.applyDefaults(); err != nil {
+		return nil, err
+	}
+	ln, err := listen(opts.Addr, opts.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+	return serve(ln, newBackend(opts), nil), nil
+}
+
+func listen(addr string, tlsCfg *tls.Config) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		ln = tls.NewListener(ln, tlsCfg)
+	}
+	return ln, nil
+}
+
+func serve(ln net.Listener, b *backend, gate func(string) (string, bool)) *Server {
+	s := &Server{
+		ln:      ln,
+		backend: b,
+		gate:    gate,
+		conns:   make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.acceptLoop()
+	return s
+}
+
+// Addr returns the listen address as host:port.
+func (s *Server) Addr() string { return s.ln.Addr().String() }
+
+// Host returns the listen host.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.Addr())
+	return host
+}
+
+// Port returns the listen port.
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.Addr())
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Token returns the accepted auth token.
+func (s *Server) Token() string { return s.token }
+
+// SingleConfig returns a single.Config pointing at the server. TLS is
+// left unset; add a TLSConfig when the server was started with one.
+func (s *Server) SingleConfig() single.Config {
+	return single.Config{
+		Host:       s.Host(),
+		TCPPort:    s.Port(),
+		AuthToken:  s.token,
+		Timeout:    2 * time.Second,
+		KeepAlive:  30 * time.Second,
+		Clock:      s.clock,
+		Location:   s.loc,
+		Currencies: s.currencies,
+	}
+}
+
+// CloseConns drops every open client connection but keeps listening,
+// which lets tests exercise client reconnects.
+func (s *Server) CloseConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// Hold stops the server answering: commands are read but wait, unexecuted,
+// until release is called, which lets tests keep calls in flight. Close
+// releases them too.
+func (s *Server) Hold() (release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held == nil {
+		s.held = make(chan struct{})
+	}
+	held := s.held
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.held == held {
+			close(held)
+			s.held = nil
+		}
+	}
+}
+
+// wait blocks while the server is held.
+func (s *Server) wait() {
+	s.mu.Lock()
+	held := s.held
+	s.mu.Unlock()
+	if held != nil {
+		<-held
+	}
+}
+
+// Close stops the listener, drops all connections and waits for the
+// connection goroutines to exit.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	if s.held != nil {
+		close(s.held)
+		s.held = nil
+	}
+	err := s.ln.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				_ = conn.Close()
+			}()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// serveConn authenticates the connection and then answers frames in the
+// order they arrive, so pipelined requests get in-order replies.
+func (s *Server) serveConn(conn net.Conn) {
+	hdr, fields, err := readRequest(conn)
+	if err != nil {
+		return
+	}
+	if hdr.Status != "LOGIN" || len(fields) == 0 || string(fields[0].Data) != s.token {
+		_, _ = conn.Write([]byte("LOGIN FAILED"))
+		return
+	}
+	if _, err := conn.Write([]byte("LOGIN OK")); err != nil {
+		return
+	}
+
+	for {
+		hdr, fields, err := readRequest(conn)
+		if err != nil {
+			return
+		}
+		s.wait()
+		resp := s.respond(hdr.Status, fields)
+		if resp == nil {
+			return // the reply is lost with the connection
+		}
+		if _, err := conn.Write(protocol.PrependHeader(hdr.ClrID, resp)); err != nil {
+			return
+		}
+	}
+}
+
+// readRequest reads one request frame; its status slot holds the command.
+func readRequest(conn net.Conn) (protocol.Header, []protocol.Field, error) {
+	hdr, payload, err := protocol.DrainFrame(conn)
+	if err != nil {
+		return hdr, nil, err
+	}
+	fields, err := protocol.ParseFields(payload[1+len(hdr.Status)+2:], hdr.FieldCnt)
+	return hdr, fields, err
+}
+
+// respond runs cmd and returns the encoded reply payload, or nil when the
+// gate wants the connection dropped instead. Writes carrying an
+// idempotency key are answered from the replay cache when the key was
+// seen before, so a retried write is applied once.
+func (s *Server) respond(cmd string, fields []protocol.Field) []byte {
+	drop := false
+	if s.gate != nil {
+		var code string
+		if code, drop = s.gate(cmd); code != "" {
+			return protocol.EncodePayload(failure(errors.New(code)))
+		}
+	}
+	a := newArgs(fields)
+	key := a.str(idempotencyKeyField)
+	if key != "" {
+		if resp, ok := s.idem.get(key); ok {
+			return resp
+		}
+	}
+	status, out := dispatch(s.store, cmd, a)
+	resp := protocol.EncodePayload(status, out)
+	if key != "" {
+		s.idem.put(key, resp)
+	}
+	if drop {
+		return nil
+	}
+	return resp
+}
@@ -0,0 +1,200 @@
+package roomzintest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/single"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+func newSingle(t *testing.T, opts roomzintest.Options) (*roomzintest.Server, api.CacheClientAPI) {
+	t.Helper()
+	srv, err := roomzintest.NewServer(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	cfg := srv.SingleConfig()
+	c, err := single.New(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return srv, c
+}
+
+func TestServerSemantics(t *testing.T) {
+	_, c := newSingle(t, roomzintest.Options{})
+	d := types.Today(nil, nil).AddDays(1)
+	day := func(avl uint8) types.UpdRoomAvlPayload {
+		return types.UpdRoomAvlPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Amount: avl}
+	}
+
+	avl := uint8(2)
+	if err := c.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Availability: &avl}); !types.IsRequest(err) {
+		t.Fatalf("SetRoomPkg before SetProp = %v, want NOT_FOUND", err)
+	}
+	if err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.IncRoomAvl(day(1)); !types.IsRequest(err) {
+		t.Fatalf("IncRoomAvl on a missing day = %v, want NOT_FOUND", err)
+	}
+	if err := c.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Availability: &avl}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		call    func() (uint8, error)
+		want    uint8
+		wantErr func(error) bool
+	}{
+		{name: "dec", call: func() (uint8, error) { return c.DecRoomAvl(day(1)) }, want: 1},
+		{name: "underflow", call: func() (uint8, error) { return c.DecRoomAvl(day(2)) }, wantErr: types.IsRequest},
+		{name: "overflow", call: func() (uint8, error) { return c.IncRoomAvl(day(255)) }, wantErr: types.IsRequest},
+		{
+			name: "cas conflict",
+			call: func() (uint8, error) {
+				return c.SetRoomAvlIf(types.SetRoomAvlIfPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Expected: 5, Amount: 3})
+			},
+			want:    1,
+			wantErr: types.IsConflict,
+		},
+		{
+			name: "keyed inc",
+			call: func() (uint8, error) {
+				return c.IncRoomAvlCtx(api.WithIdempotencyKey(context.Background(), "k1"), day(1))
+			},
+			want: 2,
+		},
+		{
+			name: "keyed inc replayed",
+			call: func() (uint8, error) {
+				return c.IncRoomAvlCtx(api.WithIdempotencyKey(context.Background(), "k1"), day(1))
+			},
+			want: 2,
+		},
+		{name: "unkeyed inc", call: func() (uint8, error) { return c.IncRoomAvl(day(1)) }, want: 3},
+	}
+	for _, tt := range tests {
+		got, err := tt.call()
+		if tt.wantErr != nil {
+			if !tt.wantErr(err) {
+				t.Fatalf("%s: err = %v", tt.name, err)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if err := c.DelRoomDay(types.DelRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: d}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DelRoomDay(types.DelRoomDayRequest{PropertyID: "hotel-1", RoomType: "dbl", Date: d}); !types.IsRequest(err) {
+		t.Fatalf("second DelRoomDay = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestServerLogin(t *testing.T) {
+	srv, _ := newSingle(t, roomzintest.Options{Token: "secret"})
+	cfg := srv.SingleConfig()
+	cfg.AuthToken = "wrong"
+	if c, err := single.New(&cfg); err == nil {
+		c.Close()
+		t.Fatal("logged in with a wrong token")
+	}
+}
+
+func TestServerCloseConns(t *testing.T) {
+	srv, c := newSingle(t, roomzintest.Options{})
+	srv.CloseConns()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := c.PropExist("hotel-1")
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("client did not reconnect: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestClientCancel checks that a call given up on matches the reason with
+// errors.Is, through the client's error wrapping.
+func TestClientCancel(t *testing.T) {
+	srv, c := newSingle(t, roomzintest.Options{})
+	release := srv.Hold()
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := c.PropExistCtx(ctx, "hotel-1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled PropExist = %v, want context.Canceled", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.DecRoomAvlCtx(ctx, types.UpdRoomAvlPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: types.Today(nil, nil), Amount: 1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("timed out DecRoomAvl = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestClientCurrencies(t *testing.T) {
+	eur, jpy := types.MustParseCurrency("EUR"), types.MustParseCurrency("JPY")
+	_, c := newSingle(t, roomzintest.Options{Currencies: types.Currencies{
+		Default:  eur,
+		Segments: map[string]types.Currency{"jp": jpy},
+		Segment:  func(string) string { return "jp" },
+	}})
+	if err := c.SetProp(types.SetPropPayload{Segment: "jp", Area: "a", PropertyID: "tokyo-1", PropertyType: "hotel", Category: "c", Stars: 3}); err != nil {
+		t.Fatal(err)
+	}
+	d := types.Today(nil, nil).AddDays(1)
+	avl := uint8(2)
+	p := types.SetRoomPkgPayload{PropertyID: "tokyo-1", RoomType: "dbl", Date: d, Availability: &avl}
+
+	if err := p.SetPrice(types.Money{Amount: 12000, Currency: eur}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetRoomPkg(p); !types.IsRequest(err) {
+		t.Fatalf("SetRoomPkg in EUR for a JPY segment = %v, want a validation error", err)
+	}
+	if err := p.SetPrice(types.Money{Amount: 12000, Currency: jpy}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetRoomPkg(p); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetPropRoomDay(types.GetRoomDayRequest{PropertyID: "tokyo-1", RoomType: "dbl", Date: d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (types.Money{Amount: 12000, Currency: jpy}); got.Price() != want {
+		t.Fatalf("GetPropRoomDay price = %v, want %v", got.Price(), want)
+	}
+
+	q := types.SearchAvailPayload{Segment: "jp", RoomType: "dbl", Date: []types.Date{d}}
+	if err := q.SetMaxPrice(types.Money{Amount: 15000, Currency: jpy}); err != nil {
+		t.Fatal(err)
+	}
+	avail, err := c.SearchAvail(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(avail) != 1 || len(avail[0].Days) != 1 || avail[0].Days[0].Price().Currency != jpy {
+		t.Fatalf("SearchAvail = %+v, want one day priced in JPY", avail)
+	}
+}
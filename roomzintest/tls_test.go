@@ -0,0 +1,174 @@
+package roomzintest_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/cluster"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/single"
+)
+
+// testPKI is a throwaway CA with one server and one client certificate.
+type testPKI struct {
+	pool   *x509.CertPool
+	server tls.Certificate
+	client tls.Certificate
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "roomzintest CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTpl, caTpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "roomzintest"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv4(127, 0, 0, 2), net.IPv4(127, 0, 0, 3)},
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tpl, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return testPKI{
+		pool:   pool,
+		server: issue(2, x509.ExtKeyUsageServerAuth),
+		client: issue(3, x509.ExtKeyUsageClientAuth),
+	}
+}
+
+func (p testPKI) serverConfig(mutual bool) *tls.Config {
+	cfg := &tls.Config{Certificates: []tls.Certificate{p.server}}
+	if mutual {
+		cfg.ClientCAs = p.pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg
+}
+
+func TestSingleTLS(t *testing.T) {
+	pki := newTestPKI(t)
+
+	tests := []struct {
+		name    string
+		mutual  bool
+		client  *tls.Config
+		wantErr bool
+	}{
+		{name: "tls", client: &tls.Config{RootCAs: pki.pool}},
+		{name: "mtls", mutual: true, client: &tls.Config{RootCAs: pki.pool, Certificates: []tls.Certificate{pki.client}}},
+		{name: "mtls without client cert", mutual: true, client: &tls.Config{RootCAs: pki.pool}, wantErr: true},
+		{name: "unknown CA", client: &tls.Config{}, wantErr: true},
+		{name: "plaintext client", client: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, err := roomzintest.NewServer(roomzintest.Options{TLSConfig: pki.serverConfig(tt.mutual)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer srv.Close()
+
+			cfg := srv.SingleConfig()
+			cfg.TLSConfig = tt.client
+			cfg.Timeout = 500 * time.Millisecond
+			c, err := single.New(&cfg)
+			if tt.wantErr {
+				if err == nil {
+					c.Close()
+					t.Fatal("single.New succeeded, want a handshake error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if _, err := c.PropExist("hotel-1"); err != nil {
+				t.Fatalf("PropExist over TLS: %v", err)
+			}
+		})
+	}
+}
+
+func TestClusterTLS(t *testing.T) {
+	pki := newTestPKI(t)
+
+	tests := []struct {
+		name    string
+		client  *tls.Config
+		wantErr bool
+	}{
+		{name: "mtls", client: &tls.Config{RootCAs: pki.pool, Certificates: []tls.Certificate{pki.client}}},
+		{name: "mtls without client cert", client: &tls.Config{RootCAs: pki.pool}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := roomzintest.ClusterOptions{}
+			opts.TLSConfig = pki.serverConfig(true)
+			cl, err := roomzintest.NewCluster(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cl.Close()
+
+			cfg := cl.ClusterConfig()
+			cfg.TLSConfig = tt.client
+			c, err := cluster.New(&cfg)
+			if tt.wantErr {
+				if err == nil {
+					c.Close()
+					t.Fatal("cluster.New succeeded, want a handshake error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if _, err := c.PropExist("hotel-1"); err != nil {
+				t.Fatalf("PropExist over TLS: %v", err)
+			}
+		})
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"strings"
 
 	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/internal/batch"
+	"github.com/roomzin/roomzin-go/internal/codecs"
 	"github.com/roomzin/roomzin-go/internal/command"
+	"github.com/roomzin/roomzin-go/internal/protocol"
 	"github.com/roomzin/roomzin-go/internal/single"
 	"github.com/roomzin/roomzin-go/types"
 )
@@ -15,7 +18,7 @@ type client struct {
 	cfg     *Config
 	ctx     context.Context
 	cancel  context.CancelFunc
-	codecs  *types.Codecs
+	codecs  *codecs.Cache
 }
 
 func New(cfg *Config) (api.CacheClientAPI, error) {
@@ -31,6 +34,7 @@ func New(cfg *Config) (api.CacheClientAPI, error) {
 		AuthToken: cfg.AuthToken,
 		Timeout:   cfg.Timeout,
 		KeepAlive: cfg.KeepAlive,
+		TLSConfig: cfg.TLSConfig,
 	}
 
 	singleClient, err := single.NewHandler(icfg, ctx)
@@ -46,29 +50,35 @@ func New(cfg *Config) (api.CacheClientAPI, error) {
 		cancel:  cancel,
 	}
 
-	c.handler.OnReconnect = func() {
-		c.codecs = nil
-	}
+	c.codecs = codecs.NewCache(ctx, c.fetchCodecs, cfg.CodecsMaxAge, cfg.CodecsMaxStale)
+	c.handler.SetOnReconnectCallback(func() {
+		c.codecs.Invalidate()
+	})
 
-	c.codecs, err = c.fetchCodecs()
-	if err != nil {
+	if _, err := c.codecs.Refresh(ctx); err != nil {
+		cancel()
 		return nil, types.RzError(err)
 	}
 
 	return c, nil
 }
 
-func (c *client) getCodecs() *types.Codecs {
-	if c.codecs != nil {
-		return c.codecs
+// getCodecs returns the cached codecs, refreshed within the configured
+// staleness bounds, or a CODECS_UNAVAILABLE error.
+func (c *client) getCodecs(ctx context.Context) (*types.Codecs, error) {
+	codecs, err := c.codecs.Get(ctx)
+	if err != nil {
+		return nil, types.RzError(err)
 	}
-	c.codecs, _ = c.fetchCodecs()
-	return c.codecs
+	return codecs, nil
 }
 
-func (c *client) fetchCodecs() (*types.Codecs, error) {
+func (c *client) fetchCodecs(ctx context.Context) (*types.Codecs, error) {
 	payload, _ := command.BuildGetCodecsPayload()
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
@@ -79,9 +89,74 @@ func (c *client) fetchCodecs() (*types.Codecs, error) {
 	return result, nil
 }
 
+// callCtx bounds a call by the caller's ctx, the client lifetime and, when
+// ctx has no deadline of its own, by cfg.Timeout.
+func (c *client) callCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	var cancel context.CancelFunc
+	if _, ok := ctx.Deadline(); ok {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+	}
+	stop := context.AfterFunc(c.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// idempotencyKey returns the caller's key for this write, or "" when the
+// caller set none, in which case no key field is sent.
+func idempotencyKey(ctx context.Context) string {
+	key, _ := api.IdempotencyKeyFrom(ctx)
+	return key
+}
+
+// setCurrency marks every day's price in avail as being in cur.
+func setCurrency(avail []types.PropertyAvail, cur types.Currency) {
+	for i := range avail {
+		for j := range avail[i].Days {
+			avail[i].Days[j].Currency = cur
+		}
+	}
+}
+
+// today returns the current date for a call on propertyID: in the ctx
+// location if set, else the property's, else the configured Location.
+func (c *client) today(ctx context.Context, propertyID string) types.Date {
+	loc, ok := api.LocationFrom(ctx)
+	if !ok && propertyID != "" && c.cfg.PropertyLocation != nil {
+		loc = c.cfg.PropertyLocation(propertyID)
+	}
+	if loc == nil {
+		loc = c.cfg.Location
+	}
+	return types.Today(c.cfg.Clock, loc)
+}
+
+// dateChecks are the Verify options for a call on propertyID: today for
+// that call and the booking horizon, taken from the config or else from
+// the server's codecs. A ctx from api.WithAnyDate skips both.
+func (c *client) dateChecks(ctx context.Context, propertyID string) []types.VerifyOption {
+	if api.AnyDateFrom(ctx) {
+		return []types.VerifyOption{types.AnyDate()}
+	}
+	horizon := c.cfg.BookingHorizon
+	if horizon == 0 {
+		if codecs, err := c.getCodecs(ctx); err == nil {
+			horizon = codecs.BookingHorizon
+		}
+	}
+	return []types.VerifyOption{types.AsOf(c.today(ctx, propertyID)), types.Horizon(horizon)}
+}
+
+func (c *client) WatchCodecs(fn func(old, new *types.Codecs)) (stop func()) {
+	return c.codecs.Watch(fn)
+}
+
 func (c *client) Close() error {
 	c.cancel()
-	return nil
+	return c.handler.Close()
 }
 
 // --------------------------------------------------
@@ -91,23 +166,141 @@ func (c *client) Close() error {
 // --------------------------------------------------
 
 func (c *client) GetCodecs() (*types.Codecs, error) {
-	if c.codecs != nil {
-		return c.codecs, nil
-	}
-	var err error
-	c.codecs, err = c.fetchCodecs()
+	return c.GetCodecsCtx(c.ctx)
+}
+
+func (c *client) SetProp(p types.SetPropPayload) error {
+	return c.SetPropCtx(c.ctx, p)
+}
+
+func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
+	return c.SearchPropCtx(c.ctx, p)
+}
+
+func (c *client) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	return c.SearchAvailCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomPkg(p types.SetRoomPkgPayload) error {
+	return c.SetRoomPkgCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.SetRoomAvlCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomAvlIf(p types.SetRoomAvlIfPayload) (uint8, error) {
+	return c.SetRoomAvlIfCtx(c.ctx, p)
+}
+
+func (c *client) SetRoomPkgRange(p types.SetRoomPkgRangePayload) ([]types.DateResult, error) {
+	return c.SetRoomPkgRangeCtx(c.ctx, p)
+}
+
+func (c *client) DelRoomRange(p types.DelRoomRangePayload) ([]types.DateResult, error) {
+	return c.DelRoomRangeCtx(c.ctx, p)
+}
+
+func (c *client) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.IncRoomAvlCtx(c.ctx, p)
+}
+
+func (c *client) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
+	return c.DecRoomAvlCtx(c.ctx, p)
+}
+
+func (c *client) PropExist(propertyID string) (bool, error) {
+	return c.PropExistCtx(c.ctx, propertyID)
+}
+
+func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
+	return c.PropRoomExistCtx(c.ctx, p)
+}
+
+func (c *client) PropRoomList(propertyID string) ([]string, error) {
+	return c.PropRoomListCtx(c.ctx, propertyID)
+}
+
+func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]types.Date, error) {
+	return c.PropRoomDateListCtx(c.ctx, p)
+}
+
+func (c *client) DelProp(propertyID string) error {
+	return c.DelPropCtx(c.ctx, propertyID)
+}
+
+func (c *client) DelSegment(segment string) error {
+	return c.DelSegmentCtx(c.ctx, segment)
+}
+
+func (c *client) DelPropDay(p types.DelPropDayRequest) error {
+	return c.DelPropDayCtx(c.ctx, p)
+}
+
+func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
+	return c.DelPropRoomCtx(c.ctx, p)
+}
+
+func (c *client) DelRoomDay(p types.DelRoomDayRequest) error {
+	return c.DelRoomDayCtx(c.ctx, p)
+}
+
+func (c *client) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	return c.GetPropRoomDayCtx(c.ctx, p)
+}
+
+func (c *client) GetSegments() ([]types.SegmentInfo, error) {
+	return c.GetSegmentsCtx(c.ctx)
+}
+
+// NewBatch pipelines writes on the node connection; see api.Batch.
+func (c *client) NewBatch() api.Batch {
+	return c.newBatch(c.ctx)
+}
+
+// newBatch returns a batch whose dates are checked as of today for ctx.
+func (c *client) newBatch(ctx context.Context) *batch.Batch {
+	getCodecs := func() (*types.Codecs, error) { return c.getCodecs(ctx) }
+	checks := func(propertyID string) []types.VerifyOption {
+		return append(c.dateChecks(ctx, propertyID), types.PricesIn(c.cfg.Currencies.ForProperty(propertyID)))
+	}
+	return batch.New(getCodecs, checks, func(ctx context.Context, payloads [][]byte, _ []bool) ([]protocol.RawResult, []error) {
+		ctx, cancel := c.callCtx(ctx)
+		defer cancel()
+		return c.handler.RoundTripBatch(ctx, payloads)
+	})
+}
+
+// --------------------------------------------------
+//
+//	context-aware API
+//
+// --------------------------------------------------
+
+// GetCodecsCtx fetches the server's codecs and refreshes the cached ones,
+// notifying WatchCodecs callbacks if their version changed.
+func (c *client) GetCodecsCtx(ctx context.Context) (*types.Codecs, error) {
+	codecs, err := c.codecs.Refresh(ctx)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
-	return c.codecs, nil
+	return codecs, nil
 }
 
-func (c *client) SetProp(p types.SetPropPayload) error {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SetPropCtx(ctx context.Context, p types.SetPropPayload) error {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := p.Verify(codecs); err != nil {
 		return types.RzError(err)
 	}
 	payload, _ := command.BuildSetPropPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -118,12 +311,19 @@ func (c *client) SetProp(p types.SetPropPayload) error {
 	return nil
 }
 
-func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SearchPropCtx(ctx context.Context, p types.SearchPropPayload) ([]string, error) {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Verify(codecs); err != nil {
 		return nil, types.RzError(err)
 	}
 	payload, _ := command.BuildSearchPropPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
@@ -134,28 +334,44 @@ func (c *client) SearchProp(p types.SearchPropPayload) ([]string, error) {
 	return result, nil
 }
 
-func (c *client) SearchAvail(p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SearchAvailCtx(ctx context.Context, p types.SearchAvailPayload) ([]types.PropertyAvail, error) {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Verify(codecs, append(c.dateChecks(ctx, ""), types.PricesIn(c.cfg.Currencies.For(p.Segment)))...); err != nil {
 		return nil, types.RzError(err)
 	}
 	payload, _ := command.BuildSearchAvailPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
-	result, err := command.ParseSearchAvailResp(c.getCodecs(), res.Status, res.Fields)
+	result, err := command.ParseSearchAvailResp(codecs, p.Date, c.today(ctx, ""), res.Status, res.Fields)
 	if err != nil {
 		return result, types.RzError(err)
 	}
+	setCurrency(result, c.cfg.Currencies.For(p.Segment))
 	return result, nil
 }
 
-func (c *client) SetRoomPkg(p types.SetRoomPkgPayload) error {
-	if err := p.Verify(c.getCodecs()); err != nil {
+func (c *client) SetRoomPkgCtx(ctx context.Context, p types.SetRoomPkgPayload) error {
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := p.Verify(codecs, append(c.dateChecks(ctx, p.PropertyID), types.PricesIn(c.cfg.Currencies.ForProperty(p.PropertyID)))...); err != nil {
 		return types.RzError(err)
 	}
 	payload, _ := command.BuildSetRoomPkgPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -166,12 +382,16 @@ func (c *client) SetRoomPkg(p types.SetRoomPkgPayload) error {
 	return nil
 }
 
-func (c *client) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) SetRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return 0, types.RzError(err)
 	}
 	payload, _ := command.BuildSetRoomAvlPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
@@ -182,12 +402,64 @@ func (c *client) SetRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
 	return result, nil
 }
 
-func (c *client) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) SetRoomPkgRangeCtx(ctx context.Context, p types.SetRoomPkgRangePayload) ([]types.DateResult, error) {
+	days, err := p.Days()
+	if err != nil {
+		return nil, types.RzError(err)
+	}
+	b := c.newBatch(ctx)
+	dates := make([]types.Date, len(days))
+	for i, d := range days {
+		b.SetRoomPkg(d)
+		dates[i] = d.Date
+	}
+	return b.ExecDates(ctx, dates)
+}
+
+func (c *client) DelRoomRangeCtx(ctx context.Context, p types.DelRoomRangePayload) ([]types.DateResult, error) {
+	days, err := p.Days()
+	if err != nil {
+		return nil, types.RzError(err)
+	}
+	b := c.newBatch(ctx)
+	dates := make([]types.Date, len(days))
+	for i, d := range days {
+		b.DelRoomDay(d)
+		dates[i] = d.Date
+	}
+	return b.ExecDates(ctx, dates)
+}
+
+func (c *client) SetRoomAvlIfCtx(ctx context.Context, p types.SetRoomAvlIfPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return 0, types.RzError(err)
+	}
+	payload, _ := command.BuildSetRoomAvlIfPayload(p)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
+	if err != nil {
+		return 0, types.RzError(err)
+	}
+	result, err := command.ParseSetRoomAvlIfResp(res.Status, res.Fields)
+	if err != nil {
+		return result, types.RzError(err)
+	}
+	return result, nil
+}
+
+func (c *client) IncRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return 0, types.RzError(err)
 	}
 	payload, _ := command.BuildIncRoomAvlPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
@@ -198,12 +470,16 @@ func (c *client) IncRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
 	return result, nil
 }
 
-func (c *client) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) DecRoomAvlCtx(ctx context.Context, p types.UpdRoomAvlPayload) (uint8, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return 0, types.RzError(err)
 	}
 	payload, _ := command.BuildDecRoomAvlPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return 0, types.RzError(err)
 	}
@@ -214,12 +490,15 @@ func (c *client) DecRoomAvl(p types.UpdRoomAvlPayload) (uint8, error) {
 	return result, nil
 }
 
-func (c *client) PropExist(propertyID string) (bool, error) {
+func (c *client) PropExistCtx(ctx context.Context, propertyID string) (bool, error) {
 	if strings.TrimSpace(propertyID) == "" {
 		return false, types.RzError("VALIDATION_ERROR: propertyID is required")
 	}
 	payload, _ := command.BuildPropExistPayload(propertyID)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return false, types.RzError(err)
 	}
@@ -230,12 +509,15 @@ func (c *client) PropExist(propertyID string) (bool, error) {
 	return result, nil
 }
 
-func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
+func (c *client) PropRoomExistCtx(ctx context.Context, p types.PropRoomExistPayload) (bool, error) {
 	if err := p.Verify(); err != nil {
 		return false, types.RzError(err)
 	}
 	payload, _ := command.BuildPropRoomExistPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return false, types.RzError(err)
 	}
@@ -246,12 +528,15 @@ func (c *client) PropRoomExist(p types.PropRoomExistPayload) (bool, error) {
 	return result, nil
 }
 
-func (c *client) PropRoomList(propertyID string) ([]string, error) {
+func (c *client) PropRoomListCtx(ctx context.Context, propertyID string) ([]string, error) {
 	if strings.TrimSpace(propertyID) == "" {
 		return nil, types.RzError("VALIDATION_ERROR: propertyID is required")
 	}
 	payload, _ := command.BuildPropRoomListPayload(propertyID)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
@@ -262,12 +547,15 @@ func (c *client) PropRoomList(propertyID string) ([]string, error) {
 	return result, nil
 }
 
-func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]string, error) {
+func (c *client) PropRoomDateListCtx(ctx context.Context, p types.PropRoomDateListPayload) ([]types.Date, error) {
 	if err := p.Verify(); err != nil {
 		return nil, types.RzError(err)
 	}
 	payload, _ := command.BuildPropRoomDateListPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
@@ -278,12 +566,16 @@ func (c *client) PropRoomDateList(p types.PropRoomDateListPayload) ([]string, er
 	return result, nil
 }
 
-func (c *client) DelProp(propertyID string) error {
+func (c *client) DelPropCtx(ctx context.Context, propertyID string) error {
 	if strings.TrimSpace(propertyID) == "" {
 		return types.RzError("VALIDATION_ERROR: propertyID is required")
 	}
 	payload, _ := command.BuildDelPropPayload(propertyID)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -294,12 +586,16 @@ func (c *client) DelProp(propertyID string) error {
 	return nil
 }
 
-func (c *client) DelSegment(segment string) error {
+func (c *client) DelSegmentCtx(ctx context.Context, segment string) error {
 	if strings.TrimSpace(segment) == "" {
 		return types.RzError("VALIDATION_ERROR: segment is required")
 	}
 	payload, _ := command.BuildDelSegmentPayload(segment)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -310,12 +606,16 @@ func (c *client) DelSegment(segment string) error {
 	return nil
 }
 
-func (c *client) DelPropDay(p types.DelPropDayRequest) error {
-	if err := p.Verify(); err != nil {
+func (c *client) DelPropDayCtx(ctx context.Context, p types.DelPropDayRequest) error {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return types.RzError(err)
 	}
 	payload, _ := command.BuildDelPropDayPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -326,12 +626,16 @@ func (c *client) DelPropDay(p types.DelPropDayRequest) error {
 	return nil
 }
 
-func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
+func (c *client) DelPropRoomCtx(ctx context.Context, p types.DelPropRoomPayload) error {
 	if err := p.Verify(); err != nil {
 		return types.RzError(err)
 	}
 	payload, _ := command.BuildDelPropRoomPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -342,12 +646,16 @@ func (c *client) DelPropRoom(p types.DelPropRoomPayload) error {
 	return nil
 }
 
-func (c *client) DelRoomDay(p types.DelRoomDayRequest) error {
-	if err := p.Verify(); err != nil {
+func (c *client) DelRoomDayCtx(ctx context.Context, p types.DelRoomDayRequest) error {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
 		return types.RzError(err)
 	}
 	payload, _ := command.BuildDelRoomDayPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	payload = command.AppendIdempotencyKey(payload, idempotencyKey(ctx))
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.RzError(err)
 	}
@@ -358,25 +666,36 @@ func (c *client) DelRoomDay(p types.DelRoomDayRequest) error {
 	return nil
 }
 
-func (c *client) GetPropRoomDay(p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
-	if err := p.Verify(); err != nil {
+func (c *client) GetPropRoomDayCtx(ctx context.Context, p types.GetRoomDayRequest) (types.GetRoomDayResult, error) {
+	if err := p.Verify(c.dateChecks(ctx, p.PropertyID)...); err != nil {
+		return types.GetRoomDayResult{}, err
+	}
+	codecs, err := c.getCodecs(ctx)
+	if err != nil {
 		return types.GetRoomDayResult{}, err
 	}
 	payload, _ := command.BuildGetPropRoomDayPayload(p)
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return types.GetRoomDayResult{}, err
 	}
-	result, err := command.ParseGetPropRoomDayResp(c.getCodecs(), res.Status, res.Fields)
+	result, err := command.ParseGetPropRoomDayResp(codecs, res.Status, res.Fields)
 	if err != nil {
 		return result, types.RzError(err)
 	}
+	result.Currency = c.cfg.Currencies.ForProperty(p.PropertyID)
 	return result, nil
 }
 
-func (c *client) GetSegments() ([]types.SegmentInfo, error) {
+func (c *client) GetSegmentsCtx(ctx context.Context) ([]types.SegmentInfo, error) {
 	payload, _ := command.BuildGetSegmentsPayload()
-	res, err := c.handler.RoundTrip(c.handler.NextID(), payload)
+	ctx, cancel := c.callCtx(ctx)
+	defer cancel()
+
+	res, err := c.handler.RoundTrip(ctx, payload)
 	if err != nil {
 		return nil, types.RzError(err)
 	}
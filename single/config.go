@@ -1,7 +1,9 @@
 package single
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -14,6 +16,31 @@ type Config struct {
 	AuthToken string
 	Timeout   time.Duration
 	KeepAlive time.Duration
+	TLSConfig *tls.Config // nil means plaintext TCP
+
+	// Clock tells the time for date checks; nil means types.SystemClock.
+	Clock types.Clock
+	// Location is the zone "today" is taken in; nil means UTC.
+	Location *time.Location
+	// PropertyLocation, when set, returns the zone of a property, so dates
+	// are checked against the property's local day. A nil result falls
+	// back to Location.
+	PropertyLocation func(propertyID string) *time.Location
+	// BookingHorizon is how many days past today dates may be. Zero
+	// means the horizon the server advertises in GETCODECS, or
+	// types.DefaultBookingHorizon when it advertises none.
+	BookingHorizon int
+
+	// Currencies says which currency FinalPrice values are in. Prices
+	// set with SetPrice must be in the call's currency, and results carry
+	// it. The zero value leaves prices as bare minor units.
+	Currencies types.Currencies
+
+	// CodecsMaxAge is how long fetched codecs are used before they are
+	// refreshed in the background, and CodecsMaxStale how long they may
+	// still be used while refreshing fails. Zero means 5 and 30 minutes.
+	CodecsMaxAge   time.Duration
+	CodecsMaxStale time.Duration
 }
 
 type ConfigBuilder struct {
@@ -54,6 +81,55 @@ func (b *ConfigBuilder) WithKeepAlive(d time.Duration) *ConfigBuilder {
 	return b
 }
 
+// WithTLSConfig enables TLS on the TCP connection. Set RootCAs for a
+// private CA and Certificates for client-certificate (mutual TLS) auth.
+func (b *ConfigBuilder) WithTLSConfig(cfg *tls.Config) *ConfigBuilder {
+	b.config.TLSConfig = cfg
+	return b
+}
+
+// WithClock sets the clock date checks use; tests pin "today" with it.
+func (b *ConfigBuilder) WithClock(clock types.Clock) *ConfigBuilder {
+	b.config.Clock = clock
+	return b
+}
+
+// WithLocation sets the time zone "today" is taken in for date checks.
+func (b *ConfigBuilder) WithLocation(loc *time.Location) *ConfigBuilder {
+	b.config.Location = loc
+	return b
+}
+
+// WithPropertyLocation looks up each property's time zone for date
+// checks, overriding WithLocation where it returns non-nil.
+func (b *ConfigBuilder) WithPropertyLocation(fn func(propertyID string) *time.Location) *ConfigBuilder {
+	b.config.PropertyLocation = fn
+	return b
+}
+
+// WithBookingHorizon sets how many days ahead dates may be, overriding
+// the horizon the server advertises.
+func (b *ConfigBuilder) WithBookingHorizon(days int) *ConfigBuilder {
+	b.config.BookingHorizon = days
+	return b
+}
+
+// WithCurrencies sets the currency of FinalPrice values, per segment or
+// for the whole client.
+func (b *ConfigBuilder) WithCurrencies(c types.Currencies) *ConfigBuilder {
+	b.config.Currencies = c
+	return b
+}
+
+// WithCodecsStaleness bounds how old the cached codecs may get: past
+// maxAge they are refreshed, and past maxStale a call waits for the
+// refresh and fails if it does.
+func (b *ConfigBuilder) WithCodecsStaleness(maxAge, maxStale time.Duration) *ConfigBuilder {
+	b.config.CodecsMaxAge = maxAge
+	b.config.CodecsMaxStale = maxStale
+	return b
+}
+
 func (b *ConfigBuilder) Build() (Config, error) {
 	if err := b.validate(); err != nil {
 		return Config{}, types.RzError(err, types.KindClient)
@@ -72,6 +148,9 @@ func (b *ConfigBuilder) validate() error {
 	if b.config.AuthToken == "" {
 		errs = append(errs, errors.New("authentication requires a token"))
 	}
+	if b.config.BookingHorizon < 0 || b.config.BookingHorizon > types.MaxBookingHorizon {
+		errs = append(errs, fmt.Errorf("booking horizon must be between 0 and %d days", types.MaxBookingHorizon))
+	}
 	if len(errs) == 0 {
 		return nil
 	}
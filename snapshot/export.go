@@ -0,0 +1,159 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// DefaultConcurrency is the number of reads in flight when
+// ExportOptions.Concurrency is zero.
+const DefaultConcurrency = 8
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	Concurrency int // GetPropRoomDay calls in flight; default DefaultConcurrency
+}
+
+// Export walks segment with SearchProp, PropRoomList, PropRoomDateList and
+// GetPropRoomDay and writes it to out as a snapshot. It returns the counts
+// written, which match the trailer on success.
+//
+// The walk is not atomic: writes landing during the export may or may not
+// be included. Properties, rooms and days deleted mid-walk are skipped.
+// Days are read whatever their date, so stored days that have fallen into
+// the past or lie beyond the booking horizon are exported as well.
+func Export(ctx context.Context, c api.CacheClientAPIContext, segment string, out io.Writer, opts ExportOptions) (Trailer, error) {
+	w := NewWriter(out)
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	codecs, err := c.GetCodecsCtx(ctx)
+	if err != nil {
+		return w.Counts(), err
+	}
+	ids, err := c.SearchPropCtx(ctx, types.SearchPropPayload{Segment: segment})
+	if err != nil {
+		return w.Counts(), err
+	}
+	slices.Sort(ids)
+
+	if err := w.WriteHeader(Header{Segment: segment, TakenAt: time.Now().UTC(), RateFeatures: codecs.RateFeatures}); err != nil {
+		return w.Counts(), err
+	}
+	for _, id := range ids {
+		if err := exportProperty(ctx, c, id, w, opts.Concurrency); err != nil {
+			return w.Counts(), err
+		}
+	}
+	return w.Counts(), w.Close()
+}
+
+func exportProperty(ctx context.Context, c api.CacheClientAPIContext, id string, w *Writer, concurrency int) error {
+	rooms, err := c.PropRoomListCtx(ctx, id)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slices.Sort(rooms)
+
+	// Read every room's days before writing, so the property line lists
+	// only rooms that still exist.
+	var (
+		kept []string
+		days [][]RoomDay
+	)
+	for _, room := range rooms {
+		d, err := roomDays(ctx, c, id, room, concurrency)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		kept = append(kept, room)
+		days = append(days, d)
+	}
+
+	if err := w.WriteProperty(Property{PropertyID: id, RoomTypes: kept}); err != nil {
+		return err
+	}
+	for _, room := range days {
+		for _, d := range room {
+			if err := w.WriteRoomDay(d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// roomDays reads every stored day of one room, concurrently but returned
+// in date order.
+func roomDays(ctx context.Context, c api.CacheClientAPIContext, id, room string, concurrency int) ([]RoomDay, error) {
+	ctx = api.WithAnyDate(ctx)
+	dates, err := c.PropRoomDateListCtx(ctx, types.PropRoomDateListPayload{PropertyID: id, RoomType: room})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(dates, types.Date.Compare)
+
+	var (
+		out   = make([]RoomDay, len(dates))
+		found = make([]bool, len(dates))
+		errs  = make([]error, len(dates))
+		sem   = make(chan struct{}, concurrency)
+		wg    sync.WaitGroup
+	)
+	for i, date := range dates {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res, err := c.GetPropRoomDayCtx(ctx, types.GetRoomDayRequest{PropertyID: id, RoomType: room, Date: date})
+			if isNotFound(err) {
+				return
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			found[i] = true
+			out[i] = RoomDay{
+				PropertyID:   id,
+				RoomType:     room,
+				Date:         date,
+				Availability: res.Availability,
+				FinalPrice:   res.FinalPrice,
+				RateFeatures: res.RateFeature,
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	kept := out[:0]
+	for i, d := range out {
+		if found[i] {
+			kept = append(kept, d)
+		}
+	}
+	return kept, nil
+}
+
+func isNotFound(err error) bool {
+	var rz *types.RoomzinError
+	return errors.As(err, &rz) && rz.Code == "NOT_FOUND"
+}
@@ -0,0 +1,126 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxLineSize bounds one snapshot line.
+const maxLineSize = 1 << 20
+
+// ErrTruncated reports a snapshot that ends without its trailer.
+var ErrTruncated = errors.New("snapshot: no trailer, the file is incomplete")
+
+// Reader reads the records of a snapshot in file order.
+type Reader struct {
+	sc     *bufio.Scanner
+	line   int
+	header Header
+	seen   Trailer
+	done   bool
+}
+
+// NewReader reads the header and returns a Reader positioned on the first
+// property.
+func NewReader(r io.Reader) (*Reader, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxLineSize)
+	rd := &Reader{sc: sc}
+	typ, data, err := rd.scan()
+	if err == io.EOF {
+		return nil, errors.New("snapshot: empty input")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if typ != TypeHeader {
+		return nil, fmt.Errorf("snapshot: line %d: expected header, got %q", rd.line, typ)
+	}
+	if err := json.Unmarshal(data, &rd.header); err != nil {
+		return nil, rd.lineErr(err)
+	}
+	if rd.header.Version < 1 || rd.header.Version > Version {
+		return nil, fmt.Errorf("snapshot: unsupported version %d", rd.header.Version)
+	}
+	return rd, nil
+}
+
+// Header returns the snapshot header.
+func (r *Reader) Header() Header { return r.header }
+
+// Line returns the 1-based line of the record last returned by Next.
+func (r *Reader) Line() int { return r.line }
+
+// Next returns the next Property or RoomDay. It returns io.EOF after the
+// trailer, once the trailer counts have been checked against the records
+// read, and ErrTruncated if the input ends first.
+func (r *Reader) Next() (any, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	typ, data, err := r.scan()
+	if err == io.EOF {
+		return nil, ErrTruncated
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case TypeProperty:
+		var p Property
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, r.lineErr(err)
+		}
+		r.seen.Properties++
+		r.seen.RoomTypes += len(p.RoomTypes)
+		return p, nil
+	case TypeRoomDay:
+		var d RoomDay
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, r.lineErr(err)
+		}
+		r.seen.RoomDays++
+		return d, nil
+	case TypeTrailer:
+		var t Trailer
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, r.lineErr(err)
+		}
+		if t != r.seen {
+			return nil, fmt.Errorf("snapshot: trailer counts %+v do not match records read %+v", t, r.seen)
+		}
+		r.done = true
+		return nil, io.EOF
+	}
+	return nil, fmt.Errorf("snapshot: line %d: unknown record type %q", r.line, typ)
+}
+
+// scan reads the next non-blank line and its type.
+func (r *Reader) scan() (string, []byte, error) {
+	for r.sc.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var tag struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &tag); err != nil {
+			return "", nil, r.lineErr(err)
+		}
+		return tag.Type, data, nil
+	}
+	if err := r.sc.Err(); err != nil {
+		return "", nil, err
+	}
+	return "", nil, io.EOF
+}
+
+func (r *Reader) lineErr(err error) error {
+	return fmt.Errorf("snapshot: line %d: %w", r.line, err)
+}
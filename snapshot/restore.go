@@ -0,0 +1,274 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/importer"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// ErrMissingProperties reports a restore whose target lacks properties of
+// the snapshot, so their days could not be written. Snapshots carry no
+// property attributes; create the properties with SetProp, e.g. with the
+// importer, or set RestoreOptions.AllowMissing.
+var ErrMissingProperties = errors.New("snapshot: properties missing from the target")
+
+// DefaultBatchSize is the number of writes pipelined per batch when
+// RestoreOptions.BatchSize is zero.
+const DefaultBatchSize = 256
+
+// RestoreOptions configures Restore and Copy.
+type RestoreOptions struct {
+	BatchSize int // SetRoomPkg calls per pipelined batch; default DefaultBatchSize
+
+	// FeatureMap renames source rate features to target ones, for codecs
+	// that name the same feature differently. Features are otherwise
+	// matched by name, never by bit position.
+	FeatureMap map[string]string
+	// DropUnknownFeatures removes rate features the target codecs lack
+	// instead of rejecting the day.
+	DropUnknownFeatures bool
+	// AllowMissing lets a restore into a target lacking some of the
+	// snapshot's properties succeed, with those properties counted as
+	// Missing and their days as Skipped. By default it fails with
+	// ErrMissingProperties.
+	AllowMissing bool
+
+	// Rejects receives one importer.Reject per failed record, as JSON
+	// lines keyed by snapshot line. Optional.
+	Rejects io.Writer
+}
+
+// RestoreStats counts the records of a restore.
+type RestoreStats struct {
+	Properties int `json:"properties"` // found in the target and restored
+	Missing    int `json:"missing"`    // properties absent from the target
+	RoomDays   int `json:"room_days"`  // room days written
+	Skipped    int `json:"skipped"`    // room days of missing properties
+	Rejected   int `json:"rejected"`   // room days that failed
+}
+
+// Restore replays a snapshot into dst with SetRoomPkg, pipelined in
+// batches. Each day's availability, price and rate features are written
+// as stored, so features are cleared where the snapshot has none; days
+// present only in dst are left alone.
+//
+// Snapshots do not carry property attributes, so properties must already
+// exist in dst, e.g. loaded with the importer. A missing property is
+// rejected once and its days are skipped; the other properties are still
+// restored, after which Restore returns ErrMissingProperties unless
+// opts.AllowMissing is set.
+//
+// Failed room days are counted and logged to opts.Rejects. Beyond missing
+// properties, the returned error is reserved for an unreadable snapshot, a
+// rate feature mapping that names a feature dst lacks, a failed reject
+// write or ctx ending.
+// Records are written as they are read, so a snapshot cut short is
+// applied up to where it ends before ErrTruncated is returned.
+func Restore(ctx context.Context, dst api.CacheClientAPI, r io.Reader, opts RestoreOptions) (RestoreStats, error) {
+	var stats RestoreStats
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	rd, err := NewReader(r)
+	if err != nil {
+		return stats, err
+	}
+	codecs, err := dst.GetCodecsCtx(ctx)
+	if err != nil {
+		return stats, err
+	}
+	remap, err := newFeatureMap(codecs.RateFeatures, opts)
+	if err != nil {
+		return stats, err
+	}
+
+	rej := &rejectWriter{w: opts.Rejects}
+	var (
+		batch = dst.NewBatch()
+		lines []int // snapshot line of each queued day
+		skip  bool  // the current property is missing from dst
+	)
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		results, _ := batch.Exec(ctx)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for i, res := range results {
+			if res.Err != nil {
+				stats.Rejected++
+				rej.add(lines[i], res.Err)
+				continue
+			}
+			stats.RoomDays++
+		}
+		lines = lines[:0]
+		return rej.err
+	}
+
+	for {
+		rec, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		switch rec := rec.(type) {
+		case Property:
+			exists, err := dst.PropExistCtx(ctx, rec.PropertyID)
+			if err != nil {
+				return stats, err
+			}
+			skip = !exists
+			if skip {
+				stats.Missing++
+				rej.add(rd.Line(), types.RzError(fmt.Sprintf("NOT_FOUND: property %s does not exist in the target, create it with SetProp first", rec.PropertyID)))
+				continue
+			}
+			stats.Properties++
+		case RoomDay:
+			if skip {
+				stats.Skipped++
+				continue
+			}
+			features, err := remap.apply(rec.RateFeatures)
+			if err != nil {
+				stats.Rejected++
+				rej.add(rd.Line(), err)
+				continue
+			}
+			avl, price := rec.Availability, rec.FinalPrice
+			batch.SetRoomPkg(types.SetRoomPkgPayload{
+				PropertyID:   rec.PropertyID,
+				RoomType:     rec.RoomType,
+				Date:         rec.Date,
+				Availability: &avl,
+				FinalPrice:   &price,
+				RateFeature:  features,
+			})
+			lines = append(lines, rd.Line())
+			if batch.Len() >= opts.BatchSize {
+				if err := flush(); err != nil {
+					return stats, err
+				}
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	if rej.err != nil {
+		return stats, rej.err
+	}
+	if stats.Missing > 0 && !opts.AllowMissing {
+		return stats, fmt.Errorf("%w: %d of %d, %d room days skipped", ErrMissingProperties, stats.Missing, stats.Missing+stats.Properties, stats.Skipped)
+	}
+	return stats, nil
+}
+
+// CopyOptions configures Copy.
+type CopyOptions struct {
+	ExportOptions
+	RestoreOptions
+}
+
+// Copy exports segment from src and restores it into dst as it is read,
+// without an intermediate file. Rate features are remapped by name as in
+// Restore, so clusters whose codecs order features differently copy
+// correctly. Properties are not copied: they must exist in dst, as for
+// Restore.
+func Copy(ctx context.Context, src api.CacheClientAPIContext, dst api.CacheClientAPI, segment string, opts CopyOptions) (RestoreStats, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	exported := make(chan error, 1)
+	go func() {
+		_, err := Export(ctx, src, segment, pw, opts.ExportOptions)
+		pw.CloseWithError(err)
+		exported <- err
+	}()
+
+	stats, err := Restore(ctx, dst, pr, opts.RestoreOptions)
+	if err != nil {
+		// Unblock an export still writing into the pipe.
+		cancel()
+		pr.CloseWithError(err)
+	}
+	if exportErr := <-exported; exportErr != nil && err == nil {
+		err = exportErr
+	}
+	return stats, err
+}
+
+// featureMap translates snapshot rate feature names to target names.
+type featureMap struct {
+	rename map[string]string
+	target []string
+	drop   bool
+}
+
+// newFeatureMap checks opts.FeatureMap against the target codecs. Source
+// features the target lacks are not an error here: only days that use
+// them are rejected, or have them dropped.
+func newFeatureMap(target []string, opts RestoreOptions) (*featureMap, error) {
+	for from, to := range opts.FeatureMap {
+		if !slices.Contains(target, to) {
+			return nil, types.RzError(fmt.Errorf("snapshot: feature map %s=%s names a rate feature the target codecs lack", from, to), types.KindClient)
+		}
+	}
+	return &featureMap{rename: opts.FeatureMap, target: target, drop: opts.DropUnknownFeatures}, nil
+}
+
+// apply maps features, never returning nil so the write always sets them.
+func (m *featureMap) apply(features []string) ([]string, error) {
+	out := make([]string, 0, len(features))
+	var unknown []string
+	for _, f := range features {
+		if to, ok := m.rename[f]; ok {
+			f = to
+		}
+		switch {
+		case slices.Contains(m.target, f):
+			if !slices.Contains(out, f) {
+				out = append(out, f)
+			}
+		case !m.drop:
+			unknown = append(unknown, f)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, types.RzError("VALIDATION_ERROR: rate features missing from the target codecs: " + strings.Join(unknown, ", "))
+	}
+	return out, nil
+}
+
+// rejectWriter logs failed records in the importer's reject format.
+type rejectWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (r *rejectWriter) add(line int, err error) {
+	if r.w == nil || r.err != nil {
+		return
+	}
+	rz := types.RzError(err)
+	b, merr := json.Marshal(importer.Reject{Line: line, Code: rz.Code, Msg: strings.TrimSpace(rz.Msg)})
+	if merr != nil {
+		r.err = merr
+		return
+	}
+	_, r.err = r.w.Write(append(b, '\n'))
+}
@@ -0,0 +1,140 @@
+// Package snapshot exports a segment to a self-describing JSONL file.
+//
+// A snapshot is one JSON object per line, each tagged with a "type":
+//
+//	{"type":"header","version":1,"segment":"paris","taken_at":"...","rate_features":["breakfast",...]}
+//	{"type":"property","property_id":"hotel-1","room_types":["dbl","sgl"]}
+//	{"type":"room_day","property_id":"hotel-1","room_type":"dbl","date":"2026-11-01","availability":3,"final_price":120,"rate_features":["breakfast"]}
+//	{"type":"trailer","properties":1,"room_types":2,"room_days":1}
+//
+// Properties, room types and dates are sorted, so two snapshots of the same
+// data are byte-identical apart from taken_at and diff cleanly. Each
+// property line is followed by its room days. The trailer marks a complete
+// file; a snapshot without one was cut short.
+//
+// The server has no command to read property attributes back, so property
+// lines carry the ID and room types only: area, type, category, stars,
+// location and amenities must come from the system that created them.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// Version is the snapshot format version written in the header.
+const Version = 1
+
+// Record types, as found in each line's "type" key.
+const (
+	TypeHeader   = "header"
+	TypeProperty = "property"
+	TypeRoomDay  = "room_day"
+	TypeTrailer  = "trailer"
+)
+
+// Header opens a snapshot. RateFeatures are the codecs in effect, in bit
+// order, so rate features can be matched by name on restore.
+type Header struct {
+	Version      int       `json:"version"`
+	Segment      string    `json:"segment"`
+	TakenAt      time.Time `json:"taken_at"`
+	RateFeatures []string  `json:"rate_features"`
+}
+
+// Property is one property of the segment and its room types.
+type Property struct {
+	PropertyID string   `json:"property_id"`
+	RoomTypes  []string `json:"room_types"`
+}
+
+// RoomDay is the stored state of one room type on one date.
+type RoomDay struct {
+	PropertyID   string     `json:"property_id"`
+	RoomType     string     `json:"room_type"`
+	Date         types.Date `json:"date"`
+	Availability uint8      `json:"availability"`
+	FinalPrice   uint32     `json:"final_price"`
+	RateFeatures []string   `json:"rate_features"`
+}
+
+// Trailer closes a snapshot with its record counts.
+type Trailer struct {
+	Properties int `json:"properties"`
+	RoomTypes  int `json:"room_types"`
+	RoomDays   int `json:"room_days"`
+}
+
+// Writer writes snapshot records. Call WriteHeader first and Close last;
+// Close writes the trailer from the counts seen.
+type Writer struct {
+	w     io.Writer
+	count Trailer
+	err   error
+}
+
+// NewWriter returns a Writer on w. Writes are not buffered.
+func NewWriter(w io.Writer) *Writer { return &Writer{w: w} }
+
+// WriteHeader writes h, filling in Version.
+func (w *Writer) WriteHeader(h Header) error {
+	h.Version = Version
+	if h.RateFeatures == nil {
+		h.RateFeatures = []string{}
+	}
+	return w.write(TypeHeader, h)
+}
+
+// WriteProperty writes p; its room days should follow.
+func (w *Writer) WriteProperty(p Property) error {
+	if p.RoomTypes == nil {
+		p.RoomTypes = []string{}
+	}
+	w.count.Properties++
+	w.count.RoomTypes += len(p.RoomTypes)
+	return w.write(TypeProperty, p)
+}
+
+// WriteRoomDay writes d.
+func (w *Writer) WriteRoomDay(d RoomDay) error {
+	if d.RateFeatures == nil {
+		d.RateFeatures = []string{}
+	}
+	w.count.RoomDays++
+	return w.write(TypeRoomDay, d)
+}
+
+// Close writes the trailer. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	return w.write(TypeTrailer, w.count)
+}
+
+// Counts returns what has been written so far.
+func (w *Writer) Counts() Trailer { return w.count }
+
+// write emits {"type":typ, ...v's fields} on one line. The first error
+// sticks, so callers may check only the last write.
+func (w *Writer) write(typ string, v any) error {
+	if w.err != nil {
+		return w.err
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	var line bytes.Buffer
+	fmt.Fprintf(&line, `{"type":%q`, typ)
+	if len(body) > 2 {
+		line.WriteByte(',')
+	}
+	line.Write(body[1:])
+	line.WriteByte('\n')
+	_, w.err = w.w.Write(line.Bytes())
+	return w.err
+}
@@ -0,0 +1,126 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/roomzin/roomzin-go/api"
+	"github.com/roomzin/roomzin-go/roomzintest"
+	"github.com/roomzin/roomzin-go/snapshot"
+	"github.com/roomzin/roomzin-go/types"
+)
+
+// clock is a settable types.Clock.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+var start = time.Date(2030, 5, 17, 12, 0, 0, 0, time.UTC)
+
+// seed creates hotel-1 with a "dbl" room on the given dates, each with
+// availability 3.
+func seed(t *testing.T, c api.CacheClientAPI, dates ...types.Date) {
+	t.Helper()
+	err := c.SetProp(types.SetPropPayload{Segment: "seg", Area: "a", PropertyID: "hotel-1", PropertyType: "hotel", Category: "c", Stars: 3, Amenities: []string{"wifi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avl, price := uint8(3), uint32(12000)
+	for _, d := range dates {
+		err := c.SetRoomPkg(types.SetRoomPkgPayload{PropertyID: "hotel-1", RoomType: "dbl", Date: d, Availability: &avl, FinalPrice: &price, RateFeature: []string{"breakfast"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExportStoredPastDays(t *testing.T) {
+	clk := &clock{now: start}
+	c, err := roomzintest.NewMemClient(roomzintest.Options{Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := types.DateOf(start)
+	seed(t, c, today, today.AddDays(1), today.AddDays(2))
+
+	// Two of the three days are now in the past.
+	clk.now = start.AddDate(0, 0, 2)
+
+	var buf bytes.Buffer
+	got, err := snapshot.Export(context.Background(), c, "seg", &buf, snapshot.ExportOptions{})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if want := (snapshot.Trailer{Properties: 1, RoomTypes: 1, RoomDays: 3}); got != want {
+		t.Fatalf("Export = %+v, want %+v", got, want)
+	}
+
+	r, err := snapshot.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []types.Date
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d, ok := rec.(snapshot.RoomDay); ok {
+			days = append(days, d.Date)
+		}
+	}
+	if len(days) != 3 || days[0] != today {
+		t.Fatalf("exported days = %v", days)
+	}
+}
+
+func TestCopyMissingProperties(t *testing.T) {
+	src, err := roomzintest.NewMemClient(roomzintest.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := types.Today(nil, nil)
+	seed(t, src, today.AddDays(1), today.AddDays(2))
+
+	tests := []struct {
+		name         string
+		allowMissing bool
+		create       bool
+		want         snapshot.RestoreStats
+		wantErr      bool
+	}{
+		{name: "missing", want: snapshot.RestoreStats{Missing: 1, Skipped: 2}, wantErr: true},
+		{name: "missing allowed", allowMissing: true, want: snapshot.RestoreStats{Missing: 1, Skipped: 2}},
+		{name: "created first", create: true, want: snapshot.RestoreStats{Properties: 1, RoomDays: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst, err := roomzintest.NewMemClient(roomzintest.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.create {
+				seed(t, dst)
+			}
+			opts := snapshot.CopyOptions{}
+			opts.AllowMissing = tt.allowMissing
+			got, err := snapshot.Copy(context.Background(), src, dst, "seg", opts)
+			if tt.wantErr != errors.Is(err, snapshot.ErrMissingProperties) {
+				t.Fatalf("Copy error = %v, want ErrMissingProperties: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Copy = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
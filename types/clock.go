@@ -0,0 +1,78 @@
+package types
+
+import "time"
+
+// Clock tells the time. Clients take one so tests can pin "today".
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock, used when no Clock is configured.
+var SystemClock Clock = systemClock{}
+
+// Today returns the current date in loc by clock. A nil clock means
+// SystemClock and a nil loc UTC.
+func Today(clock Clock, loc *time.Location) Date {
+	if clock == nil {
+		clock = SystemClock
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return DateOf(clock.Now().In(loc))
+}
+
+// VerifyOption adjusts how a payload's Verify checks it.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	today    Date
+	horizon  int
+	anyDate  bool
+	currency Currency
+}
+
+// AsOf makes date checks treat today as the current date, instead of the
+// UTC date of the system clock. Clients pass the date of their Clock in
+// the property's time zone, so a hotel can update tonight's inventory
+// whatever the offset.
+func AsOf(today Date) VerifyOption {
+	return func(c *verifyConfig) { c.today = today }
+}
+
+// Horizon sets how many days past today a date may be. Zero or less
+// means DefaultBookingHorizon. Verify methods that take Codecs start from
+// the server's advertised horizon.
+func Horizon(days int) VerifyOption {
+	return func(c *verifyConfig) { c.horizon = days }
+}
+
+// AnyDate drops the today and horizon checks, so dates in the past or
+// beyond the horizon pass as long as they are real. It is for reading or
+// removing days already stored, which may have fallen out of the window.
+func AnyDate() VerifyOption {
+	return func(c *verifyConfig) { c.anyDate = true }
+}
+
+func newVerifyConfig(opts []VerifyOption) verifyConfig {
+	var c verifyConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	if c.today.IsZero() {
+		c.today = Today(nil, nil)
+	}
+	if c.horizon <= 0 {
+		c.horizon = DefaultBookingHorizon
+	}
+	return c
+}
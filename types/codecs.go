@@ -1,26 +1,99 @@
 package types
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"slices"
+	"strconv"
 	"strings"
 )
 
 type Status string
 
+// Codecs are the dictionaries a server encodes values with. RateFeatures
+// is always present; the other dictionaries are empty when the server
+// does not advertise them, and are then not checked by Verify.
 type Codecs struct {
-	RateFeatures []string `yaml:"rate_features"`
+	// Version identifies this set of dictionaries: the server's version
+	// when it sends one, Digest otherwise. Clients compare it to notice
+	// that the server's codecs changed.
+	Version string `yaml:"version"`
+
+	RateFeatures  []string `yaml:"rate_features"`
+	Amenities     []string `yaml:"amenities"`
+	PropertyTypes []string `yaml:"property_types"`
+	Categories    []string `yaml:"categories"`
+
+	// BookingHorizon is how many days past today the server accepts
+	// dates, or 0 when the server does not advertise it.
+	BookingHorizon int `yaml:"booking_horizon"`
+}
+
+// Digest returns a hash of the dictionaries and horizon, ignoring
+// Version, for servers that do not version their codecs.
+func (c *Codecs) Digest() string {
+	h := sha256.New()
+	for _, dict := range [][]string{c.RateFeatures, c.Amenities, c.PropertyTypes, c.Categories} {
+		h.Write([]byte(strings.Join(dict, ",")))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte(strconv.Itoa(c.BookingHorizon)))
+	return hex.EncodeToString(h.Sum(nil)[:8])
 }
 
+// horizon returns the date check option for the advertised horizon, for
+// Verify methods that take codecs. Options passed after it win.
+func (c *Codecs) horizon() VerifyOption {
+	if c == nil {
+		return Horizon(0)
+	}
+	return Horizon(c.BookingHorizon)
+}
+
+// ValidateRateFeatures checks that codecs lists every one of input.
 func ValidateRateFeatures(codecs *Codecs, input []string) error {
+	_, err := NewRateFeatureSet(input...).Mask(codecs)
+	return err
+}
+
+// validateDictionary checks values against a dictionary the server
+// advertised; with none advertised every value passes.
+func validateDictionary(name string, dict []string, values ...string) error {
+	if len(dict) == 0 {
+		return nil
+	}
 	var invalid []string
-	for _, rate := range input {
-		if !slices.Contains(codecs.RateFeatures, rate) {
-			invalid = append(invalid, rate)
+	for _, v := range values {
+		if !slices.Contains(dict, v) {
+			invalid = append(invalid, v)
 		}
 	}
 	if len(invalid) > 0 {
-		return errors.New("Invalid rate features: " + strings.Join(invalid, ", "))
+		return errors.New("Invalid " + name + ": " + strings.Join(invalid, ", "))
 	}
 	return nil
 }
+
+// validateProperty checks a property's type, category and amenities
+// against the codecs; empty values are not checked.
+func (c *Codecs) validateProperty(propertyType, category string, amenities []string) []string {
+	if c == nil {
+		return nil
+	}
+	var errs []string
+	if propertyType != "" {
+		if err := validateDictionary("property type", c.PropertyTypes, propertyType); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if category != "" {
+		if err := validateDictionary("category", c.Categories, category); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if err := validateDictionary("amenities", c.Amenities, amenities...); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
@@ -0,0 +1,191 @@
+package types
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+)
+
+// DateLayout is the wire and text form of a Date.
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day with no time or zone, as used for room days.
+// The zero Date means "no date"; Verify rejects it where one is required.
+// Dates are comparable with == and usable as map keys.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// NewDate returns the date of year, month and day, normalised the way
+// time.Date does: NewDate(2026, 12, 32) is 2027-01-01.
+func NewDate(year int, month time.Month, day int) Date {
+	return DateOf(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// DateOf returns the date t falls on in its own location.
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{y, m, d}
+}
+
+// ParseDate parses a YYYY-MM-DD date.
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil || len(s) != len(DateLayout) {
+		return Date{}, fmt.Errorf("invalid date: %q, expected YYYY-MM-DD", s)
+	}
+	return DateOf(t), nil
+}
+
+// MustParseDate is ParseDate for constants; it panics on a malformed date.
+func MustParseDate(s string) Date {
+	d, err := ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// String returns the date as YYYY-MM-DD, or "" for the zero Date.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// IsZero reports whether d is the zero Date.
+func (d Date) IsZero() bool { return d == Date{} }
+
+// IsValid reports whether d names a real calendar day.
+func (d Date) IsValid() bool {
+	return d.Month >= time.January && d.Month <= time.December && NewDate(d.Year, d.Month, d.Day) == d
+}
+
+// In returns midnight at the start of d in loc.
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// AddDays returns d moved by n days, which may be negative.
+func (d Date) AddDays(n int) Date { return NewDate(d.Year, d.Month, d.Day+n) }
+
+// DaysSince returns the number of days from e to d, negative if d is
+// earlier.
+func (d Date) DaysSince(e Date) int {
+	return int(d.In(time.UTC).Sub(e.In(time.UTC)).Hours() / 24)
+}
+
+// Weekday returns the day of the week of d.
+func (d Date) Weekday() time.Weekday { return d.In(time.UTC).Weekday() }
+
+// Before reports whether d is earlier than e.
+func (d Date) Before(e Date) bool { return d.Compare(e) < 0 }
+
+// After reports whether d is later than e.
+func (d Date) After(e Date) bool { return d.Compare(e) > 0 }
+
+// Compare returns -1, 0 or +1 as d is before, equal to or after e, so
+// slices.SortFunc(dates, Date.Compare) sorts chronologically.
+func (d Date) Compare(e Date) int {
+	if c := cmp.Compare(d.Year, e.Year); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(d.Month, e.Month); c != 0 {
+		return c
+	}
+	return cmp.Compare(d.Day, e.Day)
+}
+
+// MarshalText encodes d as YYYY-MM-DD, and the zero Date as "".
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes YYYY-MM-DD; "" decodes to the zero Date.
+func (d *Date) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*d = Date{}
+		return nil
+	}
+	v, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// DateRange yields every date from from to to, both inclusive. It yields
+// nothing when to is before from.
+func DateRange(from, to Date) iter.Seq[Date] {
+	return func(yield func(Date) bool) {
+		for d := from; !d.After(to); d = d.AddDays(1) {
+			if !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// Packed dates are 16 bits: | yearOffset(3) | month-1(4) | day-1(5) |,
+// the year counted from a base year the server takes from its clock.
+const maxPackedYearOffset = 0b111
+
+// Pack encodes d in the 16-bit wire form, relative to baseYear.
+func (d Date) Pack(baseYear int) (uint16, error) {
+	if !d.IsValid() {
+		return 0, fmt.Errorf("invalid date: %v", d)
+	}
+	off := d.Year - baseYear
+	if off < 0 || off > maxPackedYearOffset {
+		return 0, fmt.Errorf("date %s out of packable range from %d", d, baseYear)
+	}
+	return uint16(off)<<9 | uint16(d.Month-1)<<5 | uint16(d.Day-1), nil
+}
+
+// UnpackDate decodes a 16-bit packed date relative to baseYear.
+func UnpackDate(packed uint16, baseYear int) (Date, error) {
+	off := int(packed>>9) & maxPackedYearOffset
+	d := Date{baseYear + off, time.Month((packed>>5)&0b1111) + 1, int(packed&0b11111) + 1}
+	if !d.IsValid() {
+		return Date{}, errors.New("invalid packed date")
+	}
+	return d, nil
+}
+
+// UnpackDateNear decodes a packed date whose base year is not known
+// exactly, such as one packed by the server around New Year. It returns
+// the date in want with the same month and day, so a reply decodes to the
+// dates that were asked for; if two match, the one whose implied base year
+// is nearest today's wins. With no match it falls back to UnpackDate from
+// today's year.
+func UnpackDateNear(packed uint16, want []Date, today Date) (Date, error) {
+	off := int(packed>>9) & maxPackedYearOffset
+	m, day := time.Month((packed>>5)&0b1111)+1, int(packed&0b11111)+1
+	year := today.Year
+	var (
+		best     Date
+		bestDist = -1
+	)
+	for _, w := range want {
+		if w.Month != m || w.Day != day {
+			continue
+		}
+		dist := w.Year - off - year
+		if dist < 0 {
+			dist = -dist
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = w, dist
+		}
+	}
+	if bestDist >= 0 {
+		return best, nil
+	}
+	return UnpackDate(packed, year)
+}
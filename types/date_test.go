@@ -0,0 +1,177 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Date
+		wantErr bool
+	}{
+		{in: "2030-05-17", want: NewDate(2030, 5, 17)},
+		{in: "2028-02-29", want: NewDate(2028, 2, 29)},
+		{in: "2027-02-29", wantErr: true},
+		{in: "2030-5-17", wantErr: true},
+		{in: "17/05/2030", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDate(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDate(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseDate(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			}
+			if got.String() != tt.in {
+				t.Fatalf("String() = %q, want %q", got.String(), tt.in)
+			}
+		})
+	}
+}
+
+func TestDateArithmetic(t *testing.T) {
+	d := NewDate(2030, 12, 31)
+	if got := d.AddDays(1); got != NewDate(2031, 1, 1) {
+		t.Fatalf("AddDays(1) = %v", got)
+	}
+	if got := NewDate(2030, 12, 32); got != NewDate(2031, 1, 1) {
+		t.Fatalf("NewDate did not normalise: %v", got)
+	}
+	if n := NewDate(2031, 3, 1).DaysSince(NewDate(2031, 2, 1)); n != 28 {
+		t.Fatalf("DaysSince = %d, want 28", n)
+	}
+	if !d.Before(d.AddDays(1)) || !d.After(d.AddDays(-1)) || d.Compare(d) != 0 {
+		t.Fatal("comparison is inconsistent")
+	}
+	if (Date{}).IsValid() || (Date{2030, 2, 30}).IsValid() || !d.IsValid() {
+		t.Fatal("IsValid is wrong")
+	}
+
+	var got []Date
+	for day := range DateRange(d, d.AddDays(2)) {
+		got = append(got, day)
+	}
+	if want := []Date{d, d.AddDays(1), d.AddDays(2)}; !slices.Equal(got, want) {
+		t.Fatalf("DateRange = %v, want %v", got, want)
+	}
+	for range DateRange(d, d.AddDays(-1)) {
+		t.Fatal("DateRange yielded for a reversed range")
+	}
+}
+
+func TestDateJSON(t *testing.T) {
+	type doc struct {
+		Date Date `json:"date"`
+	}
+	for _, in := range []doc{{NewDate(2030, 5, 17)}, {}} {
+		b, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out doc
+		if err := json.Unmarshal(b, &out); err != nil || out != in {
+			t.Fatalf("%s decoded to %v, %v", b, out, err)
+		}
+	}
+	var out doc
+	if err := json.Unmarshal([]byte(`{"date":"2030-02-30"}`), &out); err == nil {
+		t.Fatal("decoded an impossible date")
+	}
+}
+
+func TestPackDate(t *testing.T) {
+	tests := []struct {
+		date    Date
+		base    int
+		wantErr bool
+	}{
+		{date: NewDate(2030, 1, 1), base: 2030},
+		{date: NewDate(2030, 12, 31), base: 2030},
+		{date: NewDate(2037, 2, 28), base: 2030},
+		{date: NewDate(2038, 1, 1), base: 2030, wantErr: true},
+		{date: NewDate(2029, 12, 31), base: 2030, wantErr: true},
+		{date: Date{}, base: 2030, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date.String(), func(t *testing.T) {
+			packed, err := tt.date.Pack(tt.base)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Pack = %#x, want an error", packed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := UnpackDate(packed, tt.base)
+			if err != nil || got != tt.date {
+				t.Fatalf("UnpackDate(%#x) = %v, %v; want %v", packed, got, err, tt.date)
+			}
+		})
+	}
+
+	// month 13 does not exist
+	if _, err := UnpackDate(12<<5, 2030); err == nil {
+		t.Fatal("unpacked an impossible month")
+	}
+}
+
+func TestUnpackDateNear(t *testing.T) {
+	dec31, jan1 := NewDate(2030, 12, 31), NewDate(2031, 1, 1)
+
+	tests := []struct {
+		name   string
+		packed Date // packed relative to base
+		base   int
+		want   []Date
+		today  Date
+		expect Date
+	}{
+		{
+			name:   "asked date, server a year ahead",
+			packed: jan1, base: 2031,
+			want: []Date{dec31, jan1}, today: dec31,
+			expect: jan1,
+		},
+		{
+			name:   "asked date, same base",
+			packed: dec31, base: 2030,
+			want: []Date{dec31, jan1}, today: dec31,
+			expect: dec31,
+		},
+		{
+			name:   "nearest of two years",
+			packed: NewDate(2031, 3, 1), base: 2031,
+			want: []Date{NewDate(2031, 3, 1), NewDate(2034, 3, 1)}, today: dec31,
+			expect: NewDate(2031, 3, 1),
+		},
+		{
+			name:   "not asked, today's year",
+			packed: NewDate(2030, 6, 1), base: 2030,
+			want: nil, today: dec31,
+			expect: NewDate(2030, 6, 1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packed, err := tt.packed.Pack(tt.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := UnpackDateNear(packed, tt.want, tt.today)
+			if err != nil || got != tt.expect {
+				t.Fatalf("UnpackDateNear = %v, %v; want %v", got, err, tt.expect)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ const (
 	KindRequest                   // validation, not-found, overflow …
 	KindInternal                  // bug, parse failure, protocol mismatch
 	KindRetry                     // 429, 503, 308, role change …
+	KindConflict                  // conditional write lost a race
 )
 
 // RoomzinError satisfies error and gives access to the kind.
@@ -22,16 +23,34 @@ type RoomzinError struct {
 	Kind ErrorKind
 	Code string // original code, e.g. "AUTH_ERROR"
 	Msg  string // human message without prefix
+	Err  error  // the SDK error it was made from, if any
 }
 
 func (e *RoomzinError) Error() string { return fmt.Sprintf("%s:%s", e.Code, e.Msg) }
 
+// Unwrap returns the error e was made from, so errors.Is still finds
+// causes such as context.Canceled.
+func (e *RoomzinError) Unwrap() error { return e.Err }
+
 // ---------- helpers for users ----------
 
 func IsClient(err error) bool   { return isKind(err, KindClient) }
 func IsRequest(err error) bool  { return isKind(err, KindRequest) }
 func IsInternal(err error) bool { return isKind(err, KindInternal) }
 func IsCluster(err error) bool  { return isKind(err, KindRetry) }
+func IsConflict(err error) bool { return isKind(err, KindConflict) }
+
+// ErrCodecsUnavailable matches, with errors.Is, the error of a call that
+// needed the server's codecs when the client had none recent enough and
+// could not fetch them. It is a KindRetry error.
+var ErrCodecsUnavailable = &RoomzinError{Kind: KindRetry, Code: "CODECS_UNAVAILABLE", Msg: "codecs unavailable"}
+
+// ErrOutcomeUnknown matches, with errors.Is, the error of a write that
+// must not be applied twice, such as DecRoomAvl, when the connection
+// dropped before its reply and the write carried no idempotency key to
+// resend it safely with. The write may or may not have been applied. It
+// is a KindRetry error.
+var ErrOutcomeUnknown = &RoomzinError{Kind: KindRetry, Code: "OUTCOME_UNKNOWN", Msg: "connection lost before the reply; the write may have been applied"}
 
 // errors.Is support
 func (e *RoomzinError) Is(target error) bool {
@@ -61,10 +80,12 @@ func RzError(in any, kind ...ErrorKind) *RoomzinError {
 
 	// 2. explicit bucket?
 	if len(kind) > 0 {
+		cause, _ := in.(error)
 		return &RoomzinError{
 			Kind: kind[0],
 			Code: codeOf(kind[0]),
 			Msg:  extractMsg(in),
+			Err:  cause,
 		}
 	}
 
@@ -72,7 +93,9 @@ func RzError(in any, kind ...ErrorKind) *RoomzinError {
 	if err, ok := in.(error); ok {
 		s := err.Error()
 		code, msg, _ := strings.Cut(s, ":")
-		return classify(code, msg)
+		e := classify(code, msg)
+		e.Err = err
+		return e
 	}
 
 	// 3. server string?
@@ -100,6 +123,8 @@ func codeOf(k ErrorKind) string {
 		return "REQUEST_ERROR"
 	case KindRetry:
 		return "RETRY_ERROR"
+	case KindConflict:
+		return "CONFLICT"
 	default:
 		return "INTERNAL_ERROR"
 	}
@@ -123,8 +148,10 @@ func classify(code, msg string) *RoomzinError {
 		return &RoomzinError{Kind: KindClient, Code: code, Msg: msg}
 	case "VALIDATION_ERROR", "NOT_FOUND", "OVERFLOW", "UNDERFLOW", "FORBIDDEN":
 		return &RoomzinError{Kind: KindRequest, Code: code, Msg: msg}
-	case "503", "429", "308", "405":
+	case "503", "429", "308", "405", "CODECS_UNAVAILABLE", "OUTCOME_UNKNOWN":
 		return &RoomzinError{Kind: KindRetry, Code: code, Msg: msg}
+	case "CONFLICT":
+		return &RoomzinError{Kind: KindConflict, Code: code, Msg: msg}
 	default:
 		// "PARSE_ERROR" , "RESPONSE_ERROR"
 		return &RoomzinError{Kind: KindInternal, Code: code, Msg: msg}
@@ -0,0 +1,247 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency: its alphabetic code and the number of
+// decimal places of its minor unit.
+type Currency struct {
+	Code       string // e.g. "EUR"
+	MinorUnits int    // 2 for EUR, 0 for JPY, 3 for KWD
+}
+
+// minorUnits lists the ISO 4217 currencies whose minor unit is not a
+// hundredth.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// ParseCurrency returns the currency with the given ISO 4217 code. Codes
+// are matched case-insensitively, and any three-letter code not known to
+// have another minor unit is taken to have two decimal places.
+func ParseCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return Currency{}, fmt.Errorf("invalid currency code: %q", code)
+	}
+	units, ok := minorUnits[code]
+	if !ok {
+		units = 2
+	}
+	return Currency{Code: code, MinorUnits: units}, nil
+}
+
+// MustParseCurrency is ParseCurrency for constants; it panics on a
+// malformed code.
+func MustParseCurrency(code string) Currency {
+	c, err := ParseCurrency(code)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c Currency) String() string { return c.Code }
+
+// Currencies says which currency FinalPrice values are in. Roomzin stores
+// bare minor units, so the currency is a convention of the deployment:
+// one per segment, or Default for segments not listed.
+type Currencies struct {
+	Default  Currency
+	Segments map[string]Currency
+	// Segment, when set, returns the segment of a property, so calls
+	// keyed by property use that segment's currency. An empty result
+	// falls back to Default.
+	Segment func(propertyID string) string
+}
+
+// For returns the currency of prices in segment.
+func (c Currencies) For(segment string) Currency {
+	if cur, ok := c.Segments[segment]; ok {
+		return cur
+	}
+	return c.Default
+}
+
+// ForProperty returns the currency of prices of propertyID.
+func (c Currencies) ForProperty(propertyID string) Currency {
+	if c.Segment == nil {
+		return c.Default
+	}
+	return c.For(c.Segment(propertyID))
+}
+
+// PricesIn makes Verify reject a price set with SetPrice or SetMaxPrice
+// in a currency other than c. Clients pass the configured currency of the
+// call; a zero c, or a price set as bare minor units, is not checked.
+func PricesIn(c Currency) VerifyOption {
+	return func(cfg *verifyConfig) { cfg.currency = c }
+}
+
+// checkCurrency reports a price set in cur when opts expect another
+// currency.
+func checkCurrency(cur Currency, opts []VerifyOption) error {
+	var cfg verifyConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cur.Code == "" || cfg.currency.Code == "" || cur == cfg.currency {
+		return nil
+	}
+	return fmt.Errorf("price is in %s but prices here are in %s", cur, cfg.currency)
+}
+
+// Money is an amount in the minor units of a currency: 12345 EUR cents is
+// 123.45 EUR.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// MaxWirePrice is the largest amount, in minor units, a FinalPrice can
+// carry on the wire.
+const MaxWirePrice = math.MaxUint32
+
+// MoneyFromWire returns the wire FinalPrice v as an amount of c.
+func MoneyFromWire(v uint32, c Currency) Money {
+	return Money{Amount: int64(v), Currency: c}
+}
+
+// Wire returns m as a wire FinalPrice. It fails for negative amounts and
+// amounts above MaxWirePrice.
+func (m Money) Wire() (uint32, error) {
+	if m.Amount < 0 || m.Amount > MaxWirePrice {
+		return 0, fmt.Errorf("price %s does not fit the wire range 0 to %d minor units", m, uint32(MaxWirePrice))
+	}
+	return uint32(m.Amount), nil
+}
+
+// ParseMoney parses a decimal amount of c such as "123.45". It rejects
+// more decimal places than c has, so no amount is silently rounded.
+func ParseMoney(s string, c Currency) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if whole == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return Money{}, fmt.Errorf("invalid amount: %q", s)
+	}
+	if len(frac) > c.MinorUnits {
+		return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", s, c.MinorUnits, c.Code)
+	}
+	frac += strings.Repeat("0", c.MinorUnits-len(frac))
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("amount %q out of range", s)
+	}
+	if neg {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: c}, nil
+}
+
+// Decimal formats the amount with the currency's decimal places, e.g.
+// "123.45" or "-0.50".
+func (m Money) Decimal() string {
+	digits := strconv.FormatUint(absInt64(m.Amount), 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+	n := m.Currency.MinorUnits
+	if n <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= n {
+		digits = strings.Repeat("0", n-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-n] + "." + digits[len(digits)-n:]
+}
+
+func absInt64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-(v + 1)) + 1
+	}
+	return uint64(v)
+}
+
+// String formats m as "123.45 EUR", or just the amount when m has no
+// currency.
+func (m Money) String() string {
+	if m.Currency.Code == "" {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + m.Currency.Code
+}
+
+// MarshalText encodes m as String does.
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText decodes "123.45 EUR".
+func (m *Money) UnmarshalText(b []byte) error {
+	amount, code, ok := strings.Cut(string(b), " ")
+	if !ok {
+		return errors.New("money must be an amount and a currency code, e.g. \"123.45 EUR\"")
+	}
+	c, err := ParseCurrency(code)
+	if err != nil {
+		return err
+	}
+	v, err := ParseMoney(amount, c)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Price returns FinalPrice as an amount of the result's Currency.
+func (r GetRoomDayResult) Price() Money { return MoneyFromWire(r.FinalPrice, r.Currency) }
+
+// Price returns FinalPrice as an amount of the day's Currency.
+func (d DayAvail) Price() Money { return MoneyFromWire(d.FinalPrice, d.Currency) }
+
+// SetPrice sets FinalPrice to m. When m does not fit the wire it fails
+// and leaves FinalPrice as it was. Verify checks m's currency against the
+// client's for the property.
+func (p *SetRoomPkgPayload) SetPrice(m Money) error {
+	v, err := m.Wire()
+	if err != nil {
+		return err
+	}
+	p.FinalPrice, p.priceCurrency = &v, m.Currency
+	return nil
+}
+
+// SetPrice sets FinalPrice on every day to m, as SetRoomPkgPayload's
+// SetPrice does.
+func (p *SetRoomPkgRangePayload) SetPrice(m Money) error {
+	v, err := m.Wire()
+	if err != nil {
+		return err
+	}
+	p.FinalPrice, p.priceCurrency = &v, m.Currency
+	return nil
+}
+
+// SetMaxPrice limits results to days priced at most m. When m does not
+// fit the wire it fails and leaves FinalPrice as it was. Verify checks
+// m's currency against the client's for the segment.
+func (p *SearchAvailPayload) SetMaxPrice(m Money) error {
+	v, err := m.Wire()
+	if err != nil {
+		return err
+	}
+	p.FinalPrice, p.priceCurrency = &v, m.Currency
+	return nil
+}
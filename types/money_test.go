@@ -0,0 +1,157 @@
+package types
+
+import "testing"
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Currency
+		wantErr bool
+	}{
+		{in: "EUR", want: Currency{"EUR", 2}},
+		{in: " jpy ", want: Currency{"JPY", 0}},
+		{in: "KWD", want: Currency{"KWD", 3}},
+		{in: "XYZ", want: Currency{"XYZ", 2}},
+		{in: "EU", wantErr: true},
+		{in: "E1R", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCurrency(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCurrency(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseCurrency(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	eur, jpy, kwd := MustParseCurrency("EUR"), MustParseCurrency("JPY"), MustParseCurrency("KWD")
+
+	tests := []struct {
+		in      string
+		cur     Currency
+		want    int64
+		str     string
+		wantErr bool
+	}{
+		{in: "123.45", cur: eur, want: 12345, str: "123.45 EUR"},
+		{in: "0.5", cur: eur, want: 50, str: "0.50 EUR"},
+		{in: "-0.05", cur: eur, want: -5, str: "-0.05 EUR"},
+		{in: "7", cur: eur, want: 700, str: "7.00 EUR"},
+		{in: "1500", cur: jpy, want: 1500, str: "1500 JPY"},
+		{in: "1.234", cur: kwd, want: 1234, str: "1.234 KWD"},
+		{in: "1.234", cur: eur, wantErr: true},
+		{in: "1.5", cur: jpy, wantErr: true},
+		{in: ".5", cur: eur, wantErr: true},
+		{in: "1,5", cur: eur, wantErr: true},
+		{in: "99999999999999999999", cur: eur, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in+" "+tt.cur.Code, func(t *testing.T) {
+			got, err := ParseMoney(tt.in, tt.cur)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseMoney(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got.Amount != tt.want || got.Currency != tt.cur {
+				t.Fatalf("ParseMoney(%q) = %+v, %v; want %d", tt.in, got, err, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Fatalf("String() = %q, want %q", got.String(), tt.str)
+			}
+			var back Money
+			if err := back.UnmarshalText([]byte(got.String())); err != nil || back != got {
+				t.Fatalf("UnmarshalText(%q) = %+v, %v", got.String(), back, err)
+			}
+		})
+	}
+}
+
+func TestMoneyWire(t *testing.T) {
+	eur := MustParseCurrency("EUR")
+	tests := []struct {
+		amount  int64
+		wantErr bool
+	}{
+		{amount: 0},
+		{amount: 12345},
+		{amount: MaxWirePrice},
+		{amount: MaxWirePrice + 1, wantErr: true},
+		{amount: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		m := Money{Amount: tt.amount, Currency: eur}
+		v, err := m.Wire()
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("Wire(%d) = %d, want an error", tt.amount, v)
+			}
+			continue
+		}
+		if err != nil || MoneyFromWire(v, eur) != m {
+			t.Fatalf("Wire(%d) = %d, %v", tt.amount, v, err)
+		}
+	}
+}
+
+func TestSetPrice(t *testing.T) {
+	eur, usd := MustParseCurrency("EUR"), MustParseCurrency("USD")
+	old := uint32(100)
+	p := SetRoomPkgPayload{PropertyID: "p1", RoomType: "dbl", Date: Today(nil, nil), FinalPrice: &old}
+
+	if err := p.SetPrice(Money{Amount: -1, Currency: eur}); err == nil {
+		t.Fatal("SetPrice accepted a negative amount")
+	}
+	if p.FinalPrice != &old || old != 100 {
+		t.Fatalf("a failed SetPrice changed FinalPrice to %v", p.FinalPrice)
+	}
+
+	if err := p.SetPrice(Money{Amount: 12345, Currency: eur}); err != nil {
+		t.Fatal(err)
+	}
+	if *p.FinalPrice != 12345 {
+		t.Fatalf("FinalPrice = %d, want 12345", *p.FinalPrice)
+	}
+	codecs := &Codecs{RateFeatures: []string{}}
+	for _, tt := range []struct {
+		cur     Currency
+		wantErr bool
+	}{{cur: eur}, {cur: Currency{}}, {cur: usd, wantErr: true}} {
+		if err := p.Verify(codecs, PricesIn(tt.cur)); (err != nil) != tt.wantErr {
+			t.Fatalf("Verify with prices in %q = %v, want an error: %v", tt.cur, err, tt.wantErr)
+		}
+	}
+
+	// Bare minor units carry no currency to check.
+	p = SetRoomPkgPayload{PropertyID: "p1", RoomType: "dbl", Date: Today(nil, nil), FinalPrice: &old}
+	if err := p.Verify(codecs, PricesIn(usd)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCurrenciesFor(t *testing.T) {
+	eur, jpy := MustParseCurrency("EUR"), MustParseCurrency("JPY")
+	c := Currencies{
+		Default:  eur,
+		Segments: map[string]Currency{"jp": jpy},
+		Segment:  func(propertyID string) string { return map[string]string{"tokyo-1": "jp"}[propertyID] },
+	}
+	if c.For("jp") != jpy || c.For("eu") != eur {
+		t.Fatal("For ignores the segment map")
+	}
+	if c.ForProperty("tokyo-1") != jpy || c.ForProperty("paris-1") != eur {
+		t.Fatal("ForProperty ignores the property's segment")
+	}
+	if (Currencies{Default: eur}).ForProperty("tokyo-1") != eur {
+		t.Fatal("ForProperty without Segment is not Default")
+	}
+}
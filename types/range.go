@@ -0,0 +1,137 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WeekdayMask selects days of the week; bit i stands for time.Weekday(i).
+// The zero mask selects every day.
+type WeekdayMask uint8
+
+// ParseWeekdays parses a comma separated list of day names such as
+// "Fri,Sat" or "monday, tuesday". Matching is case-insensitive.
+func ParseWeekdays(s string) (WeekdayMask, error) {
+	var m WeekdayMask
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			name := strings.ToLower(d.String())
+			if part == name || part == name[:3] {
+				m |= 1 << d
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid weekday: %s", part)
+		}
+	}
+	return m, nil
+}
+
+// Has reports whether d is selected. The zero mask selects every day.
+func (m WeekdayMask) Has(d time.Weekday) bool {
+	return m == 0 || m&(1<<d) != 0
+}
+
+func (m WeekdayMask) String() string {
+	if m == 0 {
+		return ""
+	}
+	var names []string
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if m&(1<<d) != 0 {
+			names = append(names, d.String()[:3])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// expandDates lists every selected date in [from, to], both inclusive.
+// Each date still goes through ValidateDate when its per-day payload is
+// verified, so past or out-of-horizon days are reported individually.
+func expandDates(from, to Date, weekdays WeekdayMask) ([]Date, error) {
+	switch {
+	case !from.IsValid():
+		return nil, errors.New("a valid from date is required")
+	case !to.IsValid():
+		return nil, errors.New("a valid to date is required")
+	case to.Before(from):
+		return nil, errors.New("to date must not be before from date")
+	}
+	var out []Date
+	for d := range DateRange(from, to) {
+		if weekdays.Has(d.Weekday()) {
+			out = append(out, d)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no dates selected by weekdays in range")
+	}
+	return out, nil
+}
+
+// SetRoomPkgRangePayload applies one room package to every selected day in [From, To].
+type SetRoomPkgRangePayload struct {
+	PropertyID   string
+	RoomType     string
+	From         Date        // inclusive
+	To           Date        // inclusive
+	Weekdays     WeekdayMask // optional; zero means every day
+	Availability *uint8
+	FinalPrice   *uint32 // minor units; see SetPrice
+	RateFeature  []string
+
+	priceCurrency Currency // set by SetPrice
+}
+
+// Days expands the range into per-day SETROOMPKG payloads.
+func (p SetRoomPkgRangePayload) Days() ([]SetRoomPkgPayload, error) {
+	dates, err := expandDates(p.From, p.To, p.Weekdays)
+	if err != nil {
+		return nil, errors.New("VALIDATION_ERROR: " + err.Error())
+	}
+	out := make([]SetRoomPkgPayload, len(dates))
+	for i, date := range dates {
+		out[i] = SetRoomPkgPayload{
+			PropertyID:   p.PropertyID,
+			RoomType:     p.RoomType,
+			Date:         date,
+			Availability: p.Availability,
+			FinalPrice:   p.FinalPrice,
+			RateFeature:  p.RateFeature,
+
+			priceCurrency: p.priceCurrency,
+		}
+	}
+	return out, nil
+}
+
+// DelRoomRangePayload deletes a room's data for every selected day in [From, To].
+type DelRoomRangePayload struct {
+	PropertyID string
+	RoomType   string
+	From       Date        // inclusive
+	To         Date        // inclusive
+	Weekdays   WeekdayMask // optional; zero means every day
+}
+
+// Days expands the range into per-day DELROOMDAY payloads.
+func (p DelRoomRangePayload) Days() ([]DelRoomDayRequest, error) {
+	dates, err := expandDates(p.From, p.To, p.Weekdays)
+	if err != nil {
+		return nil, errors.New("VALIDATION_ERROR: " + err.Error())
+	}
+	out := make([]DelRoomDayRequest, len(dates))
+	for i, date := range dates {
+		out[i] = DelRoomDayRequest{PropertyID: p.PropertyID, RoomType: p.RoomType, Date: date}
+	}
+	return out, nil
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWeekdays(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    WeekdayMask
+		str     string
+		wantErr bool
+	}{
+		{in: "", want: 0, str: ""},
+		{in: "Fri,Sat", want: 1<<time.Friday | 1<<time.Saturday, str: "Fri,Sat"},
+		{in: " monday , TUE ,", want: 1<<time.Monday | 1<<time.Tuesday, str: "Mon,Tue"},
+		{in: "sun,sunday", want: 1 << time.Sunday, str: "Sun"},
+		{in: "fri,funday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseWeekdays(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseWeekdays(%q) = %v, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseWeekdays(%q) = %08b, %v; want %08b", tt.in, got, err, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Fatalf("String() = %q, want %q", got.String(), tt.str)
+			}
+		})
+	}
+}
+
+func TestWeekdayMaskHas(t *testing.T) {
+	var all WeekdayMask
+	weekend := WeekdayMask(1<<time.Saturday | 1<<time.Sunday)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if !all.Has(d) {
+			t.Fatalf("the zero mask does not select %s", d)
+		}
+		if want := d == time.Saturday || d == time.Sunday; weekend.Has(d) != want {
+			t.Fatalf("weekend.Has(%s) = %v, want %v", d, !want, want)
+		}
+	}
+}
+
+func TestRangeDays(t *testing.T) {
+	// 2030-05-17 is a Friday.
+	from := NewDate(2030, 5, 17)
+
+	tests := []struct {
+		name     string
+		to       Date
+		weekdays WeekdayMask
+		want     []Date
+		wantErr  bool
+	}{
+		{name: "one day", to: from, want: []Date{from}},
+		{name: "every day", to: from.AddDays(2), want: []Date{from, from.AddDays(1), from.AddDays(2)}},
+		{name: "weekend only", to: from.AddDays(7), weekdays: 1<<time.Saturday | 1<<time.Sunday, want: []Date{from.AddDays(1), from.AddDays(2)}},
+		{name: "nothing selected", to: from.AddDays(2), weekdays: 1 << time.Monday, wantErr: true},
+		{name: "reversed", to: from.AddDays(-1), wantErr: true},
+		{name: "no end", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, err := DelRoomRangePayload{PropertyID: "p1", RoomType: "dbl", From: from, To: tt.to, Weekdays: tt.weekdays}.Days()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Days() = %v, want an error", days)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []Date
+			for _, d := range days {
+				got = append(got, d.Date)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("dates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// MaxRateFeatures is how many rate features the 24-bit codec mask holds.
+const MaxRateFeatures = 24
+
+// RateFeatureSet is a set of rate feature names, such as "breakfast" and
+// "free_cancellation". The zero value is the empty set. Sets are values:
+// the operations return new sets and never change their operands.
+type RateFeatureSet struct {
+	m map[string]struct{}
+}
+
+// NewRateFeatureSet returns the set of features; duplicates are dropped.
+func NewRateFeatureSet(features ...string) RateFeatureSet {
+	s := RateFeatureSet{m: make(map[string]struct{}, len(features))}
+	for _, f := range features {
+		s.m[f] = struct{}{}
+	}
+	return s
+}
+
+// RateFeaturesFromMask decodes a wire bitmask with codecs, bit i naming
+// codecs.RateFeatures[i]. Bits without a codec are ignored.
+func RateFeaturesFromMask(codecs *Codecs, mask uint32) RateFeatureSet {
+	s := RateFeatureSet{m: make(map[string]struct{})}
+	if codecs == nil {
+		return s
+	}
+	for i, f := range codecs.RateFeatures {
+		if i >= MaxRateFeatures {
+			break
+		}
+		if mask&(1<<uint(i)) != 0 {
+			s.m[f] = struct{}{}
+		}
+	}
+	return s
+}
+
+// Mask encodes s as a wire bitmask with codecs. It fails, naming them,
+// if s holds features codecs do not list.
+func (s RateFeatureSet) Mask(codecs *Codecs) (uint32, error) {
+	if codecs == nil {
+		if s.Len() == 0 {
+			return 0, nil
+		}
+		return 0, errors.New("no codecs to encode rate features with")
+	}
+	var (
+		mask    uint32
+		invalid []string
+	)
+	for f := range s.All() {
+		i := slices.Index(codecs.RateFeatures, f)
+		if i < 0 || i >= MaxRateFeatures {
+			invalid = append(invalid, f)
+			continue
+		}
+		mask |= 1 << uint(i)
+	}
+	if len(invalid) > 0 {
+		return 0, errors.New("Invalid rate features: " + strings.Join(invalid, ", "))
+	}
+	return mask, nil
+}
+
+// Len returns the number of features in s.
+func (s RateFeatureSet) Len() int { return len(s.m) }
+
+// Contains reports whether every one of features is in s, so
+// s.Contains("breakfast", "free_cancellation") asks for both.
+func (s RateFeatureSet) Contains(features ...string) bool {
+	for _, f := range features {
+		if _, ok := s.m[f]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsAny reports whether at least one of features is in s.
+func (s RateFeatureSet) ContainsAny(features ...string) bool {
+	for _, f := range features {
+		if _, ok := s.m[f]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SubsetOf reports whether every feature of s is in o.
+func (s RateFeatureSet) SubsetOf(o RateFeatureSet) bool {
+	for f := range s.m {
+		if _, ok := o.m[f]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether s and o hold the same features.
+func (s RateFeatureSet) Equal(o RateFeatureSet) bool {
+	return s.Len() == o.Len() && s.SubsetOf(o)
+}
+
+// With returns s plus features.
+func (s RateFeatureSet) With(features ...string) RateFeatureSet {
+	return s.Union(NewRateFeatureSet(features...))
+}
+
+// Without returns s minus features.
+func (s RateFeatureSet) Without(features ...string) RateFeatureSet {
+	return s.Difference(NewRateFeatureSet(features...))
+}
+
+// Union returns the features in s or o.
+func (s RateFeatureSet) Union(o RateFeatureSet) RateFeatureSet {
+	out := RateFeatureSet{m: maps.Clone(s.m)}
+	if out.m == nil {
+		out.m = make(map[string]struct{}, o.Len())
+	}
+	maps.Copy(out.m, o.m)
+	return out
+}
+
+// Intersect returns the features in both s and o.
+func (s RateFeatureSet) Intersect(o RateFeatureSet) RateFeatureSet {
+	out := RateFeatureSet{m: make(map[string]struct{})}
+	for f := range s.m {
+		if _, ok := o.m[f]; ok {
+			out.m[f] = struct{}{}
+		}
+	}
+	return out
+}
+
+// Difference returns the features in s that are not in o.
+func (s RateFeatureSet) Difference(o RateFeatureSet) RateFeatureSet {
+	out := RateFeatureSet{m: make(map[string]struct{})}
+	for f := range s.m {
+		if _, ok := o.m[f]; !ok {
+			out.m[f] = struct{}{}
+		}
+	}
+	return out
+}
+
+// All yields the features of s in sorted order.
+func (s RateFeatureSet) All() iter.Seq[string] {
+	return slices.Values(s.Slice())
+}
+
+// Slice returns the features of s sorted, never nil, ready for the
+// RateFeature field of a payload.
+func (s RateFeatureSet) Slice() []string {
+	out := slices.Collect(maps.Keys(s.m))
+	if out == nil {
+		out = []string{}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// String returns the sorted features joined by commas.
+func (s RateFeatureSet) String() string {
+	return strings.Join(s.Slice(), ",")
+}
+
+// MarshalJSON encodes s as a sorted array of names.
+func (s RateFeatureSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Slice())
+}
+
+// UnmarshalJSON decodes an array of names; null is the empty set.
+func (s *RateFeatureSet) UnmarshalJSON(b []byte) error {
+	var features []string
+	if err := json.Unmarshal(b, &features); err != nil {
+		return err
+	}
+	*s = NewRateFeatureSet(features...)
+	return nil
+}
+
+// Features returns the rate features of the day as a set.
+func (r GetRoomDayResult) Features() RateFeatureSet { return NewRateFeatureSet(r.RateFeature...) }
+
+// Features returns the rate features of the day as a set.
+func (d DayAvail) Features() RateFeatureSet { return NewRateFeatureSet(d.RateFeature...) }
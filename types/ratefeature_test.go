@@ -0,0 +1,128 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestRateFeatureMask(t *testing.T) {
+	codecs := &Codecs{RateFeatures: []string{"breakfast", "free_cancellation", "non_refundable"}}
+
+	tests := []struct {
+		name     string
+		codecs   *Codecs
+		features []string
+		want     uint32
+		wantErr  bool
+	}{
+		{name: "empty", codecs: codecs, want: 0},
+		{name: "one", codecs: codecs, features: []string{"free_cancellation"}, want: 0b010},
+		{name: "several", codecs: codecs, features: []string{"non_refundable", "breakfast", "breakfast"}, want: 0b101},
+		{name: "unknown", codecs: codecs, features: []string{"breakfast", "spa"}, wantErr: true},
+		{name: "empty without codecs", want: 0},
+		{name: "features without codecs", features: []string{"breakfast"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewRateFeatureSet(tt.features...)
+			got, err := s.Mask(tt.codecs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Mask = %b, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Mask = %b, %v; want %b", got, err, tt.want)
+			}
+			if back := RateFeaturesFromMask(tt.codecs, got); !back.Equal(s) {
+				t.Fatalf("RateFeaturesFromMask(%b) = %v, want %v", got, back, s)
+			}
+		})
+	}
+
+	// Bits past the codecs, or past the 24-bit mask, decode to nothing.
+	many := make([]string, MaxRateFeatures+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("f%d", i)
+	}
+	if got := RateFeaturesFromMask(&Codecs{RateFeatures: many}, 1<<MaxRateFeatures|1<<3); got.String() != "f3" {
+		t.Fatalf("RateFeaturesFromMask = %v, want f3", got)
+	}
+	if _, err := NewRateFeatureSet(many[MaxRateFeatures]).Mask(&Codecs{RateFeatures: many}); err == nil {
+		t.Fatal("encoded a feature past the 24-bit mask")
+	}
+	if got := RateFeaturesFromMask(nil, 0b111); got.Len() != 0 {
+		t.Fatalf("RateFeaturesFromMask without codecs = %v", got)
+	}
+}
+
+func TestRateFeatureSetOps(t *testing.T) {
+	a := NewRateFeatureSet("breakfast", "free_cancellation")
+	b := NewRateFeatureSet("free_cancellation", "spa")
+	var zero RateFeatureSet
+
+	tests := []struct {
+		name string
+		got  RateFeatureSet
+		want []string
+	}{
+		{name: "union", got: a.Union(b), want: []string{"breakfast", "free_cancellation", "spa"}},
+		{name: "intersect", got: a.Intersect(b), want: []string{"free_cancellation"}},
+		{name: "difference", got: a.Difference(b), want: []string{"breakfast"}},
+		{name: "with", got: a.With("spa"), want: []string{"breakfast", "free_cancellation", "spa"}},
+		{name: "without", got: a.Without("breakfast", "spa"), want: []string{"free_cancellation"}},
+		{name: "zero union", got: zero.Union(b), want: []string{"free_cancellation", "spa"}},
+		{name: "zero", got: zero, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.Slice(); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := a.Slice(); !slices.Equal(got, []string{"breakfast", "free_cancellation"}) {
+		t.Fatalf("operations changed their operand: %v", got)
+	}
+	if !a.Contains("breakfast", "free_cancellation") || a.Contains("breakfast", "spa") || !a.Contains() {
+		t.Fatal("Contains is wrong")
+	}
+	if !a.ContainsAny("spa", "breakfast") || a.ContainsAny("spa") || a.ContainsAny() {
+		t.Fatal("ContainsAny is wrong")
+	}
+	if !a.Intersect(b).SubsetOf(a) || a.SubsetOf(b) || !zero.SubsetOf(a) {
+		t.Fatal("SubsetOf is wrong")
+	}
+	if !zero.Equal(NewRateFeatureSet()) || a.Equal(b) || !a.Equal(NewRateFeatureSet("free_cancellation", "breakfast")) {
+		t.Fatal("Equal is wrong")
+	}
+}
+
+func TestRateFeatureSetJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: `["spa","breakfast","spa"]`, want: `["breakfast","spa"]`},
+		{in: `[]`, want: `[]`},
+		{in: `null`, want: `[]`},
+	}
+	for _, tt := range tests {
+		var s RateFeatureSet
+		if err := json.Unmarshal([]byte(tt.in), &s); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+		}
+		got, err := json.Marshal(s)
+		if err != nil || string(got) != tt.want {
+			t.Fatalf("Marshal(Unmarshal(%s)) = %s, %v; want %s", tt.in, got, err, tt.want)
+		}
+	}
+	var s RateFeatureSet
+	if err := json.Unmarshal([]byte(`"breakfast"`), &s); err == nil {
+		t.Fatal("decoded a string as a set")
+	}
+}
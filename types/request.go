@@ -3,46 +3,49 @@ package types
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
-	"time"
 )
 
-func ValidateDate(date string) error {
+// DefaultBookingHorizon is how many days past today a date may be when
+// neither the client config nor the server sets a horizon.
+const DefaultBookingHorizon = 365
+
+// MaxBookingHorizon is the longest horizon the wire format can carry: a
+// packed date counts at most seven years from the server's current year.
+const MaxBookingHorizon = 7 * 365
+
+// ValidateDate checks that date is set, real and within the booking
+// horizon: not before today and at most DefaultBookingHorizon days ahead.
+// Today is the UTC date of the system clock unless AsOf says otherwise,
+// and Horizon changes the number of days.
+func ValidateDate(date Date, opts ...VerifyOption) error {
+	switch {
+	case date.IsZero():
+		return errors.New("date is required")
+	case !date.IsValid():
+		return fmt.Errorf("invalid date: %v", date)
+	}
+	cfg := newVerifyConfig(opts)
+	if cfg.anyDate {
+		return nil
+	}
 	var errs []string
-
-	// Check format YYYY-MM-DD
-	if matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}$`, date); !matched {
-		errs = append(errs, fmt.Sprintf("invalid date format: %s, expected YYYY-MM-DD", date))
-	} else {
-		// Parse to ensure valid date
-		parsedDate, err := time.Parse("2006-01-02", date)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("invalid date: %s", date))
-		} else {
-			// Check if date is in the past
-			today := time.Now().Truncate(24 * time.Hour)
-			if parsedDate.Before(today) {
-				errs = append(errs, fmt.Sprintf("date %s is in the past", date))
-			}
-			// Check if date is beyond 365 days
-			oneYearFromNow := today.AddDate(1, 0, 0)
-			if parsedDate.After(oneYearFromNow) {
-				errs = append(errs, fmt.Sprintf("date %s is beyond 365 days from today", date))
-			}
-		}
+	if date.Before(cfg.today) {
+		errs = append(errs, fmt.Sprintf("date %s is in the past", date))
+	}
+	if date.After(cfg.today.AddDays(cfg.horizon)) {
+		errs = append(errs, fmt.Sprintf("date %s is beyond %d days from today", date, cfg.horizon))
 	}
-
 	if len(errs) > 0 {
 		return errors.New(strings.Join(errs, "; "))
 	}
 	return nil
 }
 
-func ValidateDates(dates []string) error {
+func ValidateDates(dates []Date, opts ...VerifyOption) error {
 	var errs []string
 	for _, date := range dates {
-		err := ValidateDate(date)
+		err := ValidateDate(date, opts...)
 		if err != nil {
 			errs = append(errs, err.Error())
 		}
@@ -105,6 +108,7 @@ func (p SetPropPayload) Verify(codecs *Codecs) error {
 	if p.Longitude < -180 || p.Longitude > 180 {
 		errs = append(errs, "longitude must be between -180 and 180")
 	}
+	errs = append(errs, codecs.validateProperty(p.PropertyType, p.Category, p.Amenities)...)
 
 	if len(errs) > 0 {
 		return errors.New("VALIDATION_ERROR: " + strings.Join(errs, "; "))
@@ -167,18 +171,18 @@ func (p PropRoomDateListPayload) Verify() error {
 type DelRoomDayRequest struct {
 	PropertyID string
 	RoomType   string
-	Date       string // YYYY-MM-DD
+	Date       Date
 }
 
 // Verify validates the DelRoomDayRequest.
-func (p DelRoomDayRequest) Verify() error {
+func (p DelRoomDayRequest) Verify(opts ...VerifyOption) error {
 	if p.PropertyID == "" {
 		return errors.New("VALIDATION_ERROR: propertyID is required")
 	}
 	if p.RoomType == "" {
 		return errors.New("VALIDATION_ERROR: RoomType is required")
 	}
-	err := ValidateDate(p.Date)
+	err := ValidateDate(p.Date, opts...)
 	if err != nil {
 		return errors.New("VALIDATION_ERROR: " + err.Error())
 	}
@@ -189,11 +193,11 @@ func (p DelRoomDayRequest) Verify() error {
 type UpdRoomAvlPayload struct {
 	PropertyID string
 	RoomType   string
-	Date       string // YYYY-MM-DD
+	Date       Date
 	Amount     uint8
 }
 
-func (p UpdRoomAvlPayload) Verify() error {
+func (p UpdRoomAvlPayload) Verify(opts ...VerifyOption) error {
 	var errs []string
 
 	if p.PropertyID == "" {
@@ -206,7 +210,38 @@ func (p UpdRoomAvlPayload) Verify() error {
 		errs = append(errs, "amount must be greater than 0")
 	}
 
-	err := ValidateDate(p.Date)
+	err := ValidateDate(p.Date, opts...)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return errors.New("VALIDATION_ERROR: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// SetRoomAvlIfPayload defines the payload for a conditional availability update (SETROOMAVLIF command).
+// The server only writes Amount when the current availability equals Expected.
+type SetRoomAvlIfPayload struct {
+	PropertyID string
+	RoomType   string
+	Date       Date
+	Expected   uint8
+	Amount     uint8
+}
+
+func (p SetRoomAvlIfPayload) Verify(opts ...VerifyOption) error {
+	var errs []string
+
+	if p.PropertyID == "" {
+		errs = append(errs, "propertyID is required")
+	}
+	if p.RoomType == "" {
+		errs = append(errs, "roomType is required")
+	}
+
+	err := ValidateDate(p.Date, opts...)
 	if err != nil {
 		errs = append(errs, err.Error())
 	}
@@ -221,13 +256,15 @@ func (p UpdRoomAvlPayload) Verify() error {
 type SetRoomPkgPayload struct {
 	PropertyID   string
 	RoomType     string
-	Date         string // YYYY-MM-DD
+	Date         Date
 	Availability *uint8
-	FinalPrice   *uint32
+	FinalPrice   *uint32  // minor units; see SetPrice
 	RateFeature  []string // Optional; empty slice if not provided
+
+	priceCurrency Currency // set by SetPrice
 }
 
-func (p SetRoomPkgPayload) Verify(codecs *Codecs) error {
+func (p SetRoomPkgPayload) Verify(codecs *Codecs, opts ...VerifyOption) error {
 	var errs []string
 
 	if p.PropertyID == "" {
@@ -237,7 +274,7 @@ func (p SetRoomPkgPayload) Verify(codecs *Codecs) error {
 		errs = append(errs, "roomType is required")
 	}
 
-	dateErr := ValidateDate(p.Date)
+	dateErr := ValidateDate(p.Date, append([]VerifyOption{codecs.horizon()}, opts...)...)
 	if dateErr != nil {
 		errs = append(errs, dateErr.Error())
 	}
@@ -249,6 +286,10 @@ func (p SetRoomPkgPayload) Verify(codecs *Codecs) error {
 		}
 	}
 
+	if err := checkCurrency(p.priceCurrency, opts); err != nil {
+		errs = append(errs, err.Error())
+	}
+
 	if len(errs) > 0 {
 		return errors.New("VALIDATION_ERROR: " + strings.Join(errs, "; "))
 	}
@@ -259,18 +300,18 @@ func (p SetRoomPkgPayload) Verify(codecs *Codecs) error {
 type GetRoomDayRequest struct {
 	PropertyID string
 	RoomType   string
-	Date       string // YYYY-MM-DD
+	Date       Date
 }
 
 // Verify validates the GetRoomDayRequest.
-func (p GetRoomDayRequest) Verify() error {
+func (p GetRoomDayRequest) Verify(opts ...VerifyOption) error {
 	if p.PropertyID == "" {
 		return errors.New("VALIDATION_ERROR: propertyID is required")
 	}
 	if p.RoomType == "" {
 		return errors.New("VALIDATION_ERROR: RoomType is required")
 	}
-	err := ValidateDate(p.Date)
+	err := ValidateDate(p.Date, opts...)
 	if err != nil {
 		return errors.New("VALIDATION_ERROR: " + err.Error())
 	}
@@ -304,6 +345,7 @@ func (p SearchPropPayload) Verify(codecs *Codecs) error {
 	if p.Longitude != nil && (*p.Longitude < -180 || *p.Longitude > 180) {
 		errs = append(errs, "longitude must be between -180 and 180")
 	}
+	errs = append(errs, codecs.validateProperty(deref(p.Type), deref(p.Category), deref(p.Amenities))...)
 
 	if len(errs) > 0 {
 		return errors.New("VALIDATION_ERROR: " + strings.Join(errs, "; "))
@@ -322,14 +364,16 @@ type SearchAvailPayload struct {
 	Amenities    []string
 	Longitude    *float64
 	Latitude     *float64
-	Date         []string
+	Date         []Date
 	Availability *uint8
-	FinalPrice   *uint32
+	FinalPrice   *uint32 // maximum, in minor units; see SetMaxPrice
 	RateFeature  []string
 	Limit        *uint64
+
+	priceCurrency Currency // set by SetMaxPrice
 }
 
-func (p SearchAvailPayload) Verify(codecs *Codecs) error {
+func (p SearchAvailPayload) Verify(codecs *Codecs, opts ...VerifyOption) error {
 	var errs []string
 
 	if p.Segment == "" {
@@ -351,7 +395,7 @@ func (p SearchAvailPayload) Verify(codecs *Codecs) error {
 	if len(p.Date) == 0 {
 		errs = append(errs, "at least one date is required")
 	} else {
-		if err := ValidateDates(p.Date); err != nil {
+		if err := ValidateDates(p.Date, append([]VerifyOption{codecs.horizon()}, opts...)...); err != nil {
 			errs = append(errs, err.Error())
 		}
 	}
@@ -360,6 +404,10 @@ func (p SearchAvailPayload) Verify(codecs *Codecs) error {
 			errs = append(errs, err.Error())
 		}
 	}
+	if err := checkCurrency(p.priceCurrency, opts); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, codecs.validateProperty(deref(p.Type), deref(p.Category), p.Amenities)...)
 	if p.Limit != nil && *p.Limit == 0 {
 		errs = append(errs, "limit must be greater than 0")
 	}
@@ -373,16 +421,25 @@ func (p SearchAvailPayload) Verify(codecs *Codecs) error {
 // DelPropDayRequest defines the payload for deleting all room data for a property on a specific date (DELPROPDAY command).
 type DelPropDayRequest struct {
 	PropertyID string
-	Date       string // YYYY-MM-DD
+	Date       Date
 }
 
-func (p DelPropDayRequest) Verify() error {
+func (p DelPropDayRequest) Verify(opts ...VerifyOption) error {
 	if p.PropertyID == "" {
 		return errors.New("VALIDATION_ERROR: propertyID is required")
 	}
-	err := ValidateDate(p.Date)
+	err := ValidateDate(p.Date, opts...)
 	if err != nil {
 		return errors.New("VALIDATION_ERROR: " + err.Error())
 	}
 	return nil
 }
+
+// deref returns *p, or the zero value for nil.
+func deref[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
@@ -3,17 +3,19 @@ package types
 // GetRoomDayResult defines the result for retrieving room details for a specific date (GETPROPROOMDAY command).
 type GetRoomDayResult struct {
 	PropertyID   string
-	Date         string
+	Date         Date
 	Availability uint8
-	FinalPrice   uint32
+	FinalPrice   uint32   // minor units; see Price
+	Currency     Currency // of FinalPrice, from the client's Currencies
 	RateFeature  []string
 }
 
 // DayAvail one day inside a property.
 type DayAvail struct {
-	Date         string
+	Date         Date
 	Availability uint8
-	FinalPrice   uint32
+	FinalPrice   uint32   // minor units; see Price
+	Currency     Currency // of FinalPrice, from the client's Currencies
 	RateFeature  []string
 }
 
@@ -27,3 +29,16 @@ type SegmentInfo struct {
 	Segment   string
 	PropCount uint32
 }
+
+// BatchResult is the outcome of one queued batch command, in queue order.
+type BatchResult struct {
+	Command      string // e.g. "SETROOMPKG"
+	Availability uint8  // new availability, for the *ROOMAVL commands only
+	Err          error  // validation, transport or server error for this command
+}
+
+// DateResult is the outcome for one day of a range write.
+type DateResult struct {
+	Date Date
+	Err  error
+}